@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/output"
+)
+
+// apiHandler serves the -http JSON API:
+//
+//	GET /sample         the latest sample (503 until the first one)
+//	GET /samples?n=60   up to n recent samples, oldest first (default: all kept)
+//	GET /schema         the JSON Schema of a sample (see `sysmoni schema`)
+//
+// latest returns up to n recent samples, oldest first, and size is how many
+// are kept. Per-core detail is reduced to perCore and timestamps written in
+// tsFormat as in JSON output; cors, if set, is sent as
+// Access-Control-Allow-Origin.
+func apiHandler(latest func(n int) []model.Sample, size int, perCore, tsFormat, cors string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sample", func(w http.ResponseWriter, r *http.Request) {
+		last := latest(1)
+		if len(last) == 0 {
+			http.Error(w, "no sample yet", http.StatusServiceUnavailable)
+			return
+		}
+		output.ApplyPerCore(&last[0], perCore)
+		writeJSON(w, output.WithTimestamp(last[0], tsFormat))
+	})
+	mux.HandleFunc("GET /samples", func(w http.ResponseWriter, r *http.Request) {
+		n := size
+		if v := r.URL.Query().Get("n"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1 {
+				http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			n = parsed
+		}
+		samples := latest(n)
+		out := make([]any, len(samples))
+		for i := range samples {
+			output.ApplyPerCore(&samples[i], perCore)
+			out[i] = output.WithTimestamp(samples[i], tsFormat)
+		}
+		writeJSON(w, out)
+	})
+	mux.HandleFunc("GET /schema", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/schema+json")
+		if err := output.WriteSchema(w); err != nil {
+			slog.Debug("api write failed", "err", err)
+		}
+	})
+	if cors == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", cors)
+		w.Header().Set("Vary", "Origin")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("api write failed", "err", err)
+	}
+}
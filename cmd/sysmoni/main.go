@@ -3,38 +3,430 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/output"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/replay"
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/sampler"
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/ui"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/watch"
 )
 
 func main() {
-	cfg := config.FromFlags(os.Args[1:])
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		if err := output.WriteSchema(os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.FromArgs(os.Args[1:], os.Getenv, "")
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sysmoni:", err)
+		os.Exit(2)
+	}
+	if cfg.Version {
+		if err := printVersion(os.Stdout, cfg.JSON || cfg.JSONStream); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	jsonMode := cfg.JSON || cfg.JSONStream || cfg.CSV || (!isTTY() && !cfg.Plain)
+
+	closeLog, err := setupLogging(cfg, !jsonMode && !cfg.Plain && cfg.Prometheus == "" && cfg.HTTP == "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	// Bad options (e.g. an invalid -filter regex) fail at startup instead of
+	// silently misbehaving; missing capabilities are warned about but don't
+	// stop the run.
+	errs, warnings := config.Validate(cfg)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "sysmoni: warning:", w)
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, "sysmoni:", e)
+		}
+		os.Exit(2)
+	}
+	for _, w := range cfg.EnvWarnings {
+		fmt.Fprintln(os.Stderr, "sysmoni: warning:", w)
+	}
+	// Validate has warned about an out-of-range interval.
+	cfg.Interval = config.ClampInterval(cfg.Interval)
+	// A capture from a Linux host replays fine anywhere.
+	if err := sampler.Unsupported(); err != nil && cfg.Replay == "" {
+		fmt.Fprintln(os.Stderr, "sysmoni: warning:", err)
+	}
+	slog.Info("sysmoni starting", "json", jsonMode, "interval", cfg.Interval, "sort", cfg.Sort,
+		"gpu", cfg.EnableGPU, "battery", cfg.EnableBatt, "cgroups", cfg.EnableCgroups)
+
+	// Streaming modes stop on SIGINT/SIGTERM by cancelling the sampler's
+	// context; the TUI handles its own keys and signals.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Prometheus != "" || cfg.HTTP != "" {
+		if err := runServers(ctx, cfg); err != nil {
+			slog.Error("server failed", "err", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// JSON/NDJSON modes
-	if cfg.JSON || cfg.JSONStream || !isTTY() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		s := sampler.New(cfg.Interval)
-		out := json.NewEncoder(os.Stdout)
-		for samp := range s.Stream(ctx) {
-			_ = out.Encode(samp)
-			if !cfg.JSONStream {
-				return
-			}
+	if jsonMode {
+		if err := runJSON(ctx, cfg, newSource(cfg)); err != nil {
+			slog.Error("json output failed", "err", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 		return
 	}
 
-	if err := ui.RunTUI(cfg); err != nil {
+	if cfg.Plain {
+		if err := runPlain(ctx, cfg, newSource(cfg)); err != nil {
+			slog.Error("plain output failed", "err", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := ui.RunTUI(cfg, newSource(cfg)); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// newSource returns where samples come from: the -replay capture, or the
+// live sampler.
+func newSource(cfg config.Config) model.SampleSource {
+	if cfg.Replay != "" {
+		return replay.New(cfg.Replay, cfg.ReplaySpeed)
+	}
+	return sampler.NewWithConfig(cfg)
+}
+
+// setupLogging installs the default slog logger. Logs go to stderr, except
+// in the TUI where they would corrupt the display and default to a file.
+func setupLogging(cfg config.Config, tui bool) (func(), error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, fmt.Errorf("log-level: %w", err)
+	}
+
+	var w io.Writer = os.Stderr
+	closeFn := func() {}
+	path := cfg.LogPath
+	if path == "" && tui {
+		path = filepath.Join(os.TempDir(), "sysmoni.log")
+	}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("log-path: %w", err)
+		}
+		w = f
+		closeFn = func() { f.Close() }
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch cfg.LogFormat {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	default:
+		closeFn()
+		return nil, fmt.Errorf("log-format %q is not text|json", cfg.LogFormat)
+	}
+	return closeFn, nil
+}
+
+// runJSON emits one sample (or a stream with -json-stream) until ctx is
+// cancelled. Records are only written whole: the sampler drops a sample taken
+// during cancellation, and the writer is closed (flushing any gzip footer)
+// once the stream has drained.
+func runJSON(ctx context.Context, cfg config.Config, src model.SampleSource) (err error) {
+	w, err := output.OpenRotating(cfg.LogFile, cfg.Compress, cfg.CompressLevel,
+		output.Rotation{Size: cfg.RotateSize, Every: cfg.RotateEvery, Keep: cfg.Keep})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	// A new encoder per output file, so each rotated CSV file gets a header.
+	newEncoder := func() func(model.Sample) error {
+		if cfg.CSV {
+			return output.NewCSVEncoder(w).Encode
+		}
+		enc := json.NewEncoder(w)
+		return func(samp model.Sample) error { return enc.Encode(output.WithTimestamp(samp, cfg.TimestampFormat)) }
+	}
+	encode := newEncoder()
+	var filter *output.ChangeFilter
+	if cfg.ChangeOnly {
+		filter = output.NewChangeFilter(cfg.ChangeThreshold, cfg.Heartbeat)
+	}
+	stream, stop := watchStream(ctx, src, cfg)
+	defer stop()
+	if !cfg.JSONStream {
+		samp, ok := oneShot(stream, cfg.Samples)
+		if !ok {
+			return nil // interrupted before a full sample
+		}
+		output.ApplyPerCore(&samp, cfg.PerCore)
+		return encode(samp)
+	}
+	if s, ok := src.(*sampler.Sampler); ok {
+		defer func() {
+			if n := s.DroppedSamples(); n > 0 {
+				slog.Warn("output could not keep up; samples were dropped", "dropped", n)
+			}
+		}()
+	}
+	for samp := range stream {
+		if filter != nil && !filter.Emit(samp) {
+			continue
+		}
+		output.ApplyPerCore(&samp, cfg.PerCore)
+		if err := encode(samp); err != nil {
+			return err
+		}
+		rotated, err := w.Boundary()
+		if err != nil {
+			return err
+		}
+		if rotated {
+			encode = newEncoder()
+		}
+	}
+	return nil
+}
+
+// runPlain prints a table per sample until ctx is cancelled: redrawn in
+// place on a terminal, appended when stdout is a pipe or file.
+func runPlain(ctx context.Context, cfg config.Config, src model.SampleSource) error {
+	enc := output.NewPlainEncoder(os.Stdout, isTTY(), cfg.Sort, cfg.Top)
+	stream, stop := watchStream(ctx, src, cfg)
+	defer stop()
+	for samp := range stream {
+		if err := enc.Encode(samp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runServers serves the -prometheus exporter and the -http JSON API,
+// whichever are configured, from one sampler. Requests never trigger
+// sampling; they read whatever the sampler produced last.
+func runServers(ctx context.Context, cfg config.Config) error {
+	// Both serve from the sampler's history, sized for /samples.
+	cfg.History = max(cfg.History, 1)
+	if cfg.HTTP != "" {
+		cfg.History = max(cfg.History, cfg.HTTPHistory)
+	}
+	s := sampler.NewWithConfig(cfg)
+	stream, stop := watchStream(ctx, s, cfg)
+	defer stop()
+	go func() {
+		for range stream {
+		}
+	}()
+
+	var srvs []*http.Server
+	if cfg.Prometheus != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			latest := s.Latest(1)
+			if len(latest) == 0 {
+				http.Error(w, "no sample yet", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			if err := output.WritePrometheus(w, latest[0]); err != nil {
+				slog.Debug("metrics write failed", "err", err)
+			}
+		})
+		srvs = append(srvs, &http.Server{Addr: cfg.Prometheus, Handler: mux, ReadHeaderTimeout: 5 * time.Second})
+		slog.Info("serving prometheus metrics", "addr", cfg.Prometheus)
+	}
+	if cfg.HTTP != "" {
+		srvs = append(srvs, &http.Server{Addr: cfg.HTTP, Handler: apiHandler(s.Latest, cfg.HTTPHistory, cfg.PerCore, cfg.TimestampFormat, cfg.HTTPCORS), ReadHeaderTimeout: 5 * time.Second})
+		slog.Info("serving JSON API", "addr", cfg.HTTP, "history", cfg.HTTPHistory)
+	}
+
+	// The first server to fail (e.g. its port is taken) stops the others.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errc := make(chan error, len(srvs))
+	for _, srv := range srvs {
+		go func() {
+			err := srv.ListenAndServe()
+			if err == http.ErrServerClosed {
+				err = nil
+			}
+			errc <- err
+			cancel()
+		}()
+	}
+	<-ctx.Done()
+	shutdownCtx, done := context.WithTimeout(context.Background(), 2*time.Second)
+	defer done()
+	for _, srv := range srvs {
+		srv.Shutdown(shutdownCtx)
+	}
+	var errs []error
+	for range srvs {
+		errs = append(errs, <-errc)
+	}
+	return errors.Join(errs...)
+}
+
+// watchStream streams from src through watchSamples under its own context.
+// stop cancels the stream and drains it until it closes, so by the time stop
+// returns the sampler has stopped and -cap-cgroup limits are restored;
+// callers defer it, which also covers returning with an error.
+func watchStream(ctx context.Context, src model.SampleSource, cfg config.Config) (stream <-chan model.Sample, stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream = watchSamples(ctx, src.Stream(ctx), cfg, os.Stderr)
+	return stream, func() {
+		cancel()
+		for range stream {
+		}
+	}
+}
+
+// watchSamples passes stream through unchanged while feeding every sample to
+// the -alert rules (each state change is written to w as a JSON line, and
+// firing alerts start the -on-alert hook), the -protect-cpu protector and
+// the -cap-cgroup capper. With none configured it returns stream itself.
+// Like the sampler's own channel, the result is closed once stream is, and
+// by then any cgroup limits changed by -cap-cgroup have been restored. A
+// -replay capture only feeds the alert rules: nothing acts on this host.
+func watchSamples(ctx context.Context, stream <-chan model.Sample, cfg config.Config, w io.Writer) <-chan model.Sample {
+	ws := watch.New(cfg)
+	if ws.Empty() {
+		return stream
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // keep "cpu>90" readable
+	out := make(chan model.Sample)
+	go func() {
+		defer close(out)
+		if ws.Capper != nil {
+			defer func() {
+				if err := ws.Capper.Restore(); err != nil {
+					slog.Error("cap-cgroup restore failed", "err", err)
+				}
+			}()
+		}
+		for samp := range stream {
+			if ws.Alerts != nil {
+				for _, a := range ws.Alerts.Evaluate(samp) {
+					if err := enc.Encode(a); err != nil {
+						slog.Debug("alert write failed", "err", err)
+					}
+					if ws.Hook != nil {
+						ws.Hook.Fire(ctx, a)
+					}
+				}
+			}
+			if ws.Protector != nil {
+				ws.Protector.Apply(samp)
+			}
+			if ws.Capper != nil {
+				ws.Capper.Apply(samp)
+			}
+			select {
+			case out <- samp:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
+
+// oneShot averages the first n samples. The sampler primes its counters at
+// construction, so even the first one carries real rates.
+func oneShot(stream <-chan model.Sample, n int) (model.Sample, bool) {
+	n = max(n, 1)
+	samples := make([]model.Sample, 0, n)
+	for samp := range stream {
+		samples = append(samples, samp)
+		if len(samples) >= n {
+			return sampler.Average(samples), true
+		}
+	}
+	return model.Sample{}, false
+}
+
+// runValidate loads a config file, prints every problem found, and returns
+// the process exit code: 2 on errors (or an unreadable file), as for a bad
+// config at startup, and 0 otherwise.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("sysmoni validate", flag.ContinueOnError)
+	path := fs.String("config", "", "config file to validate")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "usage: sysmoni validate -config FILE")
+		return 2
+	}
+
+	cfg, err := config.Load(*path)
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = append(errs, joined.Unwrap()...)
+	} else if err != nil {
+		errs = append(errs, err)
+	}
+	verrs, warnings := config.Validate(cfg)
+	errs = append(errs, verrs...)
+
+	for _, e := range errs {
+		fmt.Printf("error: %v\n", e)
+	}
+	for _, w := range warnings {
+		fmt.Printf("warning: %v\n", w)
+	}
+	fmt.Printf("%s: %d error(s), %d warning(s)\n", *path, len(errs), len(warnings))
+	if len(errs) > 0 {
+		return 2
+	}
+	return 0
+}
+
 // isTTY is a tiny check to avoid pulling in extra deps; good enough for now.
 func isTTY() bool {
 	fi, err := os.Stdout.Stat()
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/sampler"
+)
+
+// fakeSource sends samples until ctx is done and, like the sampler, closes
+// its channel only after a moment of cleanup.
+type fakeSource struct {
+	samples []model.Sample
+	closed  atomic.Bool
+}
+
+func (f *fakeSource) Stream(ctx context.Context) <-chan model.Sample {
+	ch := make(chan model.Sample)
+	go func() {
+		defer close(ch)
+		defer f.closed.Store(true)
+		defer time.Sleep(20 * time.Millisecond)
+		for _, s := range f.samples {
+			select {
+			case ch <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return ch
+}
+
+func TestWatchStreamStopWaitsForStream(t *testing.T) {
+	cfg := config.Default()
+	cfg.Alerts = []string{"cpu>95"} // so watchSamples wraps the stream
+	src := &fakeSource{samples: []model.Sample{{CPU: model.CPU{Total: 10}}}}
+
+	stream, stop := watchStream(context.Background(), src, cfg)
+	<-stream
+	stop()
+	if !src.closed.Load() {
+		t.Fatal("stop returned before the source stream closed")
+	}
+}
+
+// TestOneShotCPU runs `sysmoni -json` against the real sampler on a loaded
+// machine: the single sample must carry a real CPU reading, not the zero of
+// an unprimed delta.
+func TestOneShotCPU(t *testing.T) {
+	if testing.Short() {
+		t.Skip("samples the host")
+	}
+	done := make(chan struct{})
+	defer close(done)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+
+	cfg := config.Default()
+	cfg.JSON = true
+	cfg.EnableGPU = false
+	cfg.Interval = 200 * time.Millisecond
+	cfg.LogFile = filepath.Join(t.TempDir(), "sample.json")
+	if err := runJSON(context.Background(), cfg, sampler.NewWithConfig(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfg.LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samp model.Sample
+	if err := json.Unmarshal(data, &samp); err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	if samp.CPU.Total <= 0 {
+		t.Errorf("one-shot CPU total = %v on a loaded machine", samp.CPU.Total)
+	}
+	if !slices.ContainsFunc(samp.CPU.PerCore, func(c float64) bool { return c > 0 }) {
+		t.Errorf("one-shot per-core CPU = %v on a loaded machine", samp.CPU.PerCore)
+	}
+}
+
+// journalRunner answers journalctl with one earlyoom kill and runs nothing
+// else.
+type journalRunner struct{}
+
+func (journalRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	if name == "journalctl" && slices.Contains(args, "earlyoom") {
+		return `2024-03-04T10:15:02+0100 host earlyoom[812]: sending SIGTERM to process 4242 uid 1000 "chrome": badness 900, VmRSS 2000 MiB` + "\n", nil
+	}
+	return "", nil
+}
+
+// TestJSONKills runs `sysmoni -json` with and without -kills: the kill
+// events appear in the sample only with the flag.
+func TestJSONKills(t *testing.T) {
+	if testing.Short() {
+		t.Skip("samples the host")
+	}
+	for _, kills := range []bool{true, false} {
+		cfg := config.Default()
+		cfg.JSON = true
+		cfg.EnableGPU = false
+		cfg.Kills = kills
+		cfg.EarlyOOMUnit = "earlyoom"
+		cfg.Interval = 200 * time.Millisecond
+		cfg.LogFile = filepath.Join(t.TempDir(), "sample.json")
+		s := sampler.NewWithConfig(cfg)
+		s.Runner = journalRunner{}
+		if err := runJSON(context.Background(), cfg, s); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(cfg.LogFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var samp struct{ Kills []model.KillEvent }
+		if err := json.Unmarshal(data, &samp); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		switch {
+		case kills && (len(samp.Kills) != 1 || samp.Kills[0].PID != 4242 || samp.Kills[0].Command != "chrome"):
+			t.Errorf("-kills: Kills = %+v, want the chrome kill", samp.Kills)
+		case !kills && samp.Kills != nil:
+			t.Errorf("without -kills: Kills = %+v, want none", samp.Kills)
+		}
+	}
+}
+
+// TestValidateExitCode checks that `sysmoni validate` exits 2 on config
+// errors, as startup does.
+func TestValidateExitCode(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"valid", []string{"-config", write("ok.toml", "sort = \"mem\"\n")}, 0},
+		{"unknown key", []string{"-config", write("key.toml", "colour = \"red\"\n")}, 2},
+		{"invalid option", []string{"-config", write("sort.toml", "sort = \"size\"\n")}, 2},
+		{"missing file", []string{"-config", filepath.Join(dir, "missing.toml")}, 2},
+		{"no -config", nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runValidate(tt.args); got != tt.want {
+				t.Errorf("runValidate(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
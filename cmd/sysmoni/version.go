@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// version is set at release time with -ldflags "-X main.version=v1.2.3";
+// otherwise the module version from the build info is used.
+var version = ""
+
+// buildInfo identifies the running binary for bug reports.
+type buildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Go      string `json:"go"`
+	Schema  int    `json:"schema"` // model.SchemaVersion of the JSON output
+}
+
+func readBuildInfo() buildInfo {
+	info := buildInfo{Version: version, Commit: "unknown", Go: runtime.Version(), Schema: model.SchemaVersion}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		if info.Version == "" {
+			info.Version = "unknown"
+		}
+		return info
+	}
+	if info.Version == "" {
+		info.Version = bi.Main.Version // "(devel)" for plain go build
+	}
+	info.Go = bi.GoVersion
+	var dirty bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+			if len(info.Commit) > 12 {
+				info.Commit = info.Commit[:12]
+			}
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if dirty && info.Commit != "unknown" {
+		info.Commit += "-dirty"
+	}
+	return info
+}
+
+// printVersion writes the build info as one line, or as a JSON object when
+// asJSON is set so it can be attached to -json captures.
+func printVersion(w io.Writer, asJSON bool) error {
+	info := readBuildInfo()
+	if asJSON {
+		return json.NewEncoder(w).Encode(info)
+	}
+	_, err := fmt.Fprintf(w, "sysmoni %s (commit %s, %s, schema v%d)\n", info.Version, info.Commit, info.Go, info.Schema)
+	return err
+}
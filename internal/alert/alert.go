@@ -0,0 +1,226 @@
+package alert
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// hysteresis is how far (as a fraction of the threshold) a firing rule's
+// metric must fall back before the alert resolves, so a value hovering at
+// the threshold doesn't fire and resolve on alternate ticks.
+const hysteresis = 0.05
+
+// metric reads one value from a sample. ok is false when the sample doesn't
+// carry it (no GPU, no PSI, ...); a rule on a missing metric never fires.
+// offender, if set, ranks processes to name the likely culprit of an alert.
+type metric struct {
+	value    func(model.Sample) (v float64, ok bool)
+	offender func(model.Process) float64
+}
+
+func procCPU(p model.Process) float64 { return p.CPU }
+func procMem(p model.Process) float64 { return p.Memory }
+func procIO(p model.Process) float64  { return p.ReadKBs + p.WriteKBs }
+
+func percent(used, total uint64) (float64, bool) {
+	if total == 0 {
+		return 0, false
+	}
+	return 100 * float64(used) / float64(total), true
+}
+
+// metrics are the names usable in rules. Percentages are 0-100.
+var metrics = map[string]metric{
+	"cpu":    {func(s model.Sample) (float64, bool) { return s.CPU.Total, true }, procCPU},
+	"iowait": {func(s model.Sample) (float64, bool) { return s.CPU.Iowait, true }, procIO},
+	"steal":  {func(s model.Sample) (float64, bool) { return s.CPU.Steal, true }, nil},
+	"load1":  {func(s model.Sample) (float64, bool) { return s.CPU.Load1, true }, procCPU},
+	"load5":  {func(s model.Sample) (float64, bool) { return s.CPU.Load5, true }, procCPU},
+	"load15": {func(s model.Sample) (float64, bool) { return s.CPU.Load15, true }, procCPU},
+	"mem": {func(s model.Sample) (float64, bool) {
+		return percent(s.Memory.UsedBytes, s.Memory.TotalBytes)
+	}, procMem},
+	"swap": {func(s model.Sample) (float64, bool) {
+		return percent(s.Memory.SwapUsed, s.Memory.SwapTotal)
+	}, procMem},
+	"fds": {func(s model.Sample) (float64, bool) {
+		return percent(s.OpenFDs, s.MaxFDs)
+	}, func(p model.Process) float64 { return float64(p.FDCount) }},
+	"psi-cpu": {func(s model.Sample) (float64, bool) { return s.Pressure.CPU.Some10, s.Pressure.Supported }, procCPU},
+	"psi-mem": {func(s model.Sample) (float64, bool) { return s.Pressure.Memory.Some10, s.Pressure.Supported }, procMem},
+	"psi-io":  {func(s model.Sample) (float64, bool) { return s.Pressure.IO.Some10, s.Pressure.Supported }, procIO},
+	"temp": {func(s model.Sample) (float64, bool) {
+		return maxOf(s.Temps, func(t model.Temp) float64 { return t.Temp })
+	}, nil},
+	"gpu": {func(s model.Sample) (float64, bool) {
+		gpus := slices.DeleteFunc(slices.Clone(s.GPUs), func(g model.GPU) bool { return g.Util == model.GPUUnavailable })
+		return maxOf(gpus, func(g model.GPU) float64 { return g.Util })
+	}, nil},
+	"disk": {func(s model.Sample) (float64, bool) {
+		return maxOf(s.Disks, func(d model.Disk) float64 { return d.Percent })
+	}, nil},
+	"proc-cpu": {func(s model.Sample) (float64, bool) { return maxOf(s.Top, procCPU) }, procCPU},
+	"proc-mem": {func(s model.Sample) (float64, bool) { return maxOf(s.Top, procMem) }, procMem},
+}
+
+func maxOf[T any](xs []T, f func(T) float64) (float64, bool) {
+	if len(xs) == 0 {
+		return 0, false
+	}
+	m := math.Inf(-1)
+	for _, x := range xs {
+		m = max(m, f(x))
+	}
+	return m, true
+}
+
+// Metrics returns the metric names rules may use, sorted.
+func Metrics() []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rule is one parsed -alert rule: Metric above (or below) Threshold for at
+// least For.
+type Rule struct {
+	Spec      string // as written, e.g. "cpu>90:5s"
+	Metric    string
+	Below     bool // "<" rules fire when the value drops under Threshold
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseRule parses "<metric><op><threshold>[%][:<duration>]", where op is >
+// or <, e.g. "cpu>90:5s", "mem>85%" or "psi-mem>20:30s". Without a duration
+// the rule fires on the first sample past the threshold.
+func ParseRule(spec string) (Rule, error) {
+	r := Rule{Spec: spec}
+	cond, dur, hasDur := strings.Cut(strings.TrimSpace(spec), ":")
+	if hasDur {
+		d, err := time.ParseDuration(dur)
+		if err != nil || d < 0 {
+			return Rule{}, fmt.Errorf("alert %q: bad duration %q", spec, dur)
+		}
+		r.For = d
+	}
+	i := strings.IndexAny(cond, "<>")
+	if i < 0 {
+		return Rule{}, fmt.Errorf("alert %q: want <metric>><value> or <metric><<value>", spec)
+	}
+	r.Metric, r.Below = strings.ToLower(strings.TrimSpace(cond[:i])), cond[i] == '<'
+	if _, ok := metrics[r.Metric]; !ok {
+		return Rule{}, fmt.Errorf("alert %q: unknown metric %q (want one of %s)", spec, r.Metric, strings.Join(Metrics(), ", "))
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(cond[i+1:]), "%"), 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("alert %q: bad threshold %q", spec, cond[i+1:])
+	}
+	r.Threshold = v
+	return r, nil
+}
+
+// ParseRules parses every spec, returning the first error.
+func ParseRules(specs []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(specs))
+	for _, spec := range specs {
+		r, err := ParseRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// breached reports whether v is past the threshold. A firing rule needs v
+// to come back past the hysteresis band before it counts as clear.
+func (r Rule) breached(v float64, firing bool) bool {
+	t := r.Threshold
+	if firing {
+		margin := math.Abs(t) * hysteresis
+		if r.Below {
+			t += margin
+		} else {
+			t -= margin
+		}
+	}
+	if r.Below {
+		return v < t
+	}
+	return v > t
+}
+
+type ruleState struct {
+	since  time.Time // when the current breach started; zero if not breached
+	firing bool
+}
+
+// Evaluator tracks rule state across samples. It is not safe for concurrent
+// use; feed it samples in order from one goroutine.
+type Evaluator struct {
+	rules []Rule
+	state []ruleState
+}
+
+// NewEvaluator returns an Evaluator for rules, with nothing firing.
+func NewEvaluator(rules []Rule) *Evaluator {
+	return &Evaluator{rules: slices.Clone(rules), state: make([]ruleState, len(rules))}
+}
+
+// Evaluate updates every rule with s and returns the alerts that changed
+// state: "firing" once a breach has lasted the rule's For, "resolved" once
+// a firing rule's metric is back inside the hysteresis band. Steady states
+// produce nothing, so callers can act on every returned alert.
+func (e *Evaluator) Evaluate(s model.Sample) []model.Alert {
+	var alerts []model.Alert
+	for i, r := range e.rules {
+		st := &e.state[i]
+		m := metrics[r.Metric]
+		v, ok := m.value(s)
+		breached := ok && r.breached(v, st.firing)
+		switch {
+		case breached && st.since.IsZero():
+			st.since = s.Timestamp
+		case !breached:
+			st.since = time.Time{}
+		}
+		var state string
+		switch {
+		case breached && !st.firing && s.Timestamp.Sub(st.since) >= r.For:
+			st.firing, state = true, "firing"
+		case !breached && st.firing:
+			st.firing, state = false, "resolved"
+		default:
+			continue
+		}
+		a := model.Alert{
+			Time:      s.Timestamp,
+			Rule:      r.Spec,
+			Metric:    r.Metric,
+			Value:     v,
+			Threshold: r.Threshold,
+			State:     state,
+			Since:     st.since,
+		}
+		if state == "firing" && m.offender != nil && len(s.Top) > 0 {
+			p := slices.MaxFunc(s.Top, func(a, b model.Process) int {
+				return cmp.Compare(m.offender(a), m.offender(b))
+			})
+			a.PID, a.Command = p.PID, p.Command
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
@@ -0,0 +1,84 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+func TestEvaluate(t *testing.T) {
+	type step struct {
+		at    time.Duration // since the first sample
+		cpu   float64
+		state string // "" when no alert changes state
+	}
+	tests := []struct {
+		name  string
+		rule  string
+		steps []step
+	}{
+		{"fires at once without a duration", "cpu>90", []step{
+			{0, 50, ""}, {time.Second, 95, "firing"}, {2 * time.Second, 96, ""},
+		}},
+		{"waits for the hold duration", "cpu>90:5s", []step{
+			{0, 95, ""}, {2 * time.Second, 95, ""}, {4 * time.Second, 95, ""}, {5 * time.Second, 95, "firing"}, {6 * time.Second, 95, ""},
+		}},
+		{"a dip restarts the hold", "cpu>90:5s", []step{
+			{0, 95, ""}, {4 * time.Second, 80, ""}, {5 * time.Second, 95, ""}, {9 * time.Second, 95, ""}, {10 * time.Second, 95, "firing"},
+		}},
+		{"resolves below the hysteresis band", "cpu>90", []step{
+			{0, 95, "firing"},
+			{time.Second, 89, ""}, // inside 90-5%: still firing
+			{2 * time.Second, 85, "resolved"},
+			{3 * time.Second, 85, ""},
+			{4 * time.Second, 91, "firing"},
+		}},
+		{"below rule", "cpu<10:2s", []step{
+			{0, 5, ""}, {2 * time.Second, 5, "firing"}, {3 * time.Second, 10.2, ""}, {4 * time.Second, 11, "resolved"},
+		}},
+	}
+	base := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ev := NewEvaluator([]Rule{r})
+			for i, st := range tt.steps {
+				alerts := ev.Evaluate(model.Sample{Timestamp: base.Add(st.at), CPU: model.CPU{Total: st.cpu}})
+				var got string
+				switch len(alerts) {
+				case 0:
+				case 1:
+					got = alerts[0].State
+				default:
+					t.Fatalf("step %d: %d alerts from one rule", i, len(alerts))
+				}
+				if got != st.state {
+					t.Errorf("step %d (cpu %v at %s): state %q, want %q", i, st.cpu, st.at, got, st.state)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateNamesOffender(t *testing.T) {
+	r, err := ParseRule("mem>80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := model.Sample{
+		Timestamp: time.Now(),
+		Memory:    model.Memory{UsedBytes: 90, TotalBytes: 100},
+		Top: []model.Process{
+			{PID: 1, Command: "small", Memory: 2},
+			{PID: 2, Command: "hog", Memory: 60},
+		},
+	}
+	alerts := NewEvaluator([]Rule{r}).Evaluate(s)
+	if len(alerts) != 1 || alerts[0].PID != 2 || alerts[0].Command != "hog" {
+		t.Errorf("alerts = %+v, want one naming PID 2 hog", alerts)
+	}
+}
@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// HookArgs expands tmpl for a: {pid}, {comm}, {metric}, {value}, {threshold},
+// {rule} and {state} are substituted within each whitespace-separated word.
+// The command is run directly, not through a shell, so a process name can't
+// inject anything; wrap the hook in a script for pipes or redirection.
+//
+// A template using {pid} or {comm} is refused for an alert without a process:
+// "renice -n 19 -p {pid}" must never become "renice -p 0".
+func HookArgs(tmpl string, a model.Alert) ([]string, error) {
+	words := strings.Fields(tmpl)
+	if len(words) == 0 {
+		return nil, errors.New("empty hook command")
+	}
+	if a.PID == 0 && (strings.Contains(tmpl, "{pid}") || strings.Contains(tmpl, "{comm}")) {
+		return nil, fmt.Errorf("alert %q names no process for {pid}/{comm}", a.Rule)
+	}
+	r := strings.NewReplacer(
+		"{pid}", strconv.Itoa(a.PID),
+		"{comm}", a.Command,
+		"{metric}", a.Metric,
+		"{value}", strconv.FormatFloat(a.Value, 'f', 1, 64),
+		"{threshold}", strconv.FormatFloat(a.Threshold, 'f', -1, 64),
+		"{rule}", a.Rule,
+		"{state}", a.State,
+	)
+	for i, w := range words {
+		words[i] = r.Replace(w)
+	}
+	return words, nil
+}
+
+// hookEnv describes a to the hook as SYSMONI_ALERT_* variables, added to
+// sysmoni's own environment.
+func hookEnv(a model.Alert) []string {
+	return append(os.Environ(),
+		"SYSMONI_ALERT_RULE="+a.Rule,
+		"SYSMONI_ALERT_STATE="+a.State,
+		"SYSMONI_ALERT_METRIC="+a.Metric,
+		"SYSMONI_ALERT_VALUE="+strconv.FormatFloat(a.Value, 'f', 1, 64),
+		"SYSMONI_ALERT_THRESHOLD="+strconv.FormatFloat(a.Threshold, 'f', -1, 64),
+		"SYSMONI_ALERT_PID="+strconv.Itoa(a.PID),
+		"SYSMONI_ALERT_COMM="+a.Command,
+	)
+}
+
+// RunHook runs tmpl expanded for a and waits for it, killing it after
+// timeout. The hook also gets a in SYSMONI_ALERT_* environment variables
+// and as one JSON line on stdin. Combined output is included in the error
+// on failure.
+func RunHook(ctx context.Context, tmpl string, a model.Alert, timeout time.Duration) error {
+	args, err := HookArgs(tmpl, a)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = hookEnv(a)
+	cmd.Stdin = bytes.NewReader(append(line, '\n'))
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s: timed out after %s", args[0], timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Hook runs a command template (-on-alert) for firing alerts in the
+// background. At most one hook runs at a time, and each rule triggers it at
+// most once per cooldown, so a flapping rule or a stuck command can't pile
+// up processes.
+type Hook struct {
+	tmpl     string
+	timeout  time.Duration
+	cooldown time.Duration
+
+	mu      sync.Mutex
+	running bool
+	last    map[string]time.Time // rule -> last run
+}
+
+// NewHook returns a Hook for tmpl.
+func NewHook(tmpl string, timeout, cooldown time.Duration) *Hook {
+	return &Hook{tmpl: tmpl, timeout: timeout, cooldown: cooldown, last: make(map[string]time.Time)}
+}
+
+// Fire starts the hook for a if it is firing and not rate-limited, and
+// returns immediately. Skipped and failed runs are logged.
+func (h *Hook) Fire(ctx context.Context, a model.Alert) {
+	if a.State != "firing" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.running {
+		slog.Warn("alert hook skipped: previous run still active", "rule", a.Rule)
+		return
+	}
+	if last, ok := h.last[a.Rule]; ok && time.Since(last) < h.cooldown {
+		slog.Info("alert hook skipped: cooldown", "rule", a.Rule, "cooldown", h.cooldown)
+		return
+	}
+	h.running = true
+	h.last[a.Rule] = time.Now()
+	go func() {
+		err := RunHook(ctx, h.tmpl, a, h.timeout)
+		if err != nil {
+			slog.Warn("alert hook failed", "rule", a.Rule, "err", err)
+		} else {
+			slog.Info("alert hook ran", "rule", a.Rule, "pid", a.PID)
+		}
+		h.mu.Lock()
+		h.running = false
+		h.mu.Unlock()
+	}()
+}
@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+var hookAlert = model.Alert{
+	Rule:      "cpu>90:5s",
+	Metric:    "cpu",
+	Value:     97.25,
+	Threshold: 90,
+	State:     "firing",
+	PID:       4242,
+	Command:   "stress --cpu 8",
+}
+
+func TestHookArgs(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		a       model.Alert
+		want    []string
+		wantErr bool
+	}{
+		{"renice -n 19 -p {pid}", hookAlert, []string{"renice", "-n", "19", "-p", "4242"}, false},
+		// {comm} stays one argument even with spaces or shell syntax in it.
+		{"notify {comm}", model.Alert{PID: 1, Command: "x; rm -rf /"}, []string{"notify", "x; rm -rf /"}, false},
+		{"log {metric}={value} over {threshold} ({state})", hookAlert, []string{"log", "cpu=97.2", "over", "90", "(firing)"}, false},
+		{"kill {pid}", model.Alert{Rule: "mem>90"}, nil, true},
+		{"  ", hookAlert, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := HookArgs(tt.tmpl, tt.a)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("HookArgs(%q) error = %v, want error %v", tt.tmpl, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("HookArgs(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestRunHookTimeout(t *testing.T) {
+	start := time.Now()
+	err := RunHook(context.Background(), "sleep 10", hookAlert, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("RunHook error = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("RunHook took %s; the hook was not killed", d)
+	}
+}
+
+// TestRunHookInput has a script record its environment and stdin.
+func TestRunHookInput(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nenv > \"$1.env\"\ncat > \"$1.stdin\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := RunHook(context.Background(), script+" "+out, hookAlert, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := os.ReadFile(out + ".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := strings.Split(string(env), "\n")
+	for _, want := range []string{
+		"SYSMONI_ALERT_RULE=cpu>90:5s",
+		"SYSMONI_ALERT_STATE=firing",
+		"SYSMONI_ALERT_METRIC=cpu",
+		"SYSMONI_ALERT_VALUE=97.2",
+		"SYSMONI_ALERT_THRESHOLD=90",
+		"SYSMONI_ALERT_PID=4242",
+		"SYSMONI_ALERT_COMM=stress --cpu 8",
+	} {
+		if !slices.Contains(vars, want) {
+			t.Errorf("hook environment lacks %s", want)
+		}
+	}
+	if os.Getenv("PATH") != "" && !slices.ContainsFunc(vars, func(v string) bool { return strings.HasPrefix(v, "PATH=") }) {
+		t.Error("hook environment lacks sysmoni's own PATH")
+	}
+
+	stdin, err := os.ReadFile(out + ".stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got model.Alert
+	if err := json.Unmarshal(stdin, &got); err != nil {
+		t.Fatalf("stdin %q: %v", stdin, err)
+	}
+	if got != hookAlert {
+		t.Errorf("stdin alert = %+v, want %+v", got, hookAlert)
+	}
+}
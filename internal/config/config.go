@@ -2,7 +2,13 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"io"
+	"math"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,50 +16,539 @@ import (
 type Config struct {
 	Interval   time.Duration
 	Sort       string
+	Sort2      string
 	Filter     string
 	JSON       bool
 	JSONStream bool
 	EnableGPU  bool
 	EnableBatt bool
+	// Adaptive lets the sampler shorten the interval, down to AdaptiveMin,
+	// while CPU, memory or PSI pressure is high, and lengthen it, up to
+	// AdaptiveMax, while the host is idle. Interval is the starting point.
+	Adaptive    bool
+	AdaptiveMin time.Duration
+	AdaptiveMax time.Duration
+	// GPUInterval is how often GPU tools are polled, off the main tick.
+	// GPUTimeout bounds each nvidia-smi/intel_gpu_top run (rocm-smi, a
+	// Python script, always gets at least 1s). Slow or loaded hosts may need
+	// more than the 400ms default.
+	GPUInterval time.Duration
+	GPUTimeout  time.Duration
+	// EnableCgroups gates per-process cgroup lookups and cgroup aggregates.
+	EnableCgroups bool
+
+	// Threads enumerates /proc/<pid>/task for per-thread CPU (expensive).
+	Threads bool
+
+	// Schedstat reads /proc/schedstat for run-queue latency (needs CONFIG_SCHEDSTATS).
+	Schedstat bool
+
+	// NetSoftirq reports NET_RX/NET_TX softirq load per CPU.
+	NetSoftirq bool
+
+	// Connections counts TCP/UDP sockets on a slow background loop.
+	Connections bool
+
+	// DiskUsage reports filesystem capacity per mount on a slow background
+	// loop; DiskUsageAll includes pseudo filesystems (tmpfs, proc, ...).
+	DiskUsage    bool
+	DiskUsageAll bool
+
+	// DiskExclude lists block-device name globs left out of disk I/O; a
+	// pattern without wildcards matches as a name prefix. DiskInclude
+	// overrides it: a device matching DiskInclude is always counted.
+	DiskExclude []string
+	DiskInclude []string
+
+	// Kills includes recent OOM kill events in Sample.Kills, refreshed from
+	// the journal on a slow background loop.
+	Kills bool
+
+	// systemd units whose journals are searched for OOM kills ("" skips one).
+	EarlyOOMUnit string
+	OOMDUnit     string
+
+	// Totals accumulates session/boot totals into Sample.Totals.
+	Totals bool
+
+	// Alerts are threshold rules such as "cpu>90:5s" (see package alert);
+	// state changes are written to stderr as JSON lines.
+	Alerts []string
+
+	// OnAlert is a command template run when an alert fires, e.g.
+	// "renice -n 19 -p {pid}". Each run is killed after OnAlertTimeout, and a
+	// rule re-triggers it at most once per OnAlertCooldown.
+	OnAlert         string
+	OnAlertTimeout  time.Duration
+	OnAlertCooldown time.Duration
+
+	// ProtectCPU, when positive, renices processes whose CPU percent stays
+	// above it for ProtectAfter to ProtectNice, optionally also moving them
+	// to the idle I/O class. ProtectDryRun only logs what would be done.
+	ProtectCPU    float64
+	ProtectAfter  time.Duration
+	ProtectNice   int
+	ProtectIdleIO bool
+	ProtectDryRun bool
+
+	// CapCgroups are "name:percent" rules: a cgroup using more CPU than its
+	// percent gets cpu.max set to that much. Nothing is written unless
+	// Enforce is set; changed limits are restored on exit.
+	CapCgroups []string
+	Enforce    bool
+
+	// Rates adds per-second changes of memory, swap and open files since the
+	// previous sample in Sample.Rates.
+	Rates bool
+
+	// Cadence runs a collector only every N ticks (e.g. "procs" -> 2),
+	// reusing its last result in between; unlisted collectors run every
+	// tick. Keys are CadenceCollectors.
+	Cadence map[string]int
+
+	// SparkDepth is how many samples the TUI sparklines keep; they are drawn
+	// as wide as the terminal allows, up to that many.
+	SparkDepth int
+
+	// WarnPct and CritPct split CPU, memory and GPU utilization into green,
+	// yellow and red in the TUI. Temperatures turn red at TempCrit (°C).
+	WarnPct  float64
+	CritPct  float64
+	TempCrit float64
+
+	// NoColor renders the TUI in monochrome (as does NO_COLOR in the
+	// environment).
+	NoColor bool
+
+	// Theme is the TUI palette, one of Themes.
+	Theme string
+
+	// Tree starts the TUI with processes grouped under their parents.
+	Tree bool
+
+	// Top caps the process list (0 = unlimited); throttled and cgroup lists
+	// get half and a quarter of it.
+	Top int
+
+	// Processes below either threshold (percent) are dropped from ranked lists.
+	MinCPU float64
+	MinMem float64
+
+	// CSV writes flat CSV rows instead of JSON (one-shot, or streamed with
+	// -json-stream).
+	CSV bool
+
+	// Plain prints a compact text table every interval instead of the TUI,
+	// redrawn in place on a terminal and appended otherwise.
+	Plain bool
+
+	// Prometheus, if set, is the listen address for a /metrics exporter.
+	Prometheus string
+
+	// History is how many recent samples the sampler keeps in memory for
+	// Sampler.History (0 = none).
+	History int
+
+	// HTTP, if set, is the listen address for the JSON API (/sample and
+	// /samples), which keeps the last HTTPHistory samples. HTTPCORS is sent
+	// as Access-Control-Allow-Origin; "" sends no CORS headers.
+	HTTP        string
+	HTTPHistory int
+	HTTPCORS    string
+
+	// Samples is how many intervals one-shot -json averages.
+	Samples int
+
+	// ChangeOnly suppresses streamed samples that moved less than
+	// ChangeThreshold since the last one emitted; Heartbeat forces one out.
+	ChangeOnly      bool
+	ChangeThreshold float64
+	Heartbeat       time.Duration
+
+	// PerCore sets per-core CPU detail in JSON output: full|int|summary|none.
+	PerCore string
+
+	// TimestampFormat is how Timestamp is written in JSON output and the
+	// HTTP API: rfc3339|epoch|epoch-ms.
+	TimestampFormat string
+
+	// Diagnostic logging (log/slog). LogPath "" means stderr, or a file in
+	// the temp dir while the TUI owns the terminal.
+	LogLevel  string
+	LogFormat string
+	LogPath   string
+
+	// NDJSON destination and compression ("" = stdout, auto|none|gzip;
+	// auto gzips when LogFile ends in .gz). -output is an alias of -log-file.
+	LogFile       string
+	Compress      string
+	CompressLevel int
+
+	// RotateSize and RotateEvery (-rotate 100MB or -rotate 1h) start a new,
+	// timestamped output file once the current one is that big or old;
+	// Keep deletes all but the newest Keep files (0 = keep all).
+	RotateSize  int64
+	RotateEvery time.Duration
+	Keep        int
+
+	// Replay plays an NDJSON capture back in the TUI instead of sampling
+	// the host, ReplaySpeed times faster than it was recorded.
+	Replay      string
+	ReplaySpeed float64
+
+	// Version prints build information and exits.
+	Version bool
+
+	// ConfigPath is the config file the options were loaded from, if any.
+	ConfigPath string
+	// EnvWarnings lists SRPS_SYSMONI_* values that could not be parsed and
+	// were ignored.
+	EnvWarnings []error
 }
 
 func Default() Config {
 	return Config{
 		Interval:   time.Second,
 		Sort:       "cpu",
+		Sort2:      "",
 		Filter:     "",
 		JSON:       false,
 		JSONStream: false,
 		EnableGPU:  true,
 		EnableBatt: true,
+
+		EnableCgroups: true,
+		AdaptiveMin:   250 * time.Millisecond,
+		AdaptiveMax:   5 * time.Second,
+		GPUInterval:   2 * time.Second,
+		GPUTimeout:    400 * time.Millisecond,
+		Top:           64,
+		DiskExclude:   []string{"loop*", "dm-*", "ram*", "sr*"},
+		EarlyOOMUnit:  "earlyoom",
+		OOMDUnit:      "systemd-oomd",
+
+		Samples:    1,
+		SparkDepth: 60,
+		WarnPct:    60,
+		CritPct:    85,
+		TempCrit:   85,
+		Theme:      "dark",
+
+		HTTPHistory: 300,
+
+		ChangeThreshold: 5,
+		Heartbeat:       time.Minute,
+
+		OnAlertTimeout:  10 * time.Second,
+		OnAlertCooldown: time.Minute,
+
+		ProtectAfter: 10 * time.Second,
+		ProtectNice:  10,
+
+		PerCore: "full",
+
+		TimestampFormat: "rfc3339",
+
+		ReplaySpeed: 1,
+
+		LogLevel:  "warn",
+		LogFormat: "text",
+
+		Compress:      "auto",
+		CompressLevel: -1,
 	}
 }
 
-// FromFlags parses flags and environment overrides.
-func FromFlags(args []string) Config {
-	cfg := Default()
+// newFlagSet binds every option to cfg. Flags and config-file keys share these
+// names, so both paths go through the same parsing.
+func newFlagSet(cfg *Config) *flag.FlagSet {
 	fs := flag.NewFlagSet("sysmoni", flag.ContinueOnError)
 	fs.DurationVar(&cfg.Interval, "interval", cfg.Interval, "refresh interval")
-	fs.StringVar(&cfg.Sort, "sort", cfg.Sort, "sort column: cpu|mem")
-	fs.StringVar(&cfg.Filter, "filter", cfg.Filter, "regex filter for process names")
+	fs.StringVar(&cfg.Sort, "sort", cfg.Sort, "sort column: cpu|mem|io|fd|peak")
+	fs.StringVar(&cfg.Sort2, "sort2", cfg.Sort2, "secondary sort column used to break ties: cpu|mem|io|fd|peak")
+	fs.StringVar(&cfg.Filter, "filter", cfg.Filter, "regex: only report processes whose name or command line matches")
 	fs.BoolVar(&cfg.JSON, "json", cfg.JSON, "output one-shot JSON and exit")
 	fs.BoolVar(&cfg.JSONStream, "json-stream", cfg.JSONStream, "stream NDJSON until interrupted")
 	fs.BoolVar(&cfg.EnableGPU, "gpu", cfg.EnableGPU, "enable GPU sampling")
+	fs.BoolVar(&cfg.Adaptive, "adaptive", cfg.Adaptive, "sample faster under pressure and slower when idle, between -adaptive-min and -adaptive-max")
+	fs.DurationVar(&cfg.AdaptiveMin, "adaptive-min", cfg.AdaptiveMin, "shortest interval with -adaptive")
+	fs.DurationVar(&cfg.AdaptiveMax, "adaptive-max", cfg.AdaptiveMax, "longest interval with -adaptive")
+	fs.DurationVar(&cfg.GPUInterval, "gpu-interval", cfg.GPUInterval, "how often GPU tools are polled (min 500ms)")
+	fs.DurationVar(&cfg.GPUTimeout, "gpu-timeout", cfg.GPUTimeout, "kill a GPU tool that takes longer than this (min 100ms)")
 	fs.BoolVar(&cfg.EnableBatt, "battery", cfg.EnableBatt, "enable battery sampling")
-	_ = fs.Parse(args)
+	fs.BoolVar(&cfg.Threads, "threads", cfg.Threads, "report per-thread CPU for top processes (expensive)")
+	fs.BoolVar(&cfg.Schedstat, "schedstat", cfg.Schedstat, "report run-queue latency from /proc/schedstat")
+	fs.BoolVar(&cfg.NetSoftirq, "net-softirq", cfg.NetSoftirq, "report per-CPU network softirq load from /proc/softirqs")
+	fs.BoolVar(&cfg.Connections, "connections", cfg.Connections, "count TCP/UDP sockets by state (refreshed every 5s)")
+	fs.BoolVar(&cfg.DiskUsage, "disk-usage", cfg.DiskUsage, "report used/total capacity per mounted filesystem (refreshed every 10s)")
+	fs.BoolVar(&cfg.DiskUsageAll, "disk-usage-all", cfg.DiskUsageAll, "with -disk-usage, include pseudo filesystems (tmpfs, proc, sysfs, ...)")
+	fs.Func("disk-exclude", `comma-separated block device globs to skip in disk I/O (default "loop*,dm-*,ram*,sr*"; "" = none)`, func(v string) error {
+		cfg.DiskExclude = splitList(v)
+		return nil
+	})
+	fs.Func("disk-include", `comma-separated block device globs counted in disk I/O even if -disk-exclude matches (e.g. "dm-*")`, func(v string) error {
+		cfg.DiskInclude = splitList(v)
+		return nil
+	})
+	fs.BoolVar(&cfg.Kills, "kills", cfg.Kills, "include recent earlyoom/systemd-oomd/kernel OOM kills (refreshed every 30s)")
+	fs.StringVar(&cfg.EarlyOOMUnit, "earlyoom-unit", cfg.EarlyOOMUnit, `systemd unit searched for earlyoom kills ("" = skip)`)
+	fs.StringVar(&cfg.OOMDUnit, "oomd-unit", cfg.OOMDUnit, `systemd unit searched for systemd-oomd kills ("" = skip)`)
+	fs.BoolVar(&cfg.Totals, "totals", cfg.Totals, "report cumulative CPU/disk/net totals since start and since boot")
+	fs.Func("alert", `comma-separated alert rules, e.g. "cpu>90:5s,mem>85%"; fired/resolved events go to stderr as JSON`, func(v string) error {
+		cfg.Alerts = splitList(v)
+		return nil
+	})
+	fs.StringVar(&cfg.OnAlert, "on-alert", cfg.OnAlert, `command run when an alert fires; {pid} {comm} {metric} {value} {threshold} {rule} {state} are substituted (no shell)`)
+	fs.DurationVar(&cfg.OnAlertTimeout, "on-alert-timeout", cfg.OnAlertTimeout, "kill an -on-alert command after this long")
+	fs.DurationVar(&cfg.OnAlertCooldown, "on-alert-cooldown", cfg.OnAlertCooldown, "minimum time between -on-alert runs for the same rule")
+	fs.Float64Var(&cfg.ProtectCPU, "protect-cpu", cfg.ProtectCPU, "renice processes above this CPU percent for -protect-after (0 = off)")
+	fs.DurationVar(&cfg.ProtectAfter, "protect-after", cfg.ProtectAfter, "how long a process must stay above -protect-cpu before it is reniced")
+	fs.IntVar(&cfg.ProtectNice, "protect-nice", cfg.ProtectNice, "nice value given to processes caught by -protect-cpu (1-19)")
+	fs.BoolVar(&cfg.ProtectIdleIO, "protect-idle-io", cfg.ProtectIdleIO, "with -protect-cpu, also move caught processes to the idle I/O class")
+	fs.BoolVar(&cfg.ProtectDryRun, "protect-dry-run", cfg.ProtectDryRun, "with -protect-cpu, only log what would be done")
+	fs.Func("cap-cgroup", `comma-separated name:percent rules, e.g. "backup.service:50%"; caps a cgroup's cpu.max once it exceeds percent of one core (dry run without -enforce)`, func(v string) error {
+		cfg.CapCgroups = splitList(v)
+		return nil
+	})
+	fs.BoolVar(&cfg.Enforce, "enforce", cfg.Enforce, "let -cap-cgroup actually write cpu.max (otherwise it only logs)")
+	fs.BoolVar(&cfg.Rates, "rates", cfg.Rates, "report per-second change of used memory, used swap and open files")
+	fs.Func("cadence", `comma-separated collector=N pairs, e.g. "procs=2,sensors=10": run a collector only every N ticks (`+strings.Join(CadenceCollectors, ", ")+`)`, func(v string) error {
+		c, err := parseCadence(v)
+		if err != nil {
+			return err
+		}
+		cfg.Cadence = c
+		return nil
+	})
+	fs.IntVar(&cfg.SparkDepth, "spark-depth", cfg.SparkDepth, "samples of history behind the TUI sparklines (they widen with the terminal up to this)")
+	fs.Float64Var(&cfg.WarnPct, "warn-pct", cfg.WarnPct, "TUI: utilization percent at which CPU/memory/GPU turn yellow")
+	fs.Float64Var(&cfg.CritPct, "crit-pct", cfg.CritPct, "TUI: utilization percent at which CPU/memory/GPU turn red")
+	fs.Float64Var(&cfg.TempCrit, "temp-crit", cfg.TempCrit, "TUI: temperature in °C at which sensors turn red and raise the temperature alert")
+	fs.BoolVar(&cfg.NoColor, "no-color", cfg.NoColor, "TUI: render without colors (also when NO_COLOR is set)")
+	fs.StringVar(&cfg.Theme, "theme", cfg.Theme, "TUI color theme: "+strings.Join(Themes, "|"))
+	fs.BoolVar(&cfg.Tree, "tree", cfg.Tree, "TUI: start in the process tree view (T toggles it)")
+	fs.IntVar(&cfg.Top, "top", cfg.Top, "max processes reported (0 = unlimited); throttled/cgroup lists get half/quarter")
+	fs.Float64Var(&cfg.MinCPU, "min-cpu", cfg.MinCPU, "omit processes below this CPU percent")
+	fs.Float64Var(&cfg.MinMem, "min-mem", cfg.MinMem, "omit processes below this memory percent")
+	fs.BoolVar(&cfg.EnableCgroups, "cgroups", cfg.EnableCgroups, "enable cgroup aggregation (CPU, io.stat)")
+	fs.BoolVar(&cfg.CSV, "csv", cfg.CSV, "write CSV rows instead of JSON (stream with -json-stream)")
+	fs.BoolVar(&cfg.Plain, "plain", cfg.Plain, "print a plain-text process table every interval instead of the TUI")
+	fs.StringVar(&cfg.Prometheus, "prometheus", cfg.Prometheus, "serve Prometheus metrics on this address (e.g. :9102)")
+	fs.IntVar(&cfg.History, "history", cfg.History, "keep this many recent samples in memory (0 = none)")
+	fs.StringVar(&cfg.HTTP, "http", cfg.HTTP, "serve the latest samples as JSON on this address (e.g. :8080): GET /sample, /samples?n=60")
+	fs.IntVar(&cfg.HTTPHistory, "http-history", cfg.HTTPHistory, "how many recent samples -http keeps for /samples")
+	fs.StringVar(&cfg.HTTPCORS, "http-cors", cfg.HTTPCORS, `with -http, allow browser requests from this origin ("*" = any; default: no CORS)`)
+	fs.IntVar(&cfg.Samples, "samples", cfg.Samples, "one-shot mode: average this many intervals before emitting")
+	fs.BoolVar(&cfg.ChangeOnly, "change-only", cfg.ChangeOnly, "with -json-stream, only emit samples that changed meaningfully")
+	fs.Float64Var(&cfg.ChangeThreshold, "change-threshold", cfg.ChangeThreshold, "change-only sensitivity in percent (points for utilizations, relative for rates)")
+	fs.DurationVar(&cfg.Heartbeat, "heartbeat", cfg.Heartbeat, "change-only: emit a sample at least this often")
+	fs.StringVar(&cfg.PerCore, "percore", cfg.PerCore, "per-core CPU in JSON output: full|int|summary|none")
+	fs.StringVar(&cfg.TimestampFormat, "timestamp-format", cfg.TimestampFormat, "JSON timestamp encoding: rfc3339|epoch|epoch-ms")
+	fs.BoolFunc("no-percore", "omit per-core CPU from JSON output (same as -percore none)", func(string) error {
+		cfg.PerCore = "none"
+		return nil
+	})
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "diagnostic log level: debug|info|warn|error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "diagnostic log format: text|json")
+	fs.StringVar(&cfg.LogPath, "log-path", cfg.LogPath, "write diagnostic logs to this file (default stderr; TUI: $TMPDIR/sysmoni.log)")
+	fs.StringVar(&cfg.LogFile, "log-file", cfg.LogFile, "write JSON/NDJSON to this file instead of stdout")
+	fs.StringVar(&cfg.LogFile, "output", cfg.LogFile, "alias of -log-file (gzip-compressed when the name ends in .gz)")
+	fs.StringVar(&cfg.Compress, "compress", cfg.Compress, "compress JSON output: auto|none|gzip (auto = gzip for .gz files)")
+	fs.IntVar(&cfg.CompressLevel, "compress-level", cfg.CompressLevel, "gzip level 1-9 (-1 = default)")
+	fs.Func("rotate", `with -output, start a new timestamped file once the current one reaches a size ("100MB") or age ("1h")`, func(v string) error {
+		size, every, err := parseRotate(v)
+		if err != nil {
+			return err
+		}
+		cfg.RotateSize, cfg.RotateEvery = size, every
+		return nil
+	})
+	fs.StringVar(&cfg.Replay, "replay", cfg.Replay, "review an NDJSON capture (-json-stream output, optionally .gz) in the TUI instead of sampling")
+	fs.Float64Var(&cfg.ReplaySpeed, "replay-speed", cfg.ReplaySpeed, "with -replay, play back this many times faster than real time")
+	fs.IntVar(&cfg.Keep, "keep", cfg.Keep, "with -rotate, delete all but the newest N output files (0 = keep all)")
+	fs.StringVar(&cfg.ConfigPath, "config", cfg.ConfigPath, "load options from this TOML file; env and flags override it")
+	fs.BoolVar(&cfg.Version, "version", cfg.Version, "print version, commit and Go version, then exit (JSON with -json)")
+	return fs
+}
+
+// FromArgs builds the runtime config. Precedence, lowest first: Default,
+// the config file, SRPS_SYSMONI_* environment variables, command-line flags.
+// The file is configPath, or the -config flag when that is given; a missing
+// or malformed file is an error rather than being ignored.
+func FromArgs(args []string, getenv func(string) string, configPath string) (Config, error) {
+	// First pass only finds -config; flags are applied last, on top of
+	// everything else.
+	scratch := Default()
+	fs := newFlagSet(&scratch)
+	if err := fs.Parse(args); err != nil {
+		return scratch, err
+	}
+	if scratch.ConfigPath != "" {
+		configPath = scratch.ConfigPath
+	}
+
+	cfg := Default()
+	if configPath != "" {
+		f, err := os.Open(configPath)
+		if err != nil {
+			return cfg, fmt.Errorf("config: %w", err)
+		}
+		err = apply(&cfg, f, configPath)
+		f.Close()
+		if err != nil {
+			return cfg, err
+		}
+	}
+	applyEnv(&cfg, getenv)
+
+	fs = newFlagSet(&cfg)
+	fs.SetOutput(io.Discard) // usage/errors were already reported by the first pass
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	cfg.ConfigPath = configPath
+	return cfg, nil
+}
 
-	if v := os.Getenv("SRPS_SYSMONI_INTERVAL"); v != "" {
-		if parsed, err := time.ParseDuration(v); err == nil {
-			cfg.Interval = parsed
-		} else if parsed, err2 := time.ParseDuration(v + "s"); err2 == nil {
-			cfg.Interval = parsed
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, f := range strings.Split(v, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
 		}
 	}
-	if v := os.Getenv("SRPS_SYSMONI_GPU"); v == "0" {
+	return out
+}
+
+// sizeUnits are the -rotate size suffixes, longest first so "MB" isn't read
+// as "B".
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+}
+
+// parseRotate parses -rotate as a duration ("1h") or a size ("100MB",
+// "1.5GiB"; KB/MB/GB are binary). "" disables rotation.
+func parseRotate(v string) (size int64, every time.Duration, err error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, 0, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		if d <= 0 {
+			return 0, 0, fmt.Errorf("rotate interval must be positive, got %s", d)
+		}
+		return 0, d, nil
+	}
+	num, mult := strings.ToUpper(v), 1.0
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, mult = strings.TrimSpace(n), u.bytes
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f*mult < 1 {
+		return 0, 0, fmt.Errorf("rotate %q is not a size like 100MB or a duration like 1h", v)
+	}
+	return int64(f * mult), 0, nil
+}
+
+// parseCadence parses "procs=2,sensors=10". Every collector must be one of
+// CadenceCollectors and N a positive integer.
+func parseCadence(v string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, f := range splitList(v) {
+		name, n, ok := strings.Cut(f, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("cadence %q: want collector=N", f)
+		}
+		if !slices.Contains(CadenceCollectors, name) {
+			return nil, fmt.Errorf("cadence %q: collector %q is not one of %v", f, name, CadenceCollectors)
+		}
+		every, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil || every < 1 {
+			return nil, fmt.Errorf("cadence %q: N must be a positive integer", f)
+		}
+		out[name] = every
+	}
+	return out, nil
+}
+
+// applyEnv applies SRPS_SYSMONI_* overrides. Unparsable values are ignored
+// and recorded in cfg.EnvWarnings.
+func applyEnv(cfg *Config, getenv func(string) string) {
+	bad := func(name, v string, err error) {
+		cfg.EnvWarnings = append(cfg.EnvWarnings, fmt.Errorf("ignoring %s=%q: %v", name, v, err))
+	}
+	if v := getenv("SRPS_SYSMONI_INTERVAL"); v != "" {
+		if d, err := parseEnvInterval(v); err == nil {
+			cfg.Interval = d
+		} else {
+			bad("SRPS_SYSMONI_INTERVAL", v, err)
+		}
+	}
+	if v := getenv("SRPS_SYSMONI_GPU"); v == "0" {
 		cfg.EnableGPU = false
 	}
-	if v := os.Getenv("SRPS_SYSMONI_BATT"); v == "0" {
+	if v := getenv("SRPS_SYSMONI_GPU_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.GPUInterval = d
+		} else {
+			bad("SRPS_SYSMONI_GPU_INTERVAL", v, err)
+		}
+	}
+	if v := getenv("SRPS_SYSMONI_GPU_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.GPUTimeout = d
+		} else {
+			bad("SRPS_SYSMONI_GPU_TIMEOUT", v, err)
+		}
+	}
+	if v := getenv("SRPS_SYSMONI_BATT"); v == "0" {
 		cfg.EnableBatt = false
 	}
-	return cfg
+	if v := getenv("SRPS_SYSMONI_CGROUPS"); v == "0" {
+		cfg.EnableCgroups = false
+	}
+	if v := getenv("SRPS_SYSMONI_CADENCE"); v != "" {
+		if c, err := parseCadence(v); err == nil {
+			cfg.Cadence = c
+		} else {
+			bad("SRPS_SYSMONI_CADENCE", v, err)
+		}
+	}
+	if v := getenv("SRPS_SYSMONI_THEME"); v != "" {
+		cfg.Theme = v
+	}
+	if v := getenv("SRPS_SYSMONI_TOP"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.Top = n
+		} else {
+			bad("SRPS_SYSMONI_TOP", v, fmt.Errorf("want a non-negative integer"))
+		}
+	}
+}
+
+// parseEnvInterval parses SRPS_SYSMONI_INTERVAL. A bare number is seconds
+// ("2", "0.5"), as the shell script's SRPS_SYSMONI_INTERVAL always was;
+// anything else must be a Go duration ("500ms", "5m").
+func parseEnvInterval(v string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if math.IsNaN(secs) || math.IsInf(secs, 0) || math.Abs(secs) > math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("not a usable number of seconds")
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("want seconds (e.g. 2) or a duration (e.g. 500ms, 5m)")
+	}
+	return d, nil
 }
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEnvInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"5", 5 * time.Second, false},
+		{"0.5", 500 * time.Millisecond, false},
+		{"5s", 5 * time.Second, false},
+		{"5m", 5 * time.Minute, false},
+		{"500ms", 500 * time.Millisecond, false},
+		{"garbage", 0, true},
+		{"NaN", 0, true},
+		{"1e300", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseEnvInterval(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseEnvInterval(%q) = %s, %v; want %s, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEnvIntervalWarning(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    time.Duration
+		warning bool
+	}{
+		{"5", 5 * time.Second, false},
+		{"5m", 5 * time.Minute, false},
+		{"garbage", Default().Interval, true},
+	}
+	for _, tt := range tests {
+		getenv := func(k string) string {
+			if k == "SRPS_SYSMONI_INTERVAL" {
+				return tt.env
+			}
+			return ""
+		}
+		cfg, err := FromArgs(nil, getenv, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Interval != tt.want {
+			t.Errorf("SRPS_SYSMONI_INTERVAL=%s: interval %s, want %s", tt.env, cfg.Interval, tt.want)
+		}
+		warned := len(cfg.EnvWarnings) == 1 && strings.Contains(cfg.EnvWarnings[0].Error(), "SRPS_SYSMONI_INTERVAL")
+		if warned != tt.warning || (!tt.warning && len(cfg.EnvWarnings) > 0) {
+			t.Errorf("SRPS_SYSMONI_INTERVAL=%s: warnings %v, want a warning %v", tt.env, cfg.EnvWarnings, tt.warning)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Load reads a TOML config file on top of Default. Keys are flag names
+// (without dashes) and values are what the flag takes: strings, numbers or
+// booleans, with arrays for list options. A table prefixes its keys, so
+// "level" under [log] sets -log-level. All bad keys are reported together.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	f, err := os.Open(path)
+	if err != nil {
+		return cfg, err
+	}
+	defer f.Close()
+	err = apply(&cfg, f, path)
+	return cfg, err
+}
+
+func apply(cfg *Config, r io.Reader, name string) error {
+	fs := newFlagSet(cfg)
+	fs.SetOutput(io.Discard)
+
+	var doc map[string]any
+	md, err := toml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	var errs []error
+	// md.Keys is in file order, so errors come out in the order of the file.
+	for _, key := range md.Keys() {
+		v := lookup(doc, key)
+		if _, ok := v.(map[string]any); ok {
+			continue // a table; its keys follow
+		}
+		flagName := strings.Join(key, "-")
+		if flagName == "config" {
+			errs = append(errs, fmt.Errorf("%s: config files cannot include other config files", name))
+			continue
+		}
+		if fs.Lookup(flagName) == nil {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", name, key.String()))
+			continue
+		}
+		val, err := flagValue(v)
+		if err == nil {
+			err = fs.Set(flagName, val)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %v", name, key.String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lookup returns the value at key in a decoded document.
+func lookup(doc map[string]any, key toml.Key) any {
+	var v any = doc
+	for _, k := range key {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+// flagValue renders a TOML value as a flag argument. Arrays become the
+// comma-separated form list options take.
+func flagValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := flagValue(item)
+			if err != nil {
+				return "", err
+			}
+			if _, nested := item.([]any); nested || strings.Contains(s, ",") {
+				return "", fmt.Errorf("array item %q: items cannot be arrays or contain commas", s)
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v (%T)", v, v)
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sysmoni.toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `# sysmoni defaults
+interval = "2s"
+sort = "mem"
+top = 20  # half the default
+filter = 'java|node:'
+alert = ["cpu>90:5s", "mem>85%"]
+disk-exclude = "loop*,zram*"
+gpu = false
+change-threshold = 2.5
+
+[log]
+level = "debug"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Interval != 2*time.Second || cfg.Sort != "mem" || cfg.Top != 20 || cfg.Filter != "java|node:" || cfg.EnableGPU {
+		t.Errorf("Load = interval %s, sort %q, top %d, filter %q, gpu %v", cfg.Interval, cfg.Sort, cfg.Top, cfg.Filter, cfg.EnableGPU)
+	}
+	if want := []string{"cpu>90:5s", "mem>85%"}; !slices.Equal(cfg.Alerts, want) {
+		t.Errorf("alerts = %q, want %q", cfg.Alerts, want)
+	}
+	if want := []string{"loop*", "zram*"}; !slices.Equal(cfg.DiskExclude, want) {
+		t.Errorf("disk-exclude = %q, want %q", cfg.DiskExclude, want)
+	}
+	if cfg.LogLevel != "debug" || cfg.ChangeThreshold != 2.5 {
+		t.Errorf("[log] level = %q, change-threshold = %v; want debug, 2.5", cfg.LogLevel, cfg.ChangeThreshold)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"keys", `interval = "2s"
+colour = "red"
+top = "many"
+config = "other.toml"
+filter = 2024-01-02T15:04:05Z
+alert = [["cpu>90"]]
+cap-cgroup = ["a:50%,b:20%"]
+
+[gpu]
+speed = 3
+`, []string{`unknown key "colour"`, "top:", "config files cannot include", "filter: unsupported value", "alert: array item", "cap-cgroup: array item", `unknown key "gpu.speed"`}},
+		{"syntax", "interval = \"2s\"\nnonsense\n", []string{"toml: line"}},
+		{"key: value is not TOML", "sort: mem\n", []string{"toml: line 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			// Every bad key is reported.
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q lacks %q", err, want)
+				}
+			}
+			if strings.Contains(err.Error(), "interval") {
+				t.Errorf("error %q reports the good interval key", err)
+			}
+		})
+	}
+}
+
+// TestPrecedence checks defaults < config file < environment < flags.
+func TestPrecedence(t *testing.T) {
+	path := writeConfig(t, "interval = \"3s\"\nsort = \"mem\"\ntop = 20\n")
+	env := map[string]string{"SRPS_SYSMONI_TOP": "30"}
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		interval time.Duration
+		sort     string
+		top      int
+	}{
+		{"file over defaults", nil, nil, 3 * time.Second, "mem", 20},
+		{"flag over file", []string{"-interval", "500ms", "-sort", "cpu"}, nil, 500 * time.Millisecond, "cpu", 20},
+		{"env over file", nil, env, 3 * time.Second, "mem", 30},
+		{"flag over env", []string{"-top", "5"}, env, 3 * time.Second, "mem", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := FromArgs(tt.args, func(k string) string { return tt.env[k] }, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Interval != tt.interval || cfg.Sort != tt.sort || cfg.Top != tt.top {
+				t.Errorf("interval %s, sort %q, top %d; want %s, %q, %d", cfg.Interval, cfg.Sort, cfg.Top, tt.interval, tt.sort, tt.top)
+			}
+		})
+	}
+
+	// -config on the command line replaces the default path.
+	other := writeConfig(t, "sort = \"io\"\n")
+	cfg, err := FromArgs([]string{"-config", other}, func(string) string { return "" }, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sort != "io" || cfg.Interval != Default().Interval {
+		t.Errorf("-config %s: sort %q, interval %s; want io and the default interval", other, cfg.Sort, cfg.Interval)
+	}
+
+	if _, err := FromArgs(nil, func(string) string { return "" }, filepath.Join(t.TempDir(), "missing.conf")); err == nil {
+		t.Error("missing config file: no error")
+	}
+}
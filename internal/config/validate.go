@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/alert"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/protect"
+)
+
+// SortKeys are the process sort columns understood by the sampler and UI.
+var SortKeys = []string{"cpu", "mem", "io", "fd", "peak"}
+
+// Themes are the TUI palettes; mono uses no colors.
+var Themes = []string{"dark", "light", "mono"}
+
+// CadenceCollectors are the sampler collectors -cadence can slow down.
+// "procs" covers process enumeration with cgroup aggregation, -threads and
+// per-process -schedstat; "inotify" also covers system-wide file handles.
+var CadenceCollectors = []string{"procs", "temps", "sensors", "numa", "battery", "inotify"}
+
+// MinInterval and MaxInterval bound -interval. Shorter intervals make CPU
+// deltas noise and hammer /proc; the sampler clamps to this range.
+const (
+	MinInterval = 50 * time.Millisecond
+	MaxInterval = time.Hour
+)
+
+// ClampInterval limits d to [MinInterval, MaxInterval]. Zero and negative
+// intervals, which Validate rejects, also come out as MinInterval, so a
+// hand-built config can't make the sampler's timer spin or panic.
+func ClampInterval(d time.Duration) time.Duration {
+	return min(max(d, MinInterval), MaxInterval)
+}
+
+// Validate checks cfg for values that would fail or silently misbehave at
+// runtime. Errors make the config unusable; warnings flag missing host
+// capabilities (tools, kernel files) that disable a feature.
+func Validate(cfg Config) (errs, warnings []error) {
+	if cfg.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("interval must be positive, got %s", cfg.Interval))
+	} else if d := ClampInterval(cfg.Interval); d != cfg.Interval {
+		warnings = append(warnings, fmt.Errorf("interval %s is outside %s-%s; using %s", cfg.Interval, MinInterval, MaxInterval, d))
+	} else if cfg.Interval < 100*time.Millisecond {
+		warnings = append(warnings, fmt.Errorf("interval %s is very short; sampling cost will dominate", cfg.Interval))
+	}
+	if cfg.Adaptive && (cfg.AdaptiveMin < MinInterval || cfg.AdaptiveMin > cfg.Interval || cfg.Interval > cfg.AdaptiveMax) {
+		errs = append(errs, fmt.Errorf("adaptive-min %s, interval %s and adaptive-max %s must satisfy %s <= adaptive-min <= interval <= adaptive-max",
+			cfg.AdaptiveMin, cfg.Interval, cfg.AdaptiveMax, MinInterval))
+	}
+	if !slices.Contains(SortKeys, cfg.Sort) {
+		errs = append(errs, fmt.Errorf("sort %q is not one of %v", cfg.Sort, SortKeys))
+	}
+	if cfg.Sort2 != "" && !slices.Contains(SortKeys, cfg.Sort2) {
+		errs = append(errs, fmt.Errorf("sort2 %q is not one of %v", cfg.Sort2, SortKeys))
+	}
+	if cfg.Filter != "" {
+		if _, err := regexp.Compile(cfg.Filter); err != nil {
+			errs = append(errs, fmt.Errorf("filter: %v", err))
+		}
+	}
+	if cfg.EnableGPU {
+		switch {
+		case cfg.GPUInterval < 500*time.Millisecond:
+			errs = append(errs, fmt.Errorf("gpu-interval must be at least 500ms, got %s", cfg.GPUInterval))
+		case cfg.GPUTimeout < 100*time.Millisecond:
+			errs = append(errs, fmt.Errorf("gpu-timeout must be at least 100ms, got %s", cfg.GPUTimeout))
+		case cfg.GPUTimeout > cfg.GPUInterval:
+			warnings = append(warnings, fmt.Errorf("gpu-timeout %s exceeds gpu-interval %s; GPU data will refresh less often than asked", cfg.GPUTimeout, cfg.GPUInterval))
+		}
+	}
+	for _, p := range slices.Concat(cfg.DiskExclude, cfg.DiskInclude) {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("disk pattern %q: %v", p, err))
+		}
+	}
+	if cfg.Top < 0 {
+		errs = append(errs, fmt.Errorf("top must be 0 (unlimited) or positive, got %d", cfg.Top))
+	}
+	if cfg.MinCPU < 0 || cfg.MinCPU > 100 {
+		errs = append(errs, fmt.Errorf("min-cpu %.1f is outside 0-100", cfg.MinCPU))
+	}
+	if cfg.MinMem < 0 || cfg.MinMem > 100 {
+		errs = append(errs, fmt.Errorf("min-mem %.1f is outside 0-100", cfg.MinMem))
+	}
+	if cfg.SparkDepth < 10 {
+		errs = append(errs, fmt.Errorf("spark-depth must be at least 10, got %d", cfg.SparkDepth))
+	}
+	if cfg.WarnPct <= 0 || cfg.CritPct > 100 || cfg.WarnPct >= cfg.CritPct {
+		errs = append(errs, fmt.Errorf("warn-pct %.1f and crit-pct %.1f must satisfy 0 < warn-pct < crit-pct <= 100", cfg.WarnPct, cfg.CritPct))
+	}
+	if !slices.Contains(Themes, cfg.Theme) {
+		errs = append(errs, fmt.Errorf("theme %q is not one of %v", cfg.Theme, Themes))
+	}
+	if cfg.TempCrit <= 0 {
+		errs = append(errs, fmt.Errorf("temp-crit must be positive, got %.1f", cfg.TempCrit))
+	}
+	if cfg.History < 0 {
+		errs = append(errs, fmt.Errorf("history must not be negative, got %d", cfg.History))
+	}
+	if cfg.HTTP != "" && cfg.HTTPHistory < 1 {
+		errs = append(errs, fmt.Errorf("http-history must be at least 1, got %d", cfg.HTTPHistory))
+	}
+	if cfg.Samples < 1 {
+		errs = append(errs, fmt.Errorf("samples must be at least 1, got %d", cfg.Samples))
+	}
+	if cfg.ChangeOnly {
+		if cfg.ChangeThreshold < 0 {
+			errs = append(errs, fmt.Errorf("change-threshold %.1f must not be negative", cfg.ChangeThreshold))
+		}
+		if cfg.Heartbeat <= 0 {
+			errs = append(errs, fmt.Errorf("heartbeat must be positive, got %s", cfg.Heartbeat))
+		}
+	}
+	if _, err := alert.ParseRules(cfg.Alerts); err != nil {
+		errs = append(errs, err)
+	}
+	if strings.TrimSpace(cfg.OnAlert) != "" {
+		if len(cfg.Alerts) == 0 {
+			errs = append(errs, fmt.Errorf("on-alert needs at least one -alert rule"))
+		} else if _, err := exec.LookPath(strings.Fields(cfg.OnAlert)[0]); err != nil {
+			warnings = append(warnings, fmt.Errorf("on-alert: %v", err))
+		}
+		if cfg.OnAlertTimeout <= 0 {
+			errs = append(errs, fmt.Errorf("on-alert-timeout must be positive, got %s", cfg.OnAlertTimeout))
+		}
+		if cfg.OnAlertCooldown < 0 {
+			errs = append(errs, fmt.Errorf("on-alert-cooldown must not be negative, got %s", cfg.OnAlertCooldown))
+		}
+	}
+	if cfg.ProtectCPU < 0 {
+		errs = append(errs, fmt.Errorf("protect-cpu %.1f must not be negative", cfg.ProtectCPU))
+	}
+	if cfg.ProtectCPU > 0 {
+		if cfg.ProtectNice < 1 || cfg.ProtectNice > 19 {
+			errs = append(errs, fmt.Errorf("protect-nice %d is outside 1-19", cfg.ProtectNice))
+		}
+		if cfg.ProtectAfter < 0 {
+			errs = append(errs, fmt.Errorf("protect-after must not be negative, got %s", cfg.ProtectAfter))
+		}
+	}
+	if _, err := protect.ParseCgroupCaps(cfg.CapCgroups); err != nil {
+		errs = append(errs, err)
+	}
+	if len(cfg.CapCgroups) > 0 {
+		switch {
+		case !cfg.EnableCgroups:
+			errs = append(errs, fmt.Errorf("cap-cgroup needs cgroup aggregation (-cgroups)"))
+		case !cfg.Enforce:
+			warnings = append(warnings, fmt.Errorf("cap-cgroup without -enforce: limits are only logged, not applied"))
+		case os.Geteuid() != 0:
+			warnings = append(warnings, fmt.Errorf("cap-cgroup -enforce without root: writing cpu.max will fail unless the cgroups are delegated to this user"))
+		}
+	}
+	switch cfg.PerCore {
+	case "full", "int", "summary", "none":
+	default:
+		errs = append(errs, fmt.Errorf("percore %q is not full|int|summary|none", cfg.PerCore))
+	}
+	switch cfg.TimestampFormat {
+	case "rfc3339", "epoch", "epoch-ms":
+	default:
+		errs = append(errs, fmt.Errorf("timestamp-format %q is not rfc3339|epoch|epoch-ms", cfg.TimestampFormat))
+	}
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("log-level %q is not debug|info|warn|error", cfg.LogLevel))
+	}
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log-format %q is not text|json", cfg.LogFormat))
+	}
+
+	switch cfg.Compress {
+	case "", "none":
+	case "auto", "gzip":
+		if cfg.CompressLevel < -1 || cfg.CompressLevel > 9 {
+			errs = append(errs, fmt.Errorf("compress-level %d is outside -1..9", cfg.CompressLevel))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("compress %q is not auto|none|gzip", cfg.Compress))
+	}
+	if cfg.Replay != "" {
+		if _, err := os.Stat(cfg.Replay); err != nil {
+			errs = append(errs, fmt.Errorf("replay: %v", err))
+		}
+		if cfg.ReplaySpeed <= 0 {
+			errs = append(errs, fmt.Errorf("replay-speed must be positive, got %g", cfg.ReplaySpeed))
+		}
+		if cfg.Prometheus != "" || cfg.HTTP != "" {
+			errs = append(errs, fmt.Errorf("replay can't be combined with -prometheus or -http"))
+		}
+	}
+	if (cfg.RotateSize > 0 || cfg.RotateEvery > 0) && (cfg.LogFile == "" || cfg.LogFile == "-") {
+		errs = append(errs, fmt.Errorf("rotate needs an output file (-output)"))
+	}
+	if cfg.Keep < 0 {
+		errs = append(errs, fmt.Errorf("keep must not be negative, got %d", cfg.Keep))
+	} else if cfg.Keep > 0 && cfg.RotateSize == 0 && cfg.RotateEvery == 0 {
+		errs = append(errs, fmt.Errorf("keep only applies with -rotate"))
+	}
+	if cfg.LogFile != "" && cfg.LogFile != "-" {
+		if fi, err := os.Stat(filepath.Dir(cfg.LogFile)); err != nil || !fi.IsDir() {
+			errs = append(errs, fmt.Errorf("log-file %q: directory does not exist", cfg.LogFile))
+		}
+	}
+
+	// Capability probe: features that quietly turn into zeros on this host.
+	if cfg.EnableGPU {
+		_, nvErr := exec.LookPath("nvidia-smi")
+		_, amdErr := exec.LookPath("rocm-smi")
+		_, intelErr := exec.LookPath("intel_gpu_top")
+		if nvErr != nil && amdErr != nil && intelErr != nil {
+			warnings = append(warnings, fmt.Errorf("gpu enabled but none of nvidia-smi, rocm-smi, intel_gpu_top is in PATH"))
+		}
+	}
+	if cfg.EnableCgroups {
+		if _, err := os.Stat("/sys/fs/cgroup"); err != nil {
+			warnings = append(warnings, fmt.Errorf("cgroups enabled but /sys/fs/cgroup is not mounted"))
+		}
+	}
+	if cfg.Schedstat {
+		if _, err := os.Stat("/proc/schedstat"); err != nil {
+			warnings = append(warnings, fmt.Errorf("schedstat enabled but /proc/schedstat is missing (CONFIG_SCHEDSTATS)"))
+		}
+	}
+	return errs, warnings
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClampInterval(t *testing.T) {
+	tests := []struct {
+		in, want time.Duration
+	}{
+		{-time.Second, MinInterval},
+		{0, MinInterval},
+		{49 * time.Millisecond, 50 * time.Millisecond},
+		{50 * time.Millisecond, 50 * time.Millisecond},
+		{time.Second, time.Second},
+		{time.Hour, time.Hour},
+		{time.Hour + time.Nanosecond, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := ClampInterval(tt.in); got != tt.want {
+			t.Errorf("ClampInterval(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		err      bool
+		warning  string // substring of the only interval warning; "" for none
+	}{
+		{0, true, ""},
+		{49 * time.Millisecond, false, "outside 50ms-1h0m0s; using 50ms"},
+		{50 * time.Millisecond, false, "very short"},
+		{time.Second, false, ""},
+		{time.Hour, false, ""},
+		{time.Hour + time.Nanosecond, false, "using 1h0m0s"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.EnableGPU = false // no capability warnings
+		cfg.Interval = tt.interval
+		errs, warnings := Validate(cfg)
+		if (len(errs) > 0) != tt.err {
+			t.Errorf("interval %s: errors %v, want error %v", tt.interval, errs, tt.err)
+		}
+		var got string
+		for _, w := range warnings {
+			if strings.Contains(w.Error(), "interval") {
+				got = w.Error()
+			}
+		}
+		if (got == "") != (tt.warning == "") || !strings.Contains(got, tt.warning) {
+			t.Errorf("interval %s: warning %q, want one containing %q", tt.interval, got, tt.warning)
+		}
+	}
+}
+
+func TestValidateAdaptive(t *testing.T) {
+	tests := []struct {
+		min, interval, max time.Duration
+		ok                 bool
+	}{
+		{MinInterval, time.Second, 5 * time.Second, true},
+		{MinInterval - time.Millisecond, time.Second, 5 * time.Second, false},
+		{250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond, true},
+		{2 * time.Second, time.Second, 5 * time.Second, false},
+		{250 * time.Millisecond, 10 * time.Second, 5 * time.Second, false},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.EnableGPU = false
+		cfg.Adaptive = true
+		cfg.AdaptiveMin, cfg.Interval, cfg.AdaptiveMax = tt.min, tt.interval, tt.max
+		errs, _ := Validate(cfg)
+		if ok := len(errs) == 0; ok != tt.ok {
+			t.Errorf("adaptive %s <= %s <= %s: errors %v, want ok %v", tt.min, tt.interval, tt.max, errs, tt.ok)
+		}
+	}
+}
@@ -1,24 +1,85 @@
 package model
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // CPU aggregates instantaneous CPU usage.
 type CPU struct {
 	Total   float64   // percent 0-100
 	PerCore []float64 // per-core percent
-	Load1   float64
-	Load5   float64
-	Load15  float64
+	// Iowait and Steal are shares of all CPU time (percent, system-wide).
+	// Iowait counts as idle in Total. Steal is time the hypervisor ran
+	// something else while this VM was runnable: sustained steal means a
+	// contended host, not a busy guest.
+	Iowait float64
+	Steal  float64
+	// System-wide scheduler activity from /proc/stat. A context-switch or
+	// interrupt storm can eat CPU without any one process looking busy; a
+	// high fork rate points at a respawn loop or a shell script gone wild.
+	ContextSwitchesPerSec float64
+	InterruptsPerSec      float64
+	ForksPerSec           float64
+	// PerCoreSummary replaces PerCore in output written with -percore summary.
+	PerCoreSummary *CoreSummary
+	Load1          float64
+	Load5          float64
+	Load15         float64
+
+	// Average run-queue wait per timeslice from /proc/schedstat (-schedstat).
+	// High latency with moderate Total means tasks are contending for CPUs.
+	SchedLatencyUs        float64
+	PerCoreSchedLatencyUs []float64
+
+	// Per-CPU network softirq load (-net-softirq).
+	NetSoftirq []NetSoftirq
+
+	// Freqs is each core's current clock in MHz, indexed like PerCore (0 for
+	// a core whose cpufreq entry is unreadable). Nil without cpufreq, as on
+	// many VMs. A busy core well below its peers is likely thermally throttled.
+	Freqs []float64
+}
+
+// CoreSummary condenses per-core percentages on wide hosts.
+type CoreSummary struct {
+	Min float64
+	Max float64
+	Avg float64
+}
+
+// NetSoftirq is NET_RX/NET_TX softirq activity on one CPU. SoftirqPct is the
+// share of the core's time spent in softirq handlers; Dominated marks cores
+// where that is high and mostly network processing.
+type NetSoftirq struct {
+	CPU        int
+	RxPerSec   float64
+	TxPerSec   float64
+	SoftirqPct float64
+	Dominated  bool
 }
 
 // Memory captures RAM and swap usage in bytes for precision.
 type Memory struct {
+	// UsedBytes is TotalBytes - AvailableBytes, matching the "used" that
+	// free(1) and most dashboards report: memory that cannot be reclaimed
+	// without swapping.
 	UsedBytes  uint64
 	TotalBytes uint64
-	SwapUsed   uint64
-	SwapTotal  uint64
-	Cached     uint64
-	Buffers    uint64
+	// AvailableBytes is the kernel's MemAvailable estimate of memory
+	// obtainable for new work, including reclaimable page cache.
+	AvailableBytes uint64
+	// RawUsedBytes is gopsutil's Used (total - free - buffers - cached),
+	// which is what UsedBytes reported before; kept for compatibility.
+	RawUsedBytes uint64
+	SwapUsed     uint64
+	SwapTotal    uint64
+	Cached       uint64
+	Buffers      uint64
+	// Pages swapped in/out per second (pswpin/pswpout in /proc/vmstat). A
+	// sustained swap-in rate means thrashing; a full but idle swap is fine.
+	SwapInPerSec  float64
+	SwapOutPerSec float64
 }
 
 // IO holds disk and network throughput numbers.
@@ -28,48 +89,229 @@ type IO struct {
 	NetRxMbps    float64
 	NetTxMbps    float64
 	PerDevice    []IODevice
+	PerInterface []NetInterface
+}
+
+// NetInterface carries per-interface throughput and packet error and drop
+// rates (per second). Degraded is set when any error/drop rate is non-zero
+// for the interval. Loopback traffic is included but flagged.
+type NetInterface struct {
+	Name            string
+	RxMbps          float64
+	TxMbps          float64
+	Loopback        bool
+	RxErrorsPerSec  float64
+	TxErrorsPerSec  float64
+	RxDroppedPerSec float64
+	TxDroppedPerSec float64
+	Degraded        bool
 }
 
-// IODevice captures per-block-device throughput.
+// IODevice captures per-block-device throughput and completed operations
+// per second.
 type IODevice struct {
-	Name     string
-	ReadMBs  float64
-	WriteMBs float64
+	Name      string
+	ReadMBs   float64
+	WriteMBs  float64
+	ReadIOPS  float64
+	WriteIOPS float64
 }
 
-// GPU holds a single device snapshot.
+// GPU holds a single device snapshot. Readings nvidia-smi prints as
+// "[N/A]" or "[Not Supported]" (common on laptop and MIG GPUs) are
+// GPUUnavailable, so a missing value can't be mistaken for a real 0.
 type GPU struct {
+	// Index is the card's index within its vendor tool (nvidia-smi index,
+	// rocm-smi card number); GPUs are sorted by it within each vendor.
+	// UUID is empty where the tool doesn't report one (only NVIDIA does).
+	Index      int
+	UUID       string
 	Name       string
 	Util       float64 // percent
 	MemUsedMB  float64
 	MemTotalMB float64
 	TempC      float64
+
+	// Per-engine utilization percent: memory controller, NVENC, NVDEC.
+	// Zero when the driver doesn't report them at all.
+	MemUtil     float64
+	EncoderUtil float64
+	DecoderUtil float64
+
+	BusID string    // PCI bus id; NVIDIA only
+	Procs []GPUProc // processes using the GPU, largest VRAM first
+
+	// MIGEnabled is set for NVIDIA cards in Multi-Instance GPU mode. The
+	// card's memory fields still cover the whole card (nvidia-smi reports
+	// its utilization as unavailable); MIG lists the instances it is split
+	// into, each with its own share of memory.
+	MIGEnabled bool
+	MIG        []MIGInstance
+}
+
+// MIGInstance is one MIG device of a GPU. nvidia-smi doesn't report
+// utilization per instance.
+type MIGInstance struct {
+	Index           int    // MIG device index within the GPU
+	GPUInstance     int    // GPU instance id
+	ComputeInstance int    // compute instance id within the GPU instance
+	Profile         string // e.g. "1g.10gb"; empty if nvidia-smi -L failed
+	UUID            string // MIG-...
+	MemUsedMB       float64
+	MemTotalMB      float64
+}
+
+// GPUUnavailable is the value of a GPU reading the driver doesn't provide.
+const GPUUnavailable = -1
+
+// GPUProc is a process with a context on a GPU, as the vendor tool
+// reports it.
+type GPUProc struct {
+	PID       int
+	Command   string
+	MemUsedMB float64 // zero when the driver doesn't report it
 }
 
 // Battery shows power state; absent if Percent == 0 and State is empty.
 type Battery struct {
+	Name             string // power_supply name, e.g. "BAT0"; empty for a combined view
 	Percent          float64
 	State            string
-	SecondsRemaining int64
+	SecondsRemaining int64 // TimeRemaining in whole seconds
+	// PowerW is the current draw (or charge rate) in watts. TimeRemaining is
+	// the estimate to empty while discharging, or to full while charging.
+	// Both are zero when the driver doesn't expose the needed sysfs files.
+	PowerW        float64
+	TimeRemaining time.Duration
+	// CapacityWh is the full-charge energy, used to weight Percent when
+	// several batteries are combined (0 if unknown).
+	CapacityWh float64
 }
 
 // Process is a lightweight top entry.
 type Process struct {
 	PID      int
-	Nice     int
-	CPU      float64
+	TID      int     // non-zero for per-thread entries (-threads); PID is then the owning process
+	PPID     int     // parent PID from /proc/<pid>/status; 0 when unknown
+	Nice     int     // nice value, -20..19
+	CPU      float64 // percent of one core since the previous sample; lifetime average when first seen
 	Memory   float64
 	Command  string
 	FDCount  int
 	ReadKBs  float64
 	WriteKBs float64
 	FDDiff   int
+
+	// Average run-queue wait per timeslice from /proc/<pid>/schedstat (-schedstat).
+	SchedLatencyUs float64
+
+	// Thread count and single-letter state (R running, S sleeping, D
+	// uninterruptible/disk sleep, Z zombie, T stopped) from /proc/<pid>/status.
+	Threads int
+	State   string
+
+	// Resident and peak memory from /proc/<pid>/status (VmRSS, VmHWM, VmPeak).
+	// PeakRSSBytes is the high-water mark, so it stays high after a spike is freed.
+	RSSBytes      uint64
+	PeakRSSBytes  uint64
+	PeakVirtBytes uint64
+
+	// StartTime is when the process started and Uptime its age at the
+	// sample's Timestamp; both are zero when the start time is unknown.
+	StartTime time.Time
+	Uptime    time.Duration
 }
 
 // Cgroup summarizes CPU usage by unit/name.
 type Cgroup struct {
-	Name string
+	Name string // last path component, for display
+	Path string // full path relative to the cgroup root; aggregation key
 	CPU  float64
+
+	// Memory is percent of RAM. MemorySource says where it came from:
+	// "memory.current" (cgroup v2, includes page cache) or "process-sum"
+	// (summed resident memory of member processes).
+	Memory       float64
+	MemoryBytes  uint64
+	MemorySource string
+
+	// Block I/O from cgroup v2 io.stat, summed across devices (bytes/sec).
+	IOReadBytesPerSec  float64
+	IOWriteBytesPerSec float64
+
+	// Task count from the pids controller. PIDsMax is 0 when unlimited;
+	// PIDsNearLimit is set at 90% of a finite limit.
+	PIDsCurrent   uint64
+	PIDsMax       uint64
+	PIDsNearLimit bool
+
+	// CFS bandwidth throttling from cpu.stat (v2, or the v1 cpu controller):
+	// how often per second the group hit its cpu.max quota, and how many ms
+	// per second its tasks spent throttled. NrThrottled is the running total.
+	// All zero for groups without a CPU limit.
+	NrThrottled       uint64
+	ThrottledPerSec   float64
+	ThrottledMsPerSec float64
+}
+
+// UserUsage sums the CPU and memory of every process owned by one user
+// (effective UID, as in ps). Memory is percent of RAM; User is the login
+// name, or the UID when it has no passwd entry.
+type UserUsage struct {
+	UID       int
+	User      string
+	CPU       float64
+	Memory    float64
+	Processes int
+}
+
+// Balloon describes host memory reclaim on a virtio-balloon VM. Memory
+// percentages are relative to GuestTotalBytes, which shrinks as the host
+// inflates the balloon; ConfiguredBytes is what the VM was sized with.
+type Balloon struct {
+	InflatedBytes   uint64
+	GuestTotalBytes uint64
+	ConfiguredBytes uint64
+}
+
+// NUMANode is one NUMA node's memory. Nodes don't report MemAvailable, so
+// UsedBytes is MemTotal - MemFree and counts page cache as used.
+type NUMANode struct {
+	ID         int
+	TotalBytes uint64
+	FreeBytes  uint64
+	UsedBytes  uint64
+}
+
+// Pressure is pressure stall information from /proc/pressure: the share of
+// wall time tasks were stalled waiting on each resource. Unlike load average
+// it rises before a system becomes unresponsive. Supported is false on
+// kernels without PSI, leaving everything zero.
+type Pressure struct {
+	Supported bool
+	CPU       PressureStat
+	Memory    PressureStat
+	IO        PressureStat
+}
+
+// PressureStat holds stall percentages over 10s and 60s windows. "some" is
+// time at least one task was stalled; "full" is time all non-idle tasks were
+// (always 0 for CPU on older kernels).
+type PressureStat struct {
+	Some10 float64
+	Some60 float64
+	Full10 float64
+}
+
+// Connections counts IPv4+IPv6 sockets by state (-connections, refreshed
+// every few seconds). A climbing TCPEstablished or TCPTimeWait with steady
+// traffic usually means a connection leak.
+type Connections struct {
+	TCPEstablished int
+	TCPListen      int
+	TCPTimeWait    int
+	TCPTotal       int
+	UDP            int
 }
 
 // Inotify collects watch stats.
@@ -79,27 +321,251 @@ type Inotify struct {
 	NrWatches        uint64
 }
 
+// OOMConfig captures the kernel's OOM/overcommit tunables from /proc/sys/vm
+// and whether a userspace OOM daemon is running. Read once at startup.
+type OOMConfig struct {
+	OvercommitMemory int // 0=heuristic, 1=always, 2=strict
+	OvercommitRatio  int
+	Swappiness       int
+	MinFreeKbytes    uint64
+	PanicOnOOM       int
+	EarlyOOMActive   bool
+	OOMDActive       bool // systemd-oomd
+}
+
 // Temp is a thermal sensor reading.
 type Temp struct {
-	Zone string
-	Temp float64
+	Zone string // sysfs directory, e.g. thermal_zone0
+	// Label is the zone's type (x86_pkg_temp, acpitz, nvme, ...), or Zone
+	// when that is unreadable. Several zones may share a label.
+	Label string
+	Temp  float64
+}
+
+// Sensor kinds, as in Sensor.Kind.
+const (
+	SensorTemp    = "temp"    // °C
+	SensorFan     = "fan"     // RPM
+	SensorVoltage = "voltage" // V
+)
+
+// Sensor is one hwmon reading, as lm-sensors shows it.
+type Sensor struct {
+	Chip  string // hwmon name, e.g. coretemp, nct6798, amdgpu
+	Label string // from the input's _label file, else the input (temp1, fan2, in0)
+	Kind  string // SensorTemp, SensorFan or SensorVoltage
+	Value float64
 }
 
+// ReaderTiming is the wall time one sampler reader took for a sample.
+type ReaderTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// SelfStats reports sysmoni's own sampling cost, per reader and in total.
+// SampleDuration is wall time; most readers run concurrently, so their
+// durations can add up to more.
+type SelfStats struct {
+	SampleDuration time.Duration
+	Readers        []ReaderTiming
+}
+
+// SectionAge reports when an asynchronously refreshed section (GPU, ...) was
+// actually collected, since it can lag Sample.Timestamp. Stale is set once the
+// data is several poll periods old (or was never collected).
+type SectionAge struct {
+	Name        string
+	CollectedAt time.Time
+	Stale       bool
+}
+
+// Alert is an -alert rule changing state: "firing" once its metric has
+// stayed past the threshold for the rule's duration, "resolved" once it is
+// back. PID/Command name the top process for the metric (e.g. the busiest
+// CPU user for a cpu rule) when there is one.
+type Alert struct {
+	Time      time.Time
+	Rule      string // as given, e.g. "cpu>90:5s"
+	Metric    string
+	Value     float64
+	Threshold float64
+	State     string    // "firing" or "resolved"
+	Since     time.Time // when the breach began; zero on resolve
+	PID       int
+	Command   string
+}
+
+// Rates are per-second changes since the previous sample, reported with
+// -rates. Negative values mean the quantity shrank.
+type Rates struct {
+	MemUsedBytesPerSec  float64 // Memory.UsedBytes
+	SwapUsedBytesPerSec float64 // Memory.SwapUsed
+	OpenFDsPerSec       float64 // OpenFDs
+}
+
+// Totals are cumulative counters reported with -totals.
+//
+// The unprefixed fields are session totals: per-interval deltas summed since
+// sysmoni started (Since), with counter resets/wraps contributing zero. The
+// Boot* fields are the raw kernel counters, i.e. totals since boot (or since the
+// device/interface appeared). CPU seconds are busy time summed over all cores.
+type Totals struct {
+	Since          time.Time
+	CPUSeconds     float64
+	DiskReadBytes  uint64
+	DiskWriteBytes uint64
+	NetRxBytes     uint64
+	NetTxBytes     uint64
+
+	BootCPUSeconds     float64
+	BootDiskReadBytes  uint64
+	BootDiskWriteBytes uint64
+	BootNetRxBytes     uint64
+	BootNetTxBytes     uint64
+}
+
+// Disk is the capacity of one mounted filesystem (-disk-usage). Stale is set
+// when statfs timed out (typically a hung network mount); sizes are then 0.
+type Disk struct {
+	Mountpoint string
+	Device     string
+	Fstype     string
+	UsedBytes  uint64
+	TotalBytes uint64
+	Percent    float64
+	Stale      bool
+}
+
+// KillEvent is a process (or, for systemd-oomd, a whole cgroup) killed to
+// relieve memory pressure.
+type KillEvent struct {
+	Time    time.Time // zero if the journal timestamp couldn't be parsed
+	Source  string    // "earlyoom", "oomd" or "kernel"
+	PID     int       // 0 for oomd, which kills cgroups
+	Command string    // process name, or the cgroup path for oomd
+	Message string    // the raw journal line
+}
+
+// SchemaVersion is the version of the JSON shape of Sample, emitted with
+// every sample (see `sysmoni schema`). It is bumped when a field is removed,
+// renamed or changes meaning or type; adding fields doesn't bump it.
+const SchemaVersion = 1
+
 // Sample is the full snapshot exchanged between sampler, UI, and JSON exporter.
 type Sample struct {
-	Timestamp time.Time
-	Interval  time.Duration
+	// SchemaVersion is model.SchemaVersion for samples from this build.
+	SchemaVersion int
+	Timestamp     time.Time
+	// Interval is the sampling interval in effect for this sample; with
+	// -adaptive it changes as the host's load does.
+	Interval time.Duration
+	// Lagging is set when collecting this sample took longer than Interval
+	// (see Self.SampleDuration); the sampler then skips the ticks it missed
+	// instead of sampling back to back.
+	Lagging   bool
 	CPU       CPU
 	Memory    Memory
 	IO        IO
 	GPUs      []GPU
-	Battery   Battery
-	Top       []Process
+	Sections  []SectionAge
+	Batteries []Battery // one per BAT* supply; see PrimaryBattery
+	// Battery repeats PrimaryBattery() for readers of the single-battery
+	// field.
+	//
+	// Deprecated: use PrimaryBattery().
+	Battery Battery
+	Top     []Process
+	// Niced lists processes with a positive nice value: deprioritized by a
+	// user or a renice rule, not necessarily starved.
+	Niced []Process
+	// Throttled repeats Niced under its old name. Despite the name it was
+	// never kernel throttling; CPUThrottled is.
+	//
+	// Deprecated: use Niced.
 	Throttled []Process
-	Cgroups   []Cgroup
-	Inotify   Inotify
-	Temps     []Temp
+	// CPUThrottled lists processes whose cgroup hit its CPU quota during the
+	// last interval (cpu.stat nr_throttled grew); needs cgroup aggregation.
+	CPUThrottled []Process
+	Threads      []Process // busiest threads of the Top processes; only with -threads
+	Cgroups      []Cgroup
+	Users        []UserUsage // busiest first, capped at a quarter of -top
+	Inotify      Inotify
+	// System-wide file handles in use and the fs.file-max limit.
+	OpenFDs     uint64
+	MaxFDs      uint64
+	Temps       []Temp
+	Sensors     []Sensor // hwmon; temps of chips mirroring a thermal zone are left out
+	OOM         OOMConfig
+	Pressure    Pressure
+	Balloon     *Balloon     // nil unless running as a virtio-balloon guest
+	NUMA        []NUMANode   // nil on single-node systems
+	Connections *Connections // nil unless -connections
+	Disks       []Disk       // nil unless -disk-usage
+	Kills       []KillEvent  // newest first; nil unless -kills
+	Self        SelfStats
+	Totals      *Totals // nil unless -totals
+	Rates       *Rates  // nil unless -rates, and on the first sample
+	// Errors lists the readers failing as of this sample as "reader: error",
+	// so missing data can be told apart from genuine zeros (e.g. without
+	// /proc, or on a non-Linux OS). Nil when every reader succeeded.
+	Errors []string
+}
+
+// PrimaryBattery combines all batteries into one view: Percent weighted by
+// capacity (a plain mean if any capacity is unknown), summed PowerW, and the
+// time for the combined energy at the combined rate. Dual-battery laptops
+// drain one cell after the other, so per-battery estimates alone mislead.
+// With a single battery it is that battery; with none, the zero value.
+func (s Sample) PrimaryBattery() Battery {
+	switch len(s.Batteries) {
+	case 0:
+		return Battery{}
+	case 1:
+		return s.Batteries[0]
+	}
+	out := Battery{State: s.Batteries[0].State}
+	var pctSum, capSum, leftHours float64
+	weighted := true
+	for _, b := range s.Batteries {
+		pctSum += b.Percent
+		capSum += b.CapacityWh
+		weighted = weighted && b.CapacityWh > 0
+		out.PowerW += b.PowerW
+		// The active cell's state wins over an idle one's "Not charging"/"Unknown".
+		if b.State == "Charging" || b.State == "Discharging" {
+			out.State = b.State
+		}
+	}
+	out.CapacityWh = capSum
+	if weighted {
+		var w float64
+		for _, b := range s.Batteries {
+			w += b.Percent * b.CapacityWh
+		}
+		out.Percent = w / capSum
+	} else {
+		out.Percent = pctSum / float64(len(s.Batteries))
+	}
+	if weighted && out.PowerW > 0 {
+		switch out.State {
+		case "Discharging":
+			leftHours = out.Percent / 100 * capSum / out.PowerW
+		case "Charging":
+			leftHours = (100 - out.Percent) / 100 * capSum / out.PowerW
+		}
+	}
+	out.TimeRemaining = time.Duration(leftHours * float64(time.Hour)).Round(time.Minute)
+	out.SecondsRemaining = int64(out.TimeRemaining / time.Second)
+	return out
 }
 
 // Zero returns an empty sample for initialization.
 func Zero() Sample { return Sample{Timestamp: time.Now()} }
+
+// SampleSource produces samples: the live sampler, a -replay capture, or a
+// fake in tests. Stream sends until ctx is done (or the source runs out)
+// and then closes the channel.
+type SampleSource interface {
+	Stream(ctx context.Context) <-chan Sample
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrimaryBattery(t *testing.T) {
+	bat0 := Battery{Name: "BAT0", Percent: 80, State: "Discharging", PowerW: 5, CapacityWh: 50}
+	tests := []struct {
+		name      string
+		batteries []Battery
+		want      Battery
+	}{
+		{"none", nil, Battery{}},
+		{"one", []Battery{bat0}, bat0},
+		{"charging, weighted", []Battery{
+			{Name: "BAT0", Percent: 50, State: "Not charging", CapacityWh: 20},
+			{Name: "BAT1", Percent: 20, State: "Charging", PowerW: 12, CapacityWh: 40},
+		}, Battery{Percent: 30, State: "Charging", PowerW: 12, CapacityWh: 60, TimeRemaining: 3*time.Hour + 30*time.Minute, SecondsRemaining: 12600}},
+		{"capacity unknown: plain mean, no time", []Battery{
+			{Name: "BAT0", Percent: 90, State: "Discharging", PowerW: 4},
+			{Name: "BAT1", Percent: 40, State: "Discharging", PowerW: 2, CapacityWh: 30},
+		}, Battery{Percent: 65, State: "Discharging", PowerW: 6, CapacityWh: 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Sample{Batteries: tt.batteries}).PrimaryBattery(); got != tt.want {
+				t.Errorf("PrimaryBattery = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
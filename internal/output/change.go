@@ -0,0 +1,102 @@
+package output
+
+import (
+	"math"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Throughput below these floors counts as idle, so noise between 0.01 and
+// 0.02 MB/s doesn't register as a 100% change.
+const (
+	minDiskMBs  = 0.1
+	minNetMbps  = 1.0
+	defaultBeat = time.Minute
+)
+
+// ChangeFilter suppresses samples that are near-identical to the last one
+// emitted (-change-only). A sample counts as changed when any of:
+//
+//   - CPU total, memory used %, swap used %, any GPU util or the battery
+//     percent moved by more than Threshold percentage points;
+//   - disk read/write or network rx/tx moved by more than Threshold percent
+//     of the larger of the two values (ignoring idle-level rates);
+//   - the busiest process (first Top entry) or the number of GPUs changed.
+//
+// A sample is always emitted once Heartbeat has passed since the last one.
+type ChangeFilter struct {
+	Threshold float64
+	Heartbeat time.Duration
+
+	last   model.Sample
+	lastAt time.Time
+	primed bool
+}
+
+// NewChangeFilter returns a filter with the given sensitivity and heartbeat
+// (a non-positive heartbeat means one minute).
+func NewChangeFilter(threshold float64, heartbeat time.Duration) *ChangeFilter {
+	if heartbeat <= 0 {
+		heartbeat = defaultBeat
+	}
+	return &ChangeFilter{Threshold: threshold, Heartbeat: heartbeat}
+}
+
+// Emit reports whether s should be written, and records it as the last
+// emitted sample if so.
+func (f *ChangeFilter) Emit(s model.Sample) bool {
+	if f.primed && !f.changed(s) && s.Timestamp.Sub(f.lastAt) < f.Heartbeat {
+		return false
+	}
+	f.last, f.lastAt, f.primed = s, s.Timestamp, true
+	return true
+}
+
+func (f *ChangeFilter) changed(s model.Sample) bool {
+	p := f.last
+	points := func(a, b float64) bool { return math.Abs(a-b) > f.Threshold }
+	rate := func(a, b, floor float64) bool {
+		hi := math.Max(a, b)
+		if hi < floor {
+			return false
+		}
+		return math.Abs(a-b)/hi*100 > f.Threshold
+	}
+
+	if points(s.CPU.Total, p.CPU.Total) ||
+		points(usedPct(s.Memory.UsedBytes, s.Memory.TotalBytes), usedPct(p.Memory.UsedBytes, p.Memory.TotalBytes)) ||
+		points(usedPct(s.Memory.SwapUsed, s.Memory.SwapTotal), usedPct(p.Memory.SwapUsed, p.Memory.SwapTotal)) ||
+		points(s.PrimaryBattery().Percent, p.PrimaryBattery().Percent) {
+		return true
+	}
+	if rate(s.IO.DiskReadMBs, p.IO.DiskReadMBs, minDiskMBs) ||
+		rate(s.IO.DiskWriteMBs, p.IO.DiskWriteMBs, minDiskMBs) ||
+		rate(s.IO.NetRxMbps, p.IO.NetRxMbps, minNetMbps) ||
+		rate(s.IO.NetTxMbps, p.IO.NetTxMbps, minNetMbps) {
+		return true
+	}
+	if len(s.GPUs) != len(p.GPUs) {
+		return true
+	}
+	for i := range s.GPUs {
+		if points(s.GPUs[i].Util, p.GPUs[i].Util) {
+			return true
+		}
+	}
+	return topPID(s) != topPID(p)
+}
+
+func usedPct(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+func topPID(s model.Sample) int {
+	if len(s.Top) == 0 {
+		return 0
+	}
+	return s.Top[0].PID
+}
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// csvHeader lists the flat per-sample columns. Variable-length data (cores,
+// processes) is reduced to counts.
+var csvHeader = []string{
+	"timestamp", "cpu_total", "mem_used", "mem_total", "swap_used",
+	"disk_read_mbps", "disk_write_mbps", "net_rx_mbps", "net_tx_mbps",
+	"load1", "load5", "load15", "cores", "top_procs",
+}
+
+// CSVEncoder writes one CSV row per sample, preceded by a single header row.
+type CSVEncoder struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVEncoder returns an encoder writing to w.
+func NewCSVEncoder(w io.Writer) *CSVEncoder {
+	return &CSVEncoder{w: csv.NewWriter(w)}
+}
+
+// Encode writes s as a row (and the header before the first row).
+func (e *CSVEncoder) Encode(s model.Sample) error {
+	if !e.wroteHeader {
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	row := []string{
+		s.Timestamp.Format(time.RFC3339),
+		f(s.CPU.Total),
+		u(s.Memory.UsedBytes),
+		u(s.Memory.TotalBytes),
+		u(s.Memory.SwapUsed),
+		f(s.IO.DiskReadMBs),
+		f(s.IO.DiskWriteMBs),
+		f(s.IO.NetRxMbps),
+		f(s.IO.NetTxMbps),
+		f(s.CPU.Load1),
+		f(s.CPU.Load5),
+		f(s.CPU.Load15),
+		strconv.Itoa(len(s.CPU.PerCore)),
+		strconv.Itoa(len(s.Top)),
+	}
+	if err := e.w.Write(row); err != nil {
+		return err
+	}
+	// Flush per row so streamed rows reach the file (and gzip layer) promptly.
+	e.w.Flush()
+	return e.w.Error()
+}
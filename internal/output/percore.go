@@ -0,0 +1,48 @@
+package output
+
+import (
+	"math"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Per-core detail levels for serialized samples (-percore).
+const (
+	PerCoreFull    = "full"    // every core, full precision
+	PerCoreInt     = "int"     // every core, rounded to whole percent
+	PerCoreSummary = "summary" // min/max/avg only
+	PerCoreNone    = "none"    // omitted
+)
+
+// ApplyPerCore reduces s.CPU.PerCore to the requested detail level. It
+// replaces the slice rather than editing it, so the sampler's copy is safe.
+// Freqs is indexed like PerCore and is dropped along with it.
+func ApplyPerCore(s *model.Sample, mode string) {
+	cores := s.CPU.PerCore
+	switch mode {
+	case PerCoreInt:
+		rounded := make([]float64, len(cores))
+		for i, v := range cores {
+			rounded[i] = math.Round(v)
+		}
+		s.CPU.PerCore = rounded
+	case PerCoreSummary:
+		s.CPU.PerCore = nil
+		s.CPU.Freqs = nil
+		if len(cores) == 0 {
+			return
+		}
+		sum := model.CoreSummary{Min: cores[0], Max: cores[0]}
+		var total float64
+		for _, v := range cores {
+			sum.Min = math.Min(sum.Min, v)
+			sum.Max = math.Max(sum.Max, v)
+			total += v
+		}
+		sum.Avg = total / float64(len(cores))
+		s.CPU.PerCoreSummary = &sum
+	case PerCoreNone:
+		s.CPU.PerCore = nil
+		s.CPU.Freqs = nil
+	}
+}
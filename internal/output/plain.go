@@ -0,0 +1,94 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// plainColumns are the per-process columns of the -plain table, keyed by the
+// -sort column they show.
+var plainColumns = []struct {
+	key, title string
+	width      int
+	value      func(model.Process) string
+}{
+	{"cpu", "CPU%", 6, func(p model.Process) string { return fmt.Sprintf("%.1f", p.CPU) }},
+	{"mem", "MEM%", 6, func(p model.Process) string { return fmt.Sprintf("%.1f", p.Memory) }},
+	{"io", "IO KB/s", 9, func(p model.Process) string { return fmt.Sprintf("%.0f", p.ReadKBs+p.WriteKBs) }},
+	{"fd", "FDS", 6, func(p model.Process) string { return fmt.Sprintf("%d", p.FDCount) }},
+	{"peak", "PEAK MB", 8, func(p model.Process) string { return fmt.Sprintf("%.0f", float64(p.PeakRSSBytes)/(1024*1024)) }},
+}
+
+// PlainEncoder writes a compact, top-like text table per sample (-plain).
+// With redraw set each table replaces the previous one on screen; otherwise
+// tables are appended, separated by a blank line, so they can be piped or
+// logged.
+type PlainEncoder struct {
+	w      io.Writer
+	redraw bool
+	sort   string
+	limit  int
+	tables int
+}
+
+// NewPlainEncoder returns an encoder writing to w. sortKey is the -sort
+// column, marked with "*" in the header; rows are printed in the order the
+// sampler ranked them. limit caps the process rows (0 = 20).
+func NewPlainEncoder(w io.Writer, redraw bool, sortKey string, limit int) *PlainEncoder {
+	if limit <= 0 {
+		limit = 20
+	}
+	return &PlainEncoder{w: w, redraw: redraw, sort: sortKey, limit: limit}
+}
+
+// Encode writes the table for s.
+func (e *PlainEncoder) Encode(s model.Sample) error {
+	var b strings.Builder
+	switch {
+	case e.redraw:
+		b.WriteString("\x1b[H\x1b[2J") // cursor home, clear screen
+	case e.tables > 0:
+		b.WriteString("\n")
+	}
+	e.tables++
+
+	fmt.Fprintf(&b, "sysmoni %s  cpu %.1f%%  load %.2f %.2f %.2f\n",
+		s.Timestamp.Format(time.TimeOnly), s.CPU.Total, s.CPU.Load1, s.CPU.Load5, s.CPU.Load15)
+	fmt.Fprintf(&b, "mem %s / %s (%.1f%%)  swap %s / %s\n",
+		gib(s.Memory.UsedBytes), gib(s.Memory.TotalBytes), usedPct(s.Memory.UsedBytes, s.Memory.TotalBytes),
+		gib(s.Memory.SwapUsed), gib(s.Memory.SwapTotal))
+	fmt.Fprintf(&b, "disk R %.1f W %.1f MB/s  net RX %.1f TX %.1f Mb/s\n",
+		s.IO.DiskReadMBs, s.IO.DiskWriteMBs, s.IO.NetRxMbps, s.IO.NetTxMbps)
+	for _, msg := range s.Errors {
+		fmt.Fprintf(&b, "error: %s\n", msg)
+	}
+
+	fmt.Fprintf(&b, "\n%7s", "PID")
+	for _, c := range plainColumns {
+		title := c.title
+		if c.key == e.sort {
+			title += "*"
+		}
+		fmt.Fprintf(&b, " %*s", c.width, title)
+	}
+	b.WriteString("  COMMAND\n")
+
+	procs := s.Top
+	if len(procs) > e.limit {
+		procs = procs[:e.limit]
+	}
+	for _, p := range procs {
+		fmt.Fprintf(&b, "%7d", p.PID)
+		for _, c := range plainColumns {
+			fmt.Fprintf(&b, " %*s", c.width, c.value(p))
+		}
+		fmt.Fprintf(&b, "  %s\n", strings.ReplaceAll(p.Command, "\n", " "))
+	}
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
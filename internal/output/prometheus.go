@@ -0,0 +1,185 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// promMaxProcs caps per-process series so PID churn can't blow up the
+// scraper's cardinality.
+const promMaxProcs = 20
+
+// WritePrometheus renders s in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, s model.Sample) error {
+	p := &promWriter{w: w}
+	p.gauge("sysmoni_cpu_total_percent", "Total CPU utilization.", s.CPU.Total)
+	p.header("sysmoni_cpu_core_percent", "Per-core CPU utilization.")
+	for i, v := range s.CPU.PerCore {
+		p.sample("sysmoni_cpu_core_percent", v, "core", fmt.Sprint(i))
+	}
+	p.gauge("sysmoni_cpu_iowait_percent", "Share of CPU time waiting on I/O.", s.CPU.Iowait)
+	p.gauge("sysmoni_cpu_steal_percent", "Share of CPU time stolen by the hypervisor.", s.CPU.Steal)
+	p.gauge("sysmoni_context_switches_per_second", "Context switches per second.", s.CPU.ContextSwitchesPerSec)
+	p.gauge("sysmoni_interrupts_per_second", "Interrupts per second.", s.CPU.InterruptsPerSec)
+	p.gauge("sysmoni_forks_per_second", "Processes created per second.", s.CPU.ForksPerSec)
+	p.gauge("sysmoni_load1", "1-minute load average.", s.CPU.Load1)
+	p.gauge("sysmoni_mem_used_bytes", "Used memory (total - available).", float64(s.Memory.UsedBytes))
+	p.gauge("sysmoni_mem_total_bytes", "Total memory.", float64(s.Memory.TotalBytes))
+	p.gauge("sysmoni_mem_available_bytes", "Memory available without swapping (MemAvailable).", float64(s.Memory.AvailableBytes))
+	if len(s.NUMA) > 0 {
+		p.header("sysmoni_numa_mem_used_bytes", "Used memory per NUMA node (MemTotal - MemFree).")
+		for _, n := range s.NUMA {
+			p.sample("sysmoni_numa_mem_used_bytes", float64(n.UsedBytes), "node", fmt.Sprint(n.ID))
+		}
+		p.header("sysmoni_numa_mem_total_bytes", "Total memory per NUMA node.")
+		for _, n := range s.NUMA {
+			p.sample("sysmoni_numa_mem_total_bytes", float64(n.TotalBytes), "node", fmt.Sprint(n.ID))
+		}
+	}
+	p.gauge("sysmoni_swap_used_bytes", "Used swap.", float64(s.Memory.SwapUsed))
+	p.gauge("sysmoni_swap_total_bytes", "Total swap.", float64(s.Memory.SwapTotal))
+	p.gauge("sysmoni_swap_in_pages_per_second", "Pages swapped in per second.", s.Memory.SwapInPerSec)
+	p.gauge("sysmoni_swap_out_pages_per_second", "Pages swapped out per second.", s.Memory.SwapOutPerSec)
+	if r := s.Rates; r != nil {
+		p.gauge("sysmoni_mem_used_delta_bytes_per_second", "Change in used memory since the previous sample.", r.MemUsedBytesPerSec)
+		p.gauge("sysmoni_swap_used_delta_bytes_per_second", "Change in used swap since the previous sample.", r.SwapUsedBytesPerSec)
+		p.gauge("sysmoni_open_fds_delta_per_second", "Change in system-wide open files since the previous sample.", r.OpenFDsPerSec)
+	}
+	if ps := s.Pressure; ps.Supported {
+		p.header("sysmoni_pressure_some10_percent", "Share of time some tasks stalled on the resource (PSI avg10).")
+		p.sample("sysmoni_pressure_some10_percent", ps.CPU.Some10, "resource", "cpu")
+		p.sample("sysmoni_pressure_some10_percent", ps.Memory.Some10, "resource", "memory")
+		p.sample("sysmoni_pressure_some10_percent", ps.IO.Some10, "resource", "io")
+		p.header("sysmoni_pressure_full10_percent", "Share of time all non-idle tasks stalled on the resource (PSI avg10).")
+		p.sample("sysmoni_pressure_full10_percent", ps.Memory.Full10, "resource", "memory")
+		p.sample("sysmoni_pressure_full10_percent", ps.IO.Full10, "resource", "io")
+	}
+	p.gauge("sysmoni_disk_read_mbps", "Disk read throughput (MB/s).", s.IO.DiskReadMBs)
+	p.gauge("sysmoni_disk_write_mbps", "Disk write throughput (MB/s).", s.IO.DiskWriteMBs)
+	if len(s.Disks) > 0 {
+		p.header("sysmoni_fs_used_bytes", "Used filesystem bytes per mount.")
+		for _, d := range s.Disks {
+			if !d.Stale {
+				p.sample("sysmoni_fs_used_bytes", float64(d.UsedBytes), "mount", d.Mountpoint)
+			}
+		}
+		p.header("sysmoni_fs_size_bytes", "Filesystem size per mount.")
+		for _, d := range s.Disks {
+			if !d.Stale {
+				p.sample("sysmoni_fs_size_bytes", float64(d.TotalBytes), "mount", d.Mountpoint)
+			}
+		}
+	}
+	p.gauge("sysmoni_net_rx_mbps", "Network receive throughput (Mb/s).", s.IO.NetRxMbps)
+	p.gauge("sysmoni_net_tx_mbps", "Network transmit throughput (Mb/s).", s.IO.NetTxMbps)
+
+	// Readings the driver doesn't provide are left out rather than exported
+	// as -1.
+	p.header("sysmoni_gpu_util_percent", "GPU utilization.")
+	for _, g := range s.GPUs {
+		if g.Util != model.GPUUnavailable {
+			p.sample("sysmoni_gpu_util_percent", g.Util, gpuLabels(g)...)
+		}
+	}
+	p.header("sysmoni_gpu_temp_celsius", "GPU temperature.")
+	for _, g := range s.GPUs {
+		if g.TempC != model.GPUUnavailable {
+			p.sample("sysmoni_gpu_temp_celsius", g.TempC, gpuLabels(g)...)
+		}
+	}
+
+	for _, m := range []struct{ kind, name, help string }{
+		{model.SensorTemp, "sysmoni_hwmon_temp_celsius", "hwmon temperature."},
+		{model.SensorFan, "sysmoni_hwmon_fan_rpm", "hwmon fan speed."},
+		{model.SensorVoltage, "sysmoni_hwmon_voltage_volts", "hwmon voltage."},
+	} {
+		p.header(m.name, m.help)
+		for _, sn := range s.Sensors {
+			if sn.Kind == m.kind {
+				p.sample(m.name, sn.Value, "chip", sn.Chip, "label", sn.Label)
+			}
+		}
+	}
+
+	p.header("sysmoni_user_cpu_percent", "CPU utilization of all processes owned by a user.")
+	for _, u := range s.Users {
+		p.sample("sysmoni_user_cpu_percent", u.CPU, "user", u.User)
+	}
+	p.header("sysmoni_user_mem_percent", "Memory share of all processes owned by a user.")
+	for _, u := range s.Users {
+		p.sample("sysmoni_user_mem_percent", u.Memory, "user", u.User)
+	}
+
+	procs := s.Top
+	if len(procs) > promMaxProcs {
+		procs = procs[:promMaxProcs]
+	}
+	p.header("sysmoni_process_cpu_percent", "CPU utilization of the busiest processes.")
+	for _, pr := range procs {
+		p.sample("sysmoni_process_cpu_percent", pr.CPU, "pid", fmt.Sprint(pr.PID), "comm", comm(pr.Command))
+	}
+	p.header("sysmoni_process_mem_percent", "Memory share of the busiest processes.")
+	for _, pr := range procs {
+		p.sample("sysmoni_process_mem_percent", pr.Memory, "pid", fmt.Sprint(pr.PID), "comm", comm(pr.Command))
+	}
+	return p.err
+}
+
+// gpuLabels identifies a GPU by its index and, where known, its UUID, so
+// series survive a change in the order GPUs are listed.
+func gpuLabels(g model.GPU) []string {
+	labels := []string{"gpu", fmt.Sprint(g.Index), "name", g.Name}
+	if g.UUID != "" {
+		labels = append(labels, "uuid", g.UUID)
+	}
+	return labels
+}
+
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) printf(format string, args ...any) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func (p *promWriter) header(name, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+func (p *promWriter) gauge(name, help string, v float64) {
+	p.header(name, help)
+	p.sample(name, v)
+}
+
+// sample writes one series; labels are name/value pairs.
+func (p *promWriter) sample(name string, v float64, labels ...string) {
+	if len(labels) == 0 {
+		p.printf("%s %g\n", name, v)
+		return
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], promEscape(labels[i+1])))
+	}
+	p.printf("%s{%s} %g\n", name, strings.Join(pairs, ","), v)
+}
+
+func promEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// comm reduces a command line to its executable name.
+func comm(cmd string) string {
+	if f := strings.Fields(cmd); len(f) > 0 {
+		return filepath.Base(f[0])
+	}
+	return ""
+}
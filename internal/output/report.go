@@ -0,0 +1,86 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Report is a human-readable snapshot for incident write-ups: current
+// metrics, the process list as shown, and any active alerts.
+type Report struct {
+	Host   string
+	Sample model.Sample
+	Procs  []model.Process // ranked/filtered as displayed; Sample.Top if nil
+	Alerts []string
+	Limit  int // max process rows (0 = 20)
+}
+
+// WriteMarkdown renders r as markdown suitable for pasting into a ticket.
+func WriteMarkdown(w io.Writer, r Report) error {
+	s := r.Sample
+	procs := r.Procs
+	if procs == nil {
+		procs = s.Top
+	}
+	limit := r.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if len(procs) > limit {
+		procs = procs[:limit]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# sysmoni report: %s\n\n", r.Host)
+	fmt.Fprintf(&b, "Captured %s (interval %s)\n\n", s.Timestamp.Format(time.RFC3339), s.Interval)
+
+	b.WriteString("## Alerts\n\n")
+	if len(r.Alerts) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, a := range r.Alerts {
+		fmt.Fprintf(&b, "- %s\n", a)
+	}
+
+	b.WriteString("\n## Metrics\n\n| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| CPU | %.1f%% (load %.2f %.2f %.2f) |\n", s.CPU.Total, s.CPU.Load1, s.CPU.Load5, s.CPU.Load15)
+	fmt.Fprintf(&b, "| Memory | %s / %s (%.1f%%) |\n", gib(s.Memory.UsedBytes), gib(s.Memory.TotalBytes), usedPct(s.Memory.UsedBytes, s.Memory.TotalBytes))
+	fmt.Fprintf(&b, "| Swap | %s / %s (%.1f%%) |\n", gib(s.Memory.SwapUsed), gib(s.Memory.SwapTotal), usedPct(s.Memory.SwapUsed, s.Memory.SwapTotal))
+	fmt.Fprintf(&b, "| Disk | R %.1f MB/s, W %.1f MB/s |\n", s.IO.DiskReadMBs, s.IO.DiskWriteMBs)
+	fmt.Fprintf(&b, "| Network | RX %.1f Mb/s, TX %.1f Mb/s |\n", s.IO.NetRxMbps, s.IO.NetTxMbps)
+	for _, g := range s.GPUs {
+		fmt.Fprintf(&b, "| GPU %s | %s, %s/%s MB, %s |\n", mdEscape(g.Name),
+			gpuReading("%.0f%%", g.Util), gpuReading("%.0f", g.MemUsedMB), gpuReading("%.0f", g.MemTotalMB), gpuReading("%.0f°C", g.TempC))
+	}
+	for _, bat := range s.Batteries {
+		fmt.Fprintf(&b, "| Battery %s | %.0f%% %s |\n", bat.Name, bat.Percent, bat.State)
+	}
+
+	b.WriteString("\n## Top processes\n\n| PID | CPU% | MEM% | R KB/s | W KB/s | FDs | Command |\n|---:|---:|---:|---:|---:|---:|---|\n")
+	for _, p := range procs {
+		fmt.Fprintf(&b, "| %d | %.1f | %.1f | %.0f | %.0f | %d | %s |\n",
+			p.PID, p.CPU, p.Memory, p.ReadKBs, p.WriteKBs, p.FDCount, mdEscape(p.Command))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func gib(b uint64) string { return fmt.Sprintf("%.1f GiB", float64(b)/(1024*1024*1024)) }
+
+// mdEscape keeps command lines from breaking table cells.
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// gpuReading formats a GPU reading, or "n/a" where the driver has none.
+func gpuReading(format string, v float64) string {
+	if v == model.GPUUnavailable {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, v)
+}
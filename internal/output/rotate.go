@@ -0,0 +1,161 @@
+package output
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rotateStamp is inserted into rotated file names; it sorts chronologically.
+// The milliseconds keep two rotations within one second in separate files.
+const rotateStamp = "20060102-150405.000"
+
+// rotateStampSecs parses rotateStamp; time.Parse takes the fractional
+// seconds as optional, so older per-second names are pruned too.
+const rotateStampSecs = "20060102-150405"
+
+// Rotation configures -rotate and -keep. The zero value never rotates.
+type Rotation struct {
+	Size  int64         // start a new file once this many bytes were written (0 = no limit)
+	Every time.Duration // start a new file once the current one is this old (0 = no limit)
+	Keep  int           // delete all but the newest Keep files (0 = keep all)
+}
+
+func (r Rotation) enabled() bool { return r.Size > 0 || r.Every > 0 }
+
+// Rotating is a Writer that moves on to a new, timestamped file when the
+// current one grows past Rotation.Size or gets older than Rotation.Every.
+// Files are only switched in Boundary, which callers invoke between
+// records, so no record is split, dropped or written twice. Without
+// rotation it writes to path itself.
+type Rotating struct {
+	path     string
+	compress string
+	level    int
+	opts     Rotation
+
+	cur     *Writer
+	opened  time.Time
+	written int64
+}
+
+// OpenRotating opens the first output file; see Open for the arguments.
+func OpenRotating(path, compress string, level int, opts Rotation) (*Rotating, error) {
+	r := &Rotating{path: path, compress: compress, level: level, opts: opts}
+	if err := r.open(time.Now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rotating) open(now time.Time) error {
+	path := r.path
+	if r.opts.enabled() {
+		// Stamp each file later than the last one. Reusing a name would
+		// append to that file, and a CSV file would get a second header.
+		if last := r.opened.Truncate(time.Millisecond); !now.Truncate(time.Millisecond).After(last) {
+			now = last.Add(time.Millisecond)
+		}
+		path = stampedPath(r.path, now)
+	}
+	w, err := Open(path, r.compress, r.level)
+	if err != nil {
+		return err
+	}
+	r.cur, r.opened, r.written = w, now, 0
+	if r.opts.enabled() {
+		slog.Info("writing output", "file", path)
+		r.prune()
+	}
+	return nil
+}
+
+// Write writes p to the current file. Size counts the bytes given, before
+// compression.
+func (r *Rotating) Write(p []byte) (int, error) {
+	n, err := r.cur.Write(p)
+	r.written += int64(n)
+	return n, err
+}
+
+// Boundary marks the end of a record and rotates if the current file is
+// due. It reports whether a new file was started, so formats with a
+// header (CSV) can write it again.
+func (r *Rotating) Boundary() (bool, error) {
+	if !r.opts.enabled() {
+		return false, nil
+	}
+	now := time.Now()
+	if (r.opts.Size <= 0 || r.written < r.opts.Size) && (r.opts.Every <= 0 || now.Sub(r.opened) < r.opts.Every) {
+		return false, nil
+	}
+	if err := r.cur.Close(); err != nil {
+		return false, err
+	}
+	return true, r.open(now)
+}
+
+// Close closes the current file.
+func (r *Rotating) Close() error {
+	return r.cur.Close()
+}
+
+// stampedPath inserts "-<timestamp>" before the first extension of path, so
+// "run.ndjson.gz" becomes "run-20240102-150405.123.ndjson.gz".
+func stampedPath(path string, t time.Time) string {
+	dir, base := filepath.Split(path)
+	name, ext := splitExt(base)
+	return dir + name + "-" + t.Format(rotateStamp) + ext
+}
+
+// splitExt splits base at its first dot, ignoring a leading one.
+func splitExt(base string) (name, ext string) {
+	start := 0
+	if strings.HasPrefix(base, ".") {
+		start = 1
+	}
+	if i := strings.IndexByte(base[start:], '.'); i >= 0 {
+		return base[:start+i], base[start+i:]
+	}
+	return base, ""
+}
+
+// prune deletes the oldest rotated files beyond Rotation.Keep. Only names
+// of the form stampedPath produces are considered.
+func (r *Rotating) prune() {
+	if r.opts.Keep <= 0 {
+		return
+	}
+	dir, base := filepath.Split(r.path)
+	name, ext := splitExt(base)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		slog.Warn("rotate: listing old files failed", "dir", dir, "err", err)
+		return
+	}
+	var old []string
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), name+"-")
+		if !ok {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ext)
+		if _, err := time.Parse(rotateStampSecs, stamp); ok && err == nil {
+			old = append(old, e.Name())
+		}
+	}
+	slices.Sort(old)
+	for _, f := range old[:max(len(old)-r.opts.Keep, 0)] {
+		if err := os.Remove(filepath.Join(dir, f)); err != nil {
+			slog.Warn("rotate: removing old file failed", "file", f, "err", err)
+			continue
+		}
+		slog.Info("rotate: removed old file", "file", f)
+	}
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// TestRotatingSameSecond rotates a CSV capture after every row, far faster
+// than once a second; each row must land in its own file with one header.
+func TestRotatingSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	r, err := OpenRotating(filepath.Join(dir, "run.csv"), "none", -1, Rotation{Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const rows = 5
+	enc := NewCSVEncoder(r)
+	for i := 0; i < rows; i++ {
+		if err := enc.Encode(model.Sample{Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		rotated, err := r.Boundary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rotated {
+			t.Fatalf("row %d: no rotation", i)
+		}
+		enc = NewCSVEncoder(r)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "run-*.csv"))
+	if len(files) != rows+1 { // the last file is opened but left empty
+		t.Fatalf("got %d files, want %d: %v", len(files), rows+1, files)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "timestamp,"); n > 1 {
+			t.Errorf("%s has %d headers", filepath.Base(f), n)
+		}
+	}
+}
+
+func TestPruneKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"run-20240102-150405.csv", // written before stamps had milliseconds
+		"run-20240102-150406.000.csv",
+		"run-20240102-150406.500.csv",
+		"run-20240102-150407.000.csv",
+		"run-notes.csv",
+		"other-20240102-150405.000.csv",
+	}
+	for _, n := range names {
+		if err := os.WriteFile(filepath.Join(dir, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &Rotating{path: filepath.Join(dir, "run.csv"), opts: Rotation{Size: 1, Keep: 2}}
+	r.prune()
+
+	entries, _ := os.ReadDir(dir)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{"other-20240102-150405.000.csv", "run-20240102-150406.500.csv", "run-20240102-150407.000.csv", "run-notes.csv"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("left %v, want %v", got, want)
+	}
+}
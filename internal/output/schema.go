@@ -0,0 +1,97 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// WriteSchema writes a JSON Schema (draft 2020-12) describing the samples
+// emitted by -json, -json-stream and the HTTP API at model.SchemaVersion.
+// It is derived from model.Sample, so it can't drift from the real output.
+// Objects allow extra properties: fields added within a schema version
+// must not break validation.
+func WriteSchema(w io.Writer) error {
+	defs := make(map[string]any)
+	root := schemaFor(reflect.TypeOf(model.Sample{}), defs)
+	props := defs["Sample"].(map[string]any)["properties"].(map[string]any)
+	props["SchemaVersion"] = map[string]any{"const": model.SchemaVersion}
+	props["Timestamp"] = map[string]any{
+		"anyOf":       []any{schemaFor(timeType, defs), map[string]any{"type": "integer"}},
+		"description": "RFC 3339, or Unix seconds or milliseconds with -timestamp-format epoch|epoch-ms",
+	}
+	doc := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     fmt.Sprintf("sysmoni/sample/v%d", model.SchemaVersion),
+		"title":   "sysmoni sample",
+		"$defs":   defs,
+	}
+	for k, v := range root {
+		doc[k] = v
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// schemaFor describes t as encoding/json marshals it. Structs go into defs
+// and are referenced by name; nil slices and pointers may be null.
+func schemaFor(t reflect.Type, defs map[string]any) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "integer", "description": "nanoseconds"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Pointer:
+		return nullable(schemaFor(t.Elem(), defs))
+	case reflect.Slice:
+		return nullable(map[string]any{"type": "array", "items": schemaFor(t.Elem(), defs)})
+	case reflect.Map:
+		return nullable(map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), defs)})
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // placeholder, in case of recursion
+			props := make(map[string]any)
+			var required []string
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if !f.IsExported() || f.Tag.Get("json") == "-" {
+					continue
+				}
+				props[f.Name] = schemaFor(f.Type, defs)
+				required = append(required, f.Name)
+			}
+			defs[t.Name()] = map[string]any{
+				"type":       "object",
+				"properties": props,
+				"required":   required,
+			}
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+func nullable(s map[string]any) map[string]any {
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+}
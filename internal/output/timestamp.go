@@ -0,0 +1,33 @@
+package output
+
+import (
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Timestamp encodings for serialized samples (-timestamp-format).
+const (
+	TimestampRFC3339 = "rfc3339"  // "2006-01-02T15:04:05.999999999Z07:00", as time.Time marshals
+	TimestampEpoch   = "epoch"    // integer seconds since the Unix epoch
+	TimestampEpochMs = "epoch-ms" // integer milliseconds since the Unix epoch
+)
+
+// stampedSample marshals like the embedded Sample, except that its own
+// Timestamp shadows the sample's. SchemaVersion is repeated so it stays the
+// first key.
+type stampedSample struct {
+	SchemaVersion int
+	Timestamp     int64
+	model.Sample
+}
+
+// WithTimestamp returns s ready for JSON encoding with its Timestamp in the
+// given format. RFC 3339 (or an unknown format) returns s unchanged.
+func WithTimestamp(s model.Sample, format string) any {
+	switch format {
+	case TimestampEpoch:
+		return stampedSample{s.SchemaVersion, s.Timestamp.Unix(), s}
+	case TimestampEpochMs:
+		return stampedSample{s.SchemaVersion, s.Timestamp.UnixMilli(), s}
+	}
+	return s
+}
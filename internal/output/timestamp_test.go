@@ -0,0 +1,43 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+func TestWithTimestamp(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.FixedZone("CET", 3600))
+	samp := model.Sample{SchemaVersion: model.SchemaVersion, Timestamp: ts}
+	tests := []struct {
+		format string
+		want   string // the encoded Timestamp
+	}{
+		{TimestampRFC3339, `"2024-01-02T15:04:05.123456789+01:00"`},
+		{TimestampEpoch, "1704204245"},
+		{TimestampEpochMs, "1704204245123"},
+		{"", `"2024-01-02T15:04:05.123456789+01:00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := json.Marshal(WithTimestamp(samp, tt.format))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(fields["Timestamp"]); got != tt.want {
+				t.Errorf("Timestamp = %s, want %s", got, tt.want)
+			}
+			// The stamped sample still leads with the schema version.
+			if !strings.HasPrefix(string(data), `{"SchemaVersion":`) {
+				t.Errorf("encoding starts %.40s, want SchemaVersion first", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// flushInterval bounds how long compressed data may sit in the gzip buffer, so
+// `zcat -f`/`tail` style readers see records while a capture is running.
+const flushInterval = 2 * time.Second
+
+// Writer is the destination for JSON/NDJSON records: stdout or a file,
+// optionally gzip-compressed. Close must be called to write the gzip footer.
+type Writer struct {
+	w         io.Writer
+	gz        *gzip.Writer
+	f         *os.File
+	lastFlush time.Time
+}
+
+// Open returns a Writer for path ("" or "-" means stdout). compress is "" /
+// "none", "gzip", or "auto" for gzip when path ends in .gz; level is a
+// compress/gzip level (-1 for the default).
+func Open(path, compress string, level int) (*Writer, error) {
+	if compress == "auto" {
+		compress = "none"
+		if strings.HasSuffix(path, ".gz") {
+			compress = "gzip"
+		}
+	}
+	w := &Writer{w: os.Stdout, lastFlush: time.Now()}
+	if path != "" && path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w.f = f
+		w.w = f
+	}
+	switch compress {
+	case "", "none":
+	case "gzip":
+		gz, err := gzip.NewWriterLevel(w.w, level)
+		if err != nil {
+			w.closeFile()
+			return nil, fmt.Errorf("compress level %d: %w", level, err)
+		}
+		w.gz = gz
+		w.w = gz
+	default:
+		w.closeFile()
+		return nil, fmt.Errorf("unsupported compression %q (want none|gzip)", compress)
+	}
+	return w, nil
+}
+
+// Write writes p and periodically flushes the compressor.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil || w.gz == nil {
+		return n, err
+	}
+	if time.Since(w.lastFlush) >= flushInterval {
+		w.lastFlush = time.Now()
+		err = w.gz.Flush()
+	}
+	return n, err
+}
+
+// Close finalizes the gzip stream (if any), then syncs and closes the
+// underlying file, so a capture is complete on disk once Close returns.
+func (w *Writer) Close() error {
+	var err error
+	if w.gz != nil {
+		err = w.gz.Close()
+	}
+	if cerr := w.closeFile(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *Writer) closeFile() error {
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Sync()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package protect
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ioprio_set(2) constants from linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+type sysAction struct{}
+
+// SystemAction returns the Action that calls setpriority(2) and
+// ioprio_set(2).
+func SystemAction() Action { return sysAction{} }
+
+// Both calls apply to a single thread on Linux (as `renice -p` does), so
+// every task of the process is updated; threads started later inherit the
+// values from whichever thread creates them.
+func (sysAction) Renice(pid, nice int) error {
+	return eachTask(pid, func(tid int) error {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice)
+	})
+}
+
+func (sysAction) IdleIO(pid int) error {
+	return eachTask(pid, func(tid int) error {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift)
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// eachTask calls fn for every thread of pid, falling back to pid alone if
+// its task list can't be read. Threads that exit meanwhile are ignored.
+func eachTask(pid int, fn func(tid int) error) error {
+	entries, err := os.ReadDir("/proc/" + strconv.Itoa(pid) + "/task")
+	if err != nil {
+		return fn(pid)
+	}
+	for _, e := range entries {
+		tid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if err := fn(tid); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package protect
+
+import "errors"
+
+type sysAction struct{}
+
+// SystemAction returns an Action that fails: renicing whole processes and
+// I/O priorities are only implemented for Linux.
+func SystemAction() Action { return sysAction{} }
+
+func (sysAction) Renice(pid, nice int) error { return errors.ErrUnsupported }
+func (sysAction) IdleIO(pid int) error       { return errors.ErrUnsupported }
@@ -0,0 +1,166 @@
+package protect
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// cgroupRoot is where the unified (v2) hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cpuMaxPeriod is the CFS period written with a cap; it is the kernel default.
+const cpuMaxPeriod = 100 * time.Millisecond
+
+// CgroupCap is one -cap-cgroup rule: once the cgroup called Name uses more
+// than Percent CPU (percent of one core, as in model.Cgroup.CPU), its
+// cpu.max is set to hold it there.
+type CgroupCap struct {
+	Name    string // matches model.Cgroup.Name or Path
+	Percent float64
+}
+
+// ParseCgroupCap parses "name:50%" (the % is optional). name may be a unit
+// like "backup.service" or a path like "/system.slice/backup.service".
+func ParseCgroupCap(spec string) (CgroupCap, error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 {
+		return CgroupCap{}, fmt.Errorf("cap-cgroup %q: want name:percent", spec)
+	}
+	name := strings.TrimSpace(spec[:i])
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(spec[i+1:]), "%"), 64)
+	if err != nil || pct <= 0 {
+		return CgroupCap{}, fmt.Errorf("cap-cgroup %q: percent must be a positive number", spec)
+	}
+	if strings.Contains(name, "..") {
+		return CgroupCap{}, fmt.Errorf("cap-cgroup %q: name must not contain ..", spec)
+	}
+	return CgroupCap{Name: name, Percent: pct}, nil
+}
+
+// ParseCgroupCaps parses every spec, returning the first error.
+func ParseCgroupCaps(specs []string) ([]CgroupCap, error) {
+	caps := make([]CgroupCap, 0, len(specs))
+	for _, spec := range specs {
+		c, err := ParseCgroupCap(spec)
+		if err != nil {
+			return nil, err
+		}
+		caps = append(caps, c)
+	}
+	return caps, nil
+}
+
+// CPUMax formats a cpu.max value allowing pct percent of one CPU per period:
+// 50% of a 100ms period is "50000 100000", 250% (2.5 cores) "250000 100000".
+// The quota is clamped to the kernel's 1ms minimum.
+func CPUMax(pct float64, period time.Duration) string {
+	periodUs := period.Microseconds()
+	quotaUs := max(int64(pct/100*float64(periodUs)), 1000)
+	return fmt.Sprintf("%d %d", quotaUs, periodUs)
+}
+
+// Capper writes cpu.max for cgroups that exceed their -cap-cgroup limit.
+// Without enforce it only logs what it would write. Every cgroup it changes
+// remembers its original cpu.max, which Restore writes back; callers must
+// call Restore on exit. Not safe for concurrent use.
+type Capper struct {
+	caps    []CgroupCap
+	enforce bool
+	root    string
+	saved   map[string]string // cgroup path -> original cpu.max
+	done    map[string]bool   // cgroup path -> capped (or logged in dry run)
+}
+
+// NewCapper returns a Capper for caps under the real cgroup root.
+func NewCapper(caps []CgroupCap, enforce bool) *Capper {
+	return &Capper{
+		caps:    caps,
+		enforce: enforce,
+		root:    cgroupRoot,
+		saved:   make(map[string]string),
+		done:    make(map[string]bool),
+	}
+}
+
+// Apply caps every cgroup in s over its limit that isn't capped yet.
+func (c *Capper) Apply(s model.Sample) error {
+	var errs []error
+	for _, cp := range c.caps {
+		for _, cg := range s.Cgroups {
+			if !matchCgroup(cp.Name, cg) || c.done[cg.Path] || cg.CPU <= cp.Percent {
+				continue
+			}
+			c.done[cg.Path] = true
+			val := CPUMax(cp.Percent, cpuMaxPeriod)
+			log := slog.With("cgroup", cg.Path, "cpu", cg.CPU, "cpu.max", val)
+			if !c.enforce {
+				log.Warn("cap-cgroup (dry run, pass -enforce to apply): would write cpu.max")
+				continue
+			}
+			if err := c.write(cg.Path, val); err != nil {
+				log.Error("cap-cgroup failed", "err", err)
+				errs = append(errs, err)
+				continue
+			}
+			log.Warn("cap-cgroup: capped cgroup")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Restore writes back the original cpu.max of every cgroup capped so far.
+// A cgroup that has since been removed is skipped.
+func (c *Capper) Restore() error {
+	var errs []error
+	for path, orig := range c.saved {
+		err := os.WriteFile(c.cpuMaxFile(path), []byte(orig), 0)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("restore cpu.max for %s: %w", path, err))
+		default:
+			slog.Info("cap-cgroup: restored cpu.max", "cgroup", path, "cpu.max", orig)
+		}
+		delete(c.saved, path)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Capper) cpuMaxFile(path string) string {
+	return filepath.Join(c.root, filepath.Clean("/"+path), "cpu.max")
+}
+
+// write saves the current cpu.max of path (the first time) and replaces it.
+func (c *Capper) write(path, val string) error {
+	file := c.cpuMaxFile(path)
+	orig, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: no cpu.max (needs cgroup v2 with the cpu controller enabled for the parent)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if _, ok := c.saved[path]; !ok {
+		c.saved[path] = strings.TrimSpace(string(orig))
+	}
+	if err := os.WriteFile(file, []byte(val), 0); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("%s: permission denied writing cpu.max (run as root or delegate the cgroup): %w", path, err)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func matchCgroup(name string, cg model.Cgroup) bool {
+	return name == cg.Name || name == cg.Path || "/"+name == cg.Path
+}
@@ -0,0 +1,105 @@
+package protect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+func TestCPUMax(t *testing.T) {
+	tests := []struct {
+		pct    float64
+		period time.Duration
+		want   string
+	}{
+		{50, 100 * time.Millisecond, "50000 100000"},
+		{250, 100 * time.Millisecond, "250000 100000"},
+		{100, 50 * time.Millisecond, "50000 50000"},
+		{12.5, 100 * time.Millisecond, "12500 100000"},
+		// The kernel rejects quotas under 1ms.
+		{1, 100 * time.Millisecond, "1000 100000"},
+		{0.1, 100 * time.Millisecond, "1000 100000"},
+	}
+	for _, tt := range tests {
+		if got := CPUMax(tt.pct, tt.period); got != tt.want {
+			t.Errorf("CPUMax(%v, %s) = %q, want %q", tt.pct, tt.period, got, tt.want)
+		}
+	}
+}
+
+// writeCPUMax creates a fake cgroup with the given cpu.max under root.
+func writeCPUMax(t *testing.T, root, path, val string) string {
+	t.Helper()
+	dir := filepath.Join(root, path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "cpu.max")
+	if err := os.WriteFile(file, []byte(val+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCapperRestore(t *testing.T) {
+	root := t.TempDir()
+	backup := writeCPUMax(t, root, "system.slice/backup.service", "max 100000")
+	gone := writeCPUMax(t, root, "system.slice/gone.service", "200000 100000")
+
+	c := NewCapper([]CgroupCap{{Name: "backup.service", Percent: 50}, {Name: "gone.service", Percent: 50}}, true)
+	c.root = root
+	s := model.Sample{Cgroups: []model.Cgroup{
+		{Name: "backup.service", Path: "/system.slice/backup.service", CPU: 180},
+		{Name: "gone.service", Path: "/system.slice/gone.service", CPU: 90},
+	}}
+	if err := c.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, backup); got != "50000 100000" {
+		t.Fatalf("capped cpu.max = %q, want %q", got, "50000 100000")
+	}
+	// A second breach keeps the original saved, not the capped value.
+	s.Cgroups[0].CPU = 60
+	c.done = make(map[string]bool)
+	if err := c.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(gone)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restore(); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := readFile(t, backup); got != "max 100000" {
+		t.Errorf("restored cpu.max = %q, want %q", got, "max 100000")
+	}
+	if len(c.saved) != 0 {
+		t.Errorf("saved after Restore = %v, want empty", c.saved)
+	}
+}
+
+func TestCapperDryRun(t *testing.T) {
+	root := t.TempDir()
+	file := writeCPUMax(t, root, "backup.service", "max 100000")
+	c := NewCapper([]CgroupCap{{Name: "backup.service", Percent: 50}}, false)
+	c.root = root
+	if err := c.Apply(model.Sample{Cgroups: []model.Cgroup{{Name: "backup.service", Path: "/backup.service", CPU: 180}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, file); got != "max 100000\n" {
+		t.Errorf("dry run wrote cpu.max: %q", got)
+	}
+}
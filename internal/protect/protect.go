@@ -0,0 +1,157 @@
+package protect
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Action applies a mitigation to a process. SystemAction does it for real;
+// tests and dry runs can substitute their own.
+type Action interface {
+	// Renice sets the nice value of every thread of pid.
+	Renice(pid, nice int) error
+	// IdleIO moves every thread of pid to the idle I/O scheduling class.
+	IdleIO(pid int) error
+}
+
+// Options configure a Protector. A process is acted on once its CPU percent
+// (as in model.Process.CPU) has stayed above CPU for at least After.
+type Options struct {
+	CPU    float64
+	After  time.Duration
+	Nice   int  // target nice value; only ever raised, never lowered
+	IdleIO bool // also move the process to the idle I/O class
+	DryRun bool // log what would be done instead of doing it
+}
+
+// Decision is one mitigation chosen for a process.
+type Decision struct {
+	PID     int
+	Command string
+	CPU     float64
+	OldNice int
+	NewNice int  // equal to OldNice when only the I/O class changes
+	IdleIO  bool // move to the idle I/O class
+}
+
+// Protector renices (and optionally ionices) processes that hog the CPU for
+// too long. Each process is handled once while it stays in the sample; it
+// is not safe for concurrent use.
+type Protector struct {
+	opts    Options
+	action  Action
+	self    int
+	over    map[int]time.Time // pid -> when it first went over the threshold
+	handled map[int]bool
+}
+
+// New returns a Protector applying opts through action.
+func New(opts Options, action Action) *Protector {
+	return &Protector{
+		opts:    opts,
+		action:  action,
+		self:    os.Getpid(),
+		over:    make(map[int]time.Time),
+		handled: make(map[int]bool),
+	}
+}
+
+// Decide updates per-process state with s and returns the processes due for
+// mitigation. It never targets init, sysmoni itself or per-thread rows, and
+// skips processes already at or above the target nice unless IdleIO is set.
+func (p *Protector) Decide(s model.Sample) []Decision {
+	var out []Decision
+	seen := make(map[int]bool, len(s.Top))
+	for _, proc := range s.Top {
+		if proc.TID != 0 || proc.PID <= 1 || proc.PID == p.self {
+			continue
+		}
+		seen[proc.PID] = true
+		if proc.CPU <= p.opts.CPU {
+			delete(p.over, proc.PID)
+			continue
+		}
+		since, ok := p.over[proc.PID]
+		if !ok {
+			since = s.Timestamp
+			p.over[proc.PID] = since
+		}
+		if p.handled[proc.PID] || s.Timestamp.Sub(since) < p.opts.After {
+			continue
+		}
+		p.handled[proc.PID] = true
+		d := Decision{
+			PID:     proc.PID,
+			Command: proc.Command,
+			CPU:     proc.CPU,
+			OldNice: proc.Nice,
+			NewNice: max(proc.Nice, p.opts.Nice),
+			IdleIO:  p.opts.IdleIO,
+		}
+		if d.NewNice == d.OldNice && !d.IdleIO {
+			continue
+		}
+		out = append(out, d)
+	}
+	// Forget processes that left the sample, so a reused PID starts fresh.
+	for pid := range p.over {
+		if !seen[pid] {
+			delete(p.over, pid)
+		}
+	}
+	for pid := range p.handled {
+		if !seen[pid] {
+			delete(p.handled, pid)
+		}
+	}
+	return out
+}
+
+// Apply decides on s and carries out (or, in dry-run mode, logs) each
+// decision. Failures are logged and returned joined; EPERM is reported as
+// a permission problem since acting on other users' processes needs root.
+func (p *Protector) Apply(s model.Sample) ([]Decision, error) {
+	decisions := p.Decide(s)
+	var errs []error
+	for _, d := range decisions {
+		log := slog.With("pid", d.PID, "comm", d.Command, "cpu", d.CPU, "nice", d.OldNice, "new_nice", d.NewNice, "idle_io", d.IdleIO)
+		if p.opts.DryRun {
+			log.Warn("protect (dry run): would throttle process")
+			continue
+		}
+		if err := p.act(d); err != nil {
+			log.Error("protect failed", "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Warn("protect: throttled process")
+	}
+	return decisions, errors.Join(errs...)
+}
+
+func (p *Protector) act(d Decision) error {
+	if d.NewNice != d.OldNice {
+		if err := p.action.Renice(d.PID, d.NewNice); err != nil {
+			return describe("renice", d.PID, err)
+		}
+	}
+	if d.IdleIO {
+		if err := p.action.IdleIO(d.PID); err != nil {
+			return describe("ionice", d.PID, err)
+		}
+	}
+	return nil
+}
+
+func describe(op string, pid int, err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%s pid %d: permission denied (run as root or as the process owner): %w", op, pid, err)
+	}
+	return fmt.Errorf("%s pid %d: %w", op, pid, err)
+}
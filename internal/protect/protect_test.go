@@ -0,0 +1,147 @@
+package protect
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// fakeAction records the calls a Protector makes.
+type fakeAction struct {
+	renices []call
+	idleIO  []int
+}
+
+type call struct{ pid, nice int }
+
+func (f *fakeAction) Renice(pid, nice int) error {
+	f.renices = append(f.renices, call{pid, nice})
+	return nil
+}
+
+func (f *fakeAction) IdleIO(pid int) error {
+	f.idleIO = append(f.idleIO, pid)
+	return nil
+}
+
+var t0 = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func sampleAt(d time.Duration, procs ...model.Process) model.Sample {
+	return model.Sample{Timestamp: t0.Add(d), Top: procs}
+}
+
+func TestDecideChoosesOnlyEligibleProcesses(t *testing.T) {
+	p := New(Options{CPU: 90, Nice: 10}, &fakeAction{})
+	procs := []model.Process{
+		{PID: 1, CPU: 100, Command: "init"},
+		{PID: os.Getpid(), CPU: 100, Command: "sysmoni"},
+		{PID: 200, TID: 201, CPU: 100, Command: "thread row"},
+		{PID: 300, CPU: 50, Command: "idle"},
+		{PID: 400, CPU: 99, Nice: 15, Command: "already niced"},
+		{PID: 500, CPU: 99, Nice: 0, Command: "hog"},
+	}
+	got := p.Decide(sampleAt(0, procs...))
+	if len(got) != 1 {
+		t.Fatalf("Decide = %+v, want one decision", got)
+	}
+	want := Decision{PID: 500, Command: "hog", CPU: 99, OldNice: 0, NewNice: 10}
+	if got[0] != want {
+		t.Errorf("Decide = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestDecideNewNice(t *testing.T) {
+	tests := []struct {
+		name   string
+		nice   int
+		idleIO bool
+		want   *Decision
+	}{
+		{name: "raised to target", nice: 0, want: &Decision{OldNice: 0, NewNice: 10}},
+		{name: "negative raised", nice: -5, want: &Decision{OldNice: -5, NewNice: 10}},
+		{name: "at target", nice: 10},
+		{name: "above target is never lowered", nice: 15},
+		{name: "above target with idle io", nice: 15, idleIO: true, want: &Decision{OldNice: 15, NewNice: 15, IdleIO: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(Options{CPU: 90, Nice: 10, IdleIO: tt.idleIO}, &fakeAction{})
+			got := p.Decide(sampleAt(0, model.Process{PID: 500, CPU: 99, Nice: tt.nice}))
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Fatalf("Decide = %+v, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("Decide = %+v, want one decision", got)
+			}
+			if got[0].OldNice != tt.want.OldNice || got[0].NewNice != tt.want.NewNice || got[0].IdleIO != tt.want.IdleIO {
+				t.Errorf("Decide = %+v, want nice %d -> %d, idle io %v", got[0], tt.want.OldNice, tt.want.NewNice, tt.want.IdleIO)
+			}
+		})
+	}
+}
+
+func TestDecideHold(t *testing.T) {
+	hog := func(cpu float64) model.Process { return model.Process{PID: 500, CPU: cpu, Command: "hog"} }
+	steps := []struct {
+		at   time.Duration
+		cpu  float64
+		want bool
+	}{
+		{0, 99, false},                // first seen over the threshold
+		{5 * time.Second, 99, false},  // not held long enough yet
+		{6 * time.Second, 10, false},  // dropped below: the hold restarts
+		{7 * time.Second, 99, false},  // over again from here
+		{16 * time.Second, 99, false}, // 9s
+		{17 * time.Second, 99, true},  // 10s
+		{18 * time.Second, 99, false}, // already handled
+	}
+	p := New(Options{CPU: 90, After: 10 * time.Second, Nice: 10}, &fakeAction{})
+	for _, st := range steps {
+		got := p.Decide(sampleAt(st.at, hog(st.cpu)))
+		if (len(got) == 1) != st.want {
+			t.Errorf("at %s with cpu %.0f: Decide = %+v, want decision %v", st.at, st.cpu, got, st.want)
+		}
+	}
+
+	// Once the process leaves the sample, a reused PID starts over.
+	p.Decide(sampleAt(19 * time.Second))
+	if got := p.Decide(sampleAt(20*time.Second, hog(99))); len(got) != 0 {
+		t.Errorf("reused PID acted on at once: %+v", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	hog := model.Process{PID: 500, CPU: 99, Command: "hog"}
+	t.Run("acts", func(t *testing.T) {
+		act := &fakeAction{}
+		p := New(Options{CPU: 90, Nice: 10, IdleIO: true}, act)
+		if _, err := p.Apply(sampleAt(0, hog)); err != nil {
+			t.Fatal(err)
+		}
+		if len(act.renices) != 1 || act.renices[0] != (call{500, 10}) {
+			t.Errorf("renices = %+v, want [{500 10}]", act.renices)
+		}
+		if len(act.idleIO) != 1 || act.idleIO[0] != 500 {
+			t.Errorf("idle io = %v, want [500]", act.idleIO)
+		}
+	})
+	t.Run("dry run", func(t *testing.T) {
+		act := &fakeAction{}
+		p := New(Options{CPU: 90, Nice: 10, IdleIO: true, DryRun: true}, act)
+		decisions, err := p.Apply(sampleAt(0, hog))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decisions) != 1 {
+			t.Errorf("decisions = %+v, want one", decisions)
+		}
+		if len(act.renices) != 0 || len(act.idleIO) != 0 {
+			t.Errorf("dry run made calls: renice %+v, idle io %v", act.renices, act.idleIO)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+// Package replay plays back NDJSON captures (-json-stream output) as a
+// sample stream, so a recorded incident can be reviewed in the TUI.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// maxLine bounds one NDJSON record; samples with long process lists and
+// command lines run to a few hundred KB.
+const maxLine = 16 << 20
+
+// Replayer reads samples back from a capture file.
+type Replayer struct {
+	path  string
+	speed float64
+}
+
+// New returns a Replayer for path, which may be gzip-compressed. speed
+// scales the gaps between samples: 1 is real time, 2 twice as fast.
+func New(path string, speed float64) *Replayer {
+	return &Replayer{path: path, speed: speed}
+}
+
+// Stream sends the capture's samples, spaced as they were recorded (divided
+// by the speed), and closes the channel at the end of the file or when ctx
+// is done. Undecodable lines are logged and skipped.
+func (r *Replayer) Stream(ctx context.Context) <-chan model.Sample {
+	ch := make(chan model.Sample)
+	go func() {
+		defer close(ch)
+		if err := r.play(ctx, ch); err != nil {
+			slog.Error("replay failed", "file", r.path, "err", err)
+		}
+	}()
+	return ch
+}
+
+func (r *Replayer) play(ctx context.Context, ch chan<- model.Sample) error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	in, err := decompress(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLine)
+	var prev time.Time
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		samp, err := decode(sc.Bytes())
+		if err != nil {
+			slog.Warn("replay: skipping bad record", "file", r.path, "line", line, "err", err)
+			continue
+		}
+		if !prev.IsZero() && samp.Timestamp.After(prev) {
+			wait := time.Duration(float64(samp.Timestamp.Sub(prev)) / r.speed)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		prev = samp.Timestamp
+		select {
+		case ch <- samp:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+// decompress unwraps gzip input, recognized by its magic bytes rather than
+// the file name.
+func decompress(br *bufio.Reader) (io.Reader, error) {
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// record decodes a sample with its Timestamp kept raw, since captures may
+// have been written with any -timestamp-format.
+type record struct {
+	model.Sample
+	Timestamp json.RawMessage
+}
+
+func decode(b []byte) (model.Sample, error) {
+	var rec record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return model.Sample{}, err
+	}
+	ts, err := parseTimestamp(rec.Timestamp)
+	if err != nil {
+		return model.Sample{}, err
+	}
+	rec.Sample.Timestamp = ts
+	// Captures from before Batteries only have the single Battery.
+	if len(rec.Batteries) == 0 && rec.Battery != (model.Battery{}) {
+		rec.Batteries = []model.Battery{rec.Battery}
+	}
+	return rec.Sample, nil
+}
+
+// parseTimestamp accepts RFC 3339 strings and Unix seconds or milliseconds.
+func parseTimestamp(raw json.RawMessage) (time.Time, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	n, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp %s: not RFC 3339 or a Unix time", raw)
+	}
+	// Seconds won't reach 1e12 until the year 33658.
+	if n >= 1e12 {
+		return time.UnixMilli(n), nil
+	}
+	return time.Unix(n, 0), nil
+}
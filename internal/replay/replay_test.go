@@ -0,0 +1,32 @@
+package replay
+
+import "testing"
+
+func TestDecodeBattery(t *testing.T) {
+	// A capture from before Batteries: only the single Battery field.
+	old := `{"SchemaVersion":1,"Timestamp":"2024-01-02T15:04:05Z","Battery":{"Percent":64,"State":"Discharging"}}`
+	s, err := decode([]byte(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Batteries) != 1 || s.Batteries[0].Percent != 64 || s.Batteries[0].State != "Discharging" {
+		t.Errorf("Batteries = %+v, want the capture's one battery", s.Batteries)
+	}
+
+	cur := `{"SchemaVersion":1,"Timestamp":1704207845,"Batteries":[{"Name":"BAT0","Percent":10},{"Name":"BAT1","Percent":90}],"Battery":{"Percent":50}}`
+	s, err = decode([]byte(cur))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Batteries) != 2 {
+		t.Errorf("Batteries = %+v, want both", s.Batteries)
+	}
+
+	s, err = decode([]byte(`{"SchemaVersion":1,"Timestamp":1704207845}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Batteries != nil {
+		t.Errorf("no battery: Batteries = %+v", s.Batteries)
+	}
+}
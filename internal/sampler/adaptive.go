@@ -0,0 +1,50 @@
+package sampler
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Pressure scores at or above busyScore halve the -adaptive interval; below
+// idleScore it grows by a quarter. In between it holds, so a steadily
+// half-loaded host keeps a steady cadence.
+const (
+	busyScore = 0.8
+	idleScore = 0.3
+)
+
+// nextInterval is the -adaptive controller: it returns the interval to use
+// after samp, shrinking fast when the host comes under pressure and growing
+// slowly once it calms down, within -adaptive-min and -adaptive-max.
+func (s *Sampler) nextInterval(samp model.Sample) time.Duration {
+	cur := s.Interval
+	next := cur
+	switch score := pressureScore(samp); {
+	case score >= busyScore:
+		next = cur / 2
+	case score < idleScore:
+		next = cur + cur/4
+	}
+	// Round so intervals stay readable (1.25s, not 1.220703125s).
+	next = min(max(next.Round(10*time.Millisecond), s.cfg.AdaptiveMin), s.cfg.AdaptiveMax)
+	if next != cur {
+		slog.Debug("adaptive interval", "from", cur, "to", next)
+	}
+	return next
+}
+
+// pressureScore rates how loaded samp's host is from 0 (idle) to 1: the
+// highest of CPU use, memory use and PSI stall time, where 25% of the last
+// 10s stalled already counts as fully loaded.
+func pressureScore(samp model.Sample) float64 {
+	score := samp.CPU.Total / 100
+	if samp.Memory.TotalBytes > 0 {
+		score = max(score, float64(samp.Memory.UsedBytes)/float64(samp.Memory.TotalBytes))
+	}
+	if p := samp.Pressure; p.Supported {
+		score = max(score, max(p.CPU.Some10, p.Memory.Some10, p.IO.Some10)/25)
+	}
+	return min(score, 1)
+}
@@ -0,0 +1,72 @@
+package sampler
+
+import (
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// Average combines consecutive samples for one-shot output. Utilizations and
+// throughput rates are averaged (per-core and per-GPU element-wise while the
+// shape stays the same); point-in-time state such as memory, load and the
+// process lists comes from the most recent sample.
+func Average(samples []model.Sample) model.Sample {
+	if len(samples) == 0 {
+		return model.Zero()
+	}
+	last := samples[len(samples)-1]
+	if len(samples) == 1 {
+		return last
+	}
+	n := float64(len(samples))
+	out := last
+	out.CPU.Total, out.CPU.Iowait, out.CPU.Steal = 0, 0, 0
+	out.CPU.ContextSwitchesPerSec, out.CPU.InterruptsPerSec, out.CPU.ForksPerSec = 0, 0, 0
+	out.CPU.PerCore = make([]float64, len(last.CPU.PerCore))
+	out.IO.DiskReadMBs, out.IO.DiskWriteMBs, out.IO.NetRxMbps, out.IO.NetTxMbps = 0, 0, 0, 0
+	out.GPUs = append([]model.GPU(nil), last.GPUs...)
+	for i := range out.GPUs {
+		out.GPUs[i].Util = 0
+	}
+
+	var coreN float64
+	gpuN := make([]float64, len(out.GPUs))
+	for _, s := range samples {
+		out.CPU.Total += s.CPU.Total / n
+		out.CPU.Iowait += s.CPU.Iowait / n
+		out.CPU.Steal += s.CPU.Steal / n
+		out.CPU.ContextSwitchesPerSec += s.CPU.ContextSwitchesPerSec / n
+		out.CPU.InterruptsPerSec += s.CPU.InterruptsPerSec / n
+		out.CPU.ForksPerSec += s.CPU.ForksPerSec / n
+		out.IO.DiskReadMBs += s.IO.DiskReadMBs / n
+		out.IO.DiskWriteMBs += s.IO.DiskWriteMBs / n
+		out.IO.NetRxMbps += s.IO.NetRxMbps / n
+		out.IO.NetTxMbps += s.IO.NetTxMbps / n
+		if len(s.CPU.PerCore) == len(out.CPU.PerCore) {
+			coreN++
+			for i, v := range s.CPU.PerCore {
+				out.CPU.PerCore[i] += v
+			}
+		}
+		if len(s.GPUs) == len(out.GPUs) {
+			for i, g := range s.GPUs {
+				if g.Util != model.GPUUnavailable {
+					gpuN[i]++
+					out.GPUs[i].Util += g.Util
+				}
+			}
+		}
+	}
+	for i := range out.CPU.PerCore {
+		out.CPU.PerCore[i] /= coreN
+	}
+	for i := range out.GPUs {
+		if gpuN[i] == 0 {
+			out.GPUs[i].Util = model.GPUUnavailable
+			continue
+		}
+		out.GPUs[i].Util /= gpuN[i]
+	}
+	out.Interval = last.Interval * time.Duration(len(samples))
+	return out
+}
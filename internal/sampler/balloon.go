@@ -0,0 +1,42 @@
+package sampler
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// hasVirtioBalloon reports whether a virtio-balloon device is bound, i.e. we
+// are a VM whose host can reclaim guest memory.
+func hasVirtioBalloon() bool {
+	devs, _ := filepath.Glob("/sys/bus/virtio/drivers/virtio_balloon/virtio*")
+	return len(devs) > 0
+}
+
+// readBalloon reports the pages currently held by the balloon driver
+// (nr_balloon_pages in /proc/vmstat). The kernel takes inflated pages out of
+// MemTotal, so guestTotal is what the guest can actually use.
+func readBalloon(guestTotal uint64) *model.Balloon {
+	f, err := os.Open("/proc/vmstat")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	b := &model.Balloon{GuestTotalBytes: guestTotal}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), " ")
+		if !ok || k != "nr_balloon_pages" {
+			continue
+		}
+		pages, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		b.InflatedBytes = pages * uint64(os.Getpagesize())
+		break
+	}
+	b.ConfiguredBytes = b.GuestTotalBytes + b.InflatedBytes
+	return b
+}
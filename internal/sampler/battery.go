@@ -0,0 +1,97 @@
+package sampler
+
+import (
+	"io/fs"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// batteries reads every /sys/class/power_supply/BAT* in name order.
+func (s *Sampler) batteries() []model.Battery {
+	var out []model.Battery
+	battPaths, _ := fs.Glob(s.FS, "sys/class/power_supply/BAT*/capacity")
+	for _, capPath := range battPaths {
+		base := path.Dir(capPath)
+		capBytes, err := fs.ReadFile(s.FS, capPath)
+		if err != nil {
+			continue
+		}
+		pct := parseFloat(string(capBytes))
+		stateBytes, _ := fs.ReadFile(s.FS, path.Join(base, "status"))
+		state := strings.TrimSpace(string(stateBytes))
+		b := model.Battery{Name: path.Base(base), Percent: pct, State: state}
+		b.PowerW, b.TimeRemaining, b.CapacityWh = batteryPower(s.FS, base, state)
+		b.SecondsRemaining = int64(b.TimeRemaining / time.Second)
+		out = append(out, b)
+	}
+	return out
+}
+
+// batteryPower derives power draw and time to empty (discharging) or full
+// (charging) from a power_supply directory. Drivers report either energy_*
+// (µWh) with power_now (µW), or charge_* (µAh) with current_now (µA); both are
+// handled, with voltage_now (µV) converting between them. Missing files leave
+// the results zero. capacityWh is the full-charge energy. base is relative
+// to fsys.
+func batteryPower(fsys fs.FS, base, state string) (watts float64, remaining time.Duration, capacityWh float64) {
+	read := func(name string) (float64, bool) {
+		b, err := fs.ReadFile(fsys, path.Join(base, name))
+		if err != nil {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+		if err != nil {
+			return 0, false
+		}
+		return math.Abs(v), true // some drivers sign current by direction
+	}
+	volts, hasVolts := read("voltage_now")
+	volts /= 1e6
+
+	// left is the energy (Wh) or charge (Ah) still to go in the current
+	// direction; rate is the matching power (W) or current (A).
+	var left, rate float64
+	if now, ok := read("energy_now"); ok {
+		full, _ := read("energy_full")
+		capacityWh = full / 1e6
+		left = remainingFor(state, now, full) / 1e6
+		if p, ok := read("power_now"); ok {
+			rate = p / 1e6
+		} else if c, ok := read("current_now"); ok && hasVolts {
+			rate = c / 1e6 * volts
+		}
+		watts = rate
+	} else if now, ok := read("charge_now"); ok {
+		full, _ := read("charge_full")
+		capacityWh = full / 1e6 * volts
+		left = remainingFor(state, now, full) / 1e6
+		if c, ok := read("current_now"); ok {
+			rate = c / 1e6
+			if hasVolts {
+				watts = rate * volts
+			}
+		}
+	} else if p, ok := read("power_now"); ok {
+		watts = p / 1e6
+	} else if c, ok := read("current_now"); ok && hasVolts {
+		watts = c / 1e6 * volts
+	}
+
+	if rate > 0 && left > 0 && (state == "Discharging" || state == "Charging") {
+		remaining = time.Duration(left / rate * float64(time.Hour)).Round(time.Minute)
+	}
+	return watts, remaining, capacityWh
+}
+
+// remainingFor is what is left to drain (discharging) or to fill (charging).
+func remainingFor(state string, now, full float64) float64 {
+	if state == "Charging" {
+		return math.Max(full-now, 0)
+	}
+	return now
+}
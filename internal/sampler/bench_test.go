@@ -0,0 +1,47 @@
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// benchProcs is the size of the synthetic process tree, about what a busy
+// build or CI host runs.
+const benchProcs = 2000
+
+// BenchmarkSample measures one full sample, then each reader on its own
+// under the name it has in SelfStats. Processes, their status and cgroup
+// files and the sysfs readers use the synthetic fixture; cpu, mem, io and
+// pressure read this host's counters.
+func BenchmarkSample(b *testing.B) {
+	procs, fsys := syntheticTree(benchProcs)
+	s := fixtureSampler(b, config.Default(), procs, fsys)
+	temps := s.temps()
+
+	run := func(name string, fn func()) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				fn()
+			}
+		})
+	}
+	run("all", func() { s.sample(time.Now()) })
+	run("procs", func() { s.topProcs() })
+	run("cpu", func() { s.cpuPercents() })
+	run("mem", func() {
+		mem.VirtualMemory()
+		mem.SwapMemory()
+		readSwapCounters()
+	})
+	run("io", func() { s.ioNet() })
+	run("battery", func() { s.batteries() })
+	run("inotify", func() { s.inotify() })
+	run("temps", func() { s.temps() })
+	run("sensors", func() { s.sensors(temps) })
+	run("numa", func() { s.numaNodes() })
+	run("pressure", func() { readPressure() })
+}
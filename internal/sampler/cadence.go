@@ -0,0 +1,71 @@
+package sampler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// cadenced holds the latest result of every collector -cadence can slow
+// down; sample reuses it on ticks where the collector doesn't run.
+type cadenced struct {
+	top, niced, cpuThrottled []model.Process
+	threads                  []model.Process
+	cgroups                  []model.Cgroup
+	users                    []model.UserUsage
+	temps                    []model.Temp
+	sensors                  []model.Sensor
+	numa                     []model.NUMANode
+	batts                    []model.Battery
+	inotify                  model.Inotify
+	openFDs, maxFDs          uint64
+}
+
+// every is how many ticks apart collector name runs.
+func (s *Sampler) every(name string) int {
+	return max(s.cfg.Cadence[name], 1)
+}
+
+// due reports whether collector name runs on this tick and, if so, records
+// now as its collection time. Every collector runs on the first tick.
+func (s *Sampler) due(name string, now time.Time) bool {
+	if s.tick%s.every(name) != 0 {
+		return false
+	}
+	if prev, ok := s.ranAt[name]; ok {
+		s.spans[name] = now.Sub(prev)
+	}
+	s.ranAt[name] = now
+	return true
+}
+
+// span is the time in seconds since collector name last ran, which its
+// per-second rates are computed over: its cadence times the interval, or
+// more when ticks were skipped.
+func (s *Sampler) span(name string) float64 {
+	dt, ok := s.spans[name]
+	if !ok {
+		dt = s.Interval * time.Duration(s.every(name))
+	}
+	if dt <= 0 {
+		return 1
+	}
+	return dt.Seconds()
+}
+
+// cadenceAges reports when each collector slowed down by -cadence last ran.
+func (s *Sampler) cadenceAges(now time.Time) []model.SectionAge {
+	names := make([]string, 0, len(s.cfg.Cadence))
+	for name, n := range s.cfg.Cadence {
+		if n > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	ages := make([]model.SectionAge, 0, len(names))
+	for _, name := range names {
+		ages = append(ages, sectionAge(name, s.ranAt[name], s.Interval*time.Duration(s.every(name)), now))
+	}
+	return ages
+}
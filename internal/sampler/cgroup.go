@@ -0,0 +1,162 @@
+package sampler
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// cgroupRoot is where the unified (v2) hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupRef identifies a process's cgroup: the path relative to the hierarchy
+// root (as listed in /proc/<pid>/cgroup) and its last component for display.
+type cgroupRef struct {
+	name string
+	path string
+}
+
+// cgroupIO holds cumulative io.stat byte counters summed across devices.
+type cgroupIO struct {
+	read  uint64
+	write uint64
+}
+
+// readCgroupIOStat parses cgroup v2 io.stat, whose lines are keyed by device:
+//
+//	8:0 rbytes=1234 wbytes=5678 rios=1 wios=2 dbytes=0 dios=0
+func readCgroupIOStat(path string) (cgroupIO, error) {
+	f, err := os.Open(filepath.Join(cgroupRoot, path, "io.stat"))
+	if err != nil {
+		return cgroupIO{}, err
+	}
+	defer f.Close()
+	var io cgroupIO
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		for _, kv := range fields[min(1, len(fields)):] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseUint(v, 10, 64)
+			switch k {
+			case "rbytes":
+				io.read += n
+			case "wbytes":
+				io.write += n
+			}
+		}
+	}
+	return io, sc.Err()
+}
+
+// cgroupCPUStat holds cumulative CFS bandwidth throttling counters.
+type cgroupCPUStat struct {
+	nrThrottled   uint64
+	throttledUsec uint64
+}
+
+// cgroupV1CPUDirs are where the v1 cpu controller is commonly mounted.
+var cgroupV1CPUDirs = []string{"cpu,cpuacct", "cpu"}
+
+// readCgroupCPUStat reads throttling counters from cpu.stat: v2 reports
+// nr_throttled and throttled_usec; v1 (under the cpu controller's own mount)
+// reports nr_throttled and throttled_time in nanoseconds. ok is false when
+// neither is available, e.g. the cpu controller isn't enabled for the group.
+func readCgroupCPUStat(path string) (cgroupCPUStat, bool) {
+	if st, ok := parseCPUStat(filepath.Join(cgroupRoot, path, "cpu.stat")); ok {
+		return st, true
+	}
+	for _, dir := range cgroupV1CPUDirs {
+		if st, ok := parseCPUStat(filepath.Join(cgroupRoot, dir, path, "cpu.stat")); ok {
+			return st, true
+		}
+	}
+	return cgroupCPUStat{}, false
+}
+
+func parseCPUStat(file string) (cgroupCPUStat, bool) {
+	var st cgroupCPUStat
+	f, err := os.Open(file)
+	if err != nil {
+		return st, false
+	}
+	defer f.Close()
+	found := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), " ")
+		if !ok {
+			continue
+		}
+		n, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		switch k {
+		case "nr_throttled":
+			st.nrThrottled = n
+			found = true
+		case "throttled_usec":
+			st.throttledUsec = n
+		case "throttled_time": // v1, nanoseconds
+			st.throttledUsec = n / 1000
+		}
+	}
+	return st, found
+}
+
+// pidsNearLimit is the pids.current/pids.max ratio at which a cgroup is
+// flagged: past it, fork/clone start failing with EAGAIN.
+const pidsNearLimit = 0.9
+
+// readCgroupPids reads the v2 pids controller. max is 0 when the limit is
+// "max" (unlimited).
+func readCgroupPids(path string) (current, max uint64, err error) {
+	dir := filepath.Join(cgroupRoot, path)
+	b, err := os.ReadFile(filepath.Join(dir, "pids.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err = strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "pids.max"))
+	if err != nil {
+		return current, 0, nil
+	}
+	if v := strings.TrimSpace(string(b)); v != "max" {
+		max, _ = strconv.ParseUint(v, 10, 64)
+	}
+	return current, max, nil
+}
+
+// Cgroup memory sources, reported in model.Cgroup.MemorySource.
+const (
+	memSourceCurrent    = "memory.current"
+	memSourceProcessSum = "process-sum"
+)
+
+// cgroupMemory fills cg's memory from the v2 memory.current file when
+// readable (authoritative, includes page cache and kernel memory charged to
+// the cgroup); otherwise it falls back to procSum, the summed memory percent
+// of the member processes (resident memory only).
+func (s *Sampler) cgroupMemory(cg *model.Cgroup, path string, procSum float64) {
+	if b, err := os.ReadFile(filepath.Join(cgroupRoot, path, "memory.current")); err == nil {
+		if v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64); err == nil {
+			cg.MemoryBytes = v
+			if s.memTotal > 0 {
+				cg.Memory = float64(v) / float64(s.memTotal) * 100
+			}
+			cg.MemorySource = memSourceCurrent
+			return
+		}
+	}
+	cg.Memory = procSum
+	cg.MemoryBytes = uint64(procSum / 100 * float64(s.memTotal))
+	cg.MemorySource = memSourceProcessSum
+}
@@ -0,0 +1,83 @@
+package sampler
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// connPollInterval is how often connLoop recounts sockets. Busy servers have
+// tens of thousands of lines in /proc/net/tcp*, so this runs off the main tick.
+const connPollInterval = 5 * time.Second
+
+// TCP states as hex in the "st" column of /proc/net/tcp (include/net/tcp_states.h).
+const (
+	tcpEstablished = "01"
+	tcpTimeWait    = "06"
+	tcpListen      = "0A"
+)
+
+func (s *Sampler) connLoop(ctx context.Context) {
+	s.updateConnections()
+	ticker := time.NewTicker(connPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.updateConnections()
+		}
+	}
+}
+
+func (s *Sampler) updateConnections() {
+	c := countConnections()
+	s.connMu.Lock()
+	s.connData = c
+	s.connAt = time.Now()
+	s.connMu.Unlock()
+}
+
+// countConnections reads /proc/net/{tcp,tcp6,udp,udp6} directly, which is
+// much cheaper than resolving owning PIDs the way net.Connections does.
+func countConnections() model.Connections {
+	var c model.Connections
+	for _, name := range []string{"tcp", "tcp6"} {
+		eachSocket("/proc/net/"+name, func(fields []string) {
+			switch fields[3] {
+			case tcpEstablished:
+				c.TCPEstablished++
+			case tcpListen:
+				c.TCPListen++
+			case tcpTimeWait:
+				c.TCPTimeWait++
+			}
+			c.TCPTotal++
+		})
+	}
+	for _, name := range []string{"udp", "udp6"} {
+		eachSocket("/proc/net/"+name, func([]string) { c.UDP++ })
+	}
+	return c
+}
+
+// eachSocket calls fn with the fields of every socket line, skipping the header.
+func eachSocket(path string, fn func(fields []string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) > 3 {
+			fn(fields)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package sampler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskPollInterval is how often diskLoop re-stats mounts (-disk-usage).
+// Capacity changes slowly, and statfs on network mounts can block, so this
+// runs off the main tick.
+const diskPollInterval = 10 * time.Second
+
+// diskStatTimeout bounds statfs on a single mount. A mount that times out is
+// reported as Stale and skipped until its pending call returns, so a dead NFS
+// server costs at most one stuck goroutine.
+const diskStatTimeout = 2 * time.Second
+
+// pseudoFS are filesystems without meaningful capacity, skipped unless
+// -disk-usage-all. squashfs (snaps, live images) is always 100% full.
+var pseudoFS = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "ramfs": true, "proc": true, "sysfs": true,
+	"cgroup": true, "cgroup2": true, "devpts": true, "mqueue": true, "hugetlbfs": true,
+	"debugfs": true, "tracefs": true, "securityfs": true, "pstore": true, "bpf": true,
+	"configfs": true, "fusectl": true, "autofs": true, "binfmt_misc": true,
+	"efivarfs": true, "nsfs": true, "rpc_pipefs": true, "squashfs": true,
+}
+
+func (s *Sampler) diskLoop(ctx context.Context) {
+	st := &mountStatter{pending: make(map[string]bool)}
+	s.updateDisks(st)
+	ticker := time.NewTicker(diskPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.updateDisks(st)
+		}
+	}
+}
+
+func (s *Sampler) updateDisks(st *mountStatter) {
+	parts, err := disk.Partitions(s.cfg.DiskUsageAll)
+	s.health.report("partitions", err)
+	var disks []model.Disk
+	seen := make(map[string]bool)
+	for _, p := range parts {
+		if seen[p.Mountpoint] || (!s.cfg.DiskUsageAll && pseudoFS[p.Fstype]) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+		d := model.Disk{Mountpoint: p.Mountpoint, Device: p.Device, Fstype: p.Fstype}
+		u, ok := st.usage(p.Mountpoint)
+		if !ok {
+			d.Stale = true
+		} else if u != nil {
+			d.UsedBytes, d.TotalBytes, d.Percent = u.Used, u.Total, u.UsedPercent
+		}
+		if d.Stale || d.TotalBytes > 0 {
+			disks = append(disks, d)
+		}
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Mountpoint < disks[j].Mountpoint })
+
+	s.diskMu.Lock()
+	s.diskData = disks
+	s.diskAt = time.Now()
+	s.diskMu.Unlock()
+}
+
+// mountStatter runs statfs with a timeout and remembers mounts whose call is
+// still outstanding, so they are not stat-ed again until it returns.
+type mountStatter struct {
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// usage returns the mount's usage, or ok=false if it timed out now or on an
+// earlier poll. A nil result with ok=true means statfs failed outright.
+func (m *mountStatter) usage(mount string) (u *disk.UsageStat, ok bool) {
+	m.mu.Lock()
+	busy := m.pending[mount]
+	m.mu.Unlock()
+	if busy {
+		return nil, false
+	}
+
+	type result struct {
+		u   *disk.UsageStat
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		u, err := disk.Usage(mount)
+		ch <- result{u, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.u, true
+	case <-time.After(diskStatTimeout):
+		m.mu.Lock()
+		m.pending[mount] = true
+		m.mu.Unlock()
+		go func() {
+			<-ch
+			m.mu.Lock()
+			delete(m.pending, mount)
+			m.mu.Unlock()
+		}()
+		return nil, false
+	}
+}
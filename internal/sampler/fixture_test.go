@@ -0,0 +1,136 @@
+package sampler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// fakeProc is a Proc with fixed values. Methods named in fail return a zero
+// value and an error instead, as for a process that exits mid-scan.
+type fakeProc struct {
+	pid         int
+	name, cmd   string
+	start       int64 // ms since the epoch
+	cpuSecs     float64
+	mem         float32
+	nice        int
+	read, write uint64
+	uid         int32
+	fail        map[string]bool
+}
+
+var errGone = errors.New("process gone")
+
+// result returns v, or the zero value and errGone if method fails.
+func result[T any](p *fakeProc, method string, v T) (T, error) {
+	if p.fail[method] {
+		var zero T
+		return zero, errGone
+	}
+	return v, nil
+}
+
+func (p *fakeProc) PID() int                        { return p.pid }
+func (p *fakeProc) Name() (string, error)           { return result(p, "Name", p.name) }
+func (p *fakeProc) Cmdline() (string, error)        { return result(p, "Cmdline", p.cmd) }
+func (p *fakeProc) CreateTime() (int64, error)      { return result(p, "CreateTime", p.start) }
+func (p *fakeProc) MemoryPercent() (float32, error) { return result(p, "MemoryPercent", p.mem) }
+func (p *fakeProc) Times() (*cpu.TimesStat, error) {
+	return result(p, "Times", &cpu.TimesStat{User: p.cpuSecs * 0.75, System: p.cpuSecs * 0.25})
+}
+
+// Nice returns the raw getpriority(2) value, as gopsutil does.
+func (p *fakeProc) Nice() (int32, error) { return result(p, "Nice", int32(20-p.nice)) }
+func (p *fakeProc) IOCounters() (*process.IOCountersStat, error) {
+	return result(p, "IOCounters", &process.IOCountersStat{ReadBytes: p.read, WriteBytes: p.write})
+}
+func (p *fakeProc) Uids() ([]int32, error) {
+	return result(p, "Uids", []int32{p.uid, p.uid, p.uid, p.uid})
+}
+
+// procStatusFile renders a /proc/<pid>/status with the fields readProcStatus
+// reads.
+func procStatusFile(name, state string, ppid, threads int, rssKB uint64) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(fmt.Sprintf(
+		"Name:\t%s\nState:\t%s\nPPid:\t%d\nUid:\t1000\t1000\t1000\t1000\nVmPeak:\t%d kB\nVmHWM:\t%d kB\nVmRSS:\t%d kB\nThreads:\t%d\n",
+		name, state, ppid, rssKB*3, rssKB*2, rssKB, threads))}
+}
+
+// syntheticTree returns n processes forming a tree eight children wide under
+// PID 100, spread over n/20 systemd services, and a /proc and /sys fixture
+// with their status and cgroup files plus thermal zones, hwmon chips, a
+// battery, two NUMA nodes and inotify limits.
+func syntheticTree(n int) ([]Proc, fstest.MapFS) {
+	start := time.Now().Add(-time.Hour).UnixMilli()
+	fsys := fstest.MapFS{
+		"proc/sys/fs/inotify/max_user_watches":    {Data: []byte("65536\n")},
+		"proc/sys/fs/inotify/max_user_instances":  {Data: []byte("128\n")},
+		"proc/sys/fs/inotify/nr_watches":          {Data: []byte("1024\n")},
+		"sys/class/power_supply/BAT0/capacity":    {Data: []byte("80\n")},
+		"sys/class/power_supply/BAT0/status":      {Data: []byte("Discharging\n")},
+		"sys/class/power_supply/BAT0/energy_now":  {Data: []byte("40000000\n")},
+		"sys/class/power_supply/BAT0/energy_full": {Data: []byte("50000000\n")},
+		"sys/class/power_supply/BAT0/power_now":   {Data: []byte("10000000\n")},
+	}
+	for z := 0; z < 4; z++ {
+		fsys[fmt.Sprintf("sys/class/thermal/thermal_zone%d/temp", z)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("%d\n", 40000+z*5000))}
+		fsys[fmt.Sprintf("sys/class/thermal/thermal_zone%d/type", z)] = &fstest.MapFile{Data: []byte("x86_pkg_temp\n")}
+	}
+	for h := 0; h < 3; h++ {
+		dir := fmt.Sprintf("sys/class/hwmon/hwmon%d/", h)
+		fsys[dir+"name"] = &fstest.MapFile{Data: []byte(fmt.Sprintf("nct%d\n", h))}
+		fsys[dir+"temp1_input"] = &fstest.MapFile{Data: []byte("45000\n")}
+		fsys[dir+"temp1_label"] = &fstest.MapFile{Data: []byte("SYSTIN\n")}
+		fsys[dir+"fan1_input"] = &fstest.MapFile{Data: []byte("1200\n")}
+		fsys[dir+"in0_input"] = &fstest.MapFile{Data: []byte("1104\n")}
+	}
+	for node := 0; node < 2; node++ {
+		fsys[fmt.Sprintf("sys/devices/system/node/node%d/meminfo", node)] = &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			"Node %d MemTotal:       16384000 kB\nNode %d MemFree:         8192000 kB\n", node, node))}
+	}
+
+	procs := make([]Proc, n)
+	for i := range procs {
+		pid := 100 + i
+		ppid := 1
+		if i > 0 {
+			ppid = 100 + (i-1)/8
+		}
+		name := fmt.Sprintf("worker%d", i%50)
+		procs[i] = &fakeProc{
+			pid:     pid,
+			name:    name,
+			cmd:     fmt.Sprintf("/usr/bin/%s --id %d", name, i),
+			start:   start,
+			cpuSecs: float64(i % 97),
+			mem:     float32(i%13) / 10,
+			nice:    i % 3 * 5,
+			read:    uint64(i) << 12,
+			write:   uint64(i) << 10,
+			uid:     int32(1000 + i%4),
+		}
+		dir := fmt.Sprintf("proc/%d/", pid)
+		fsys[dir+"status"] = procStatusFile(name, "S (sleeping)", ppid, 1+i%16, uint64(1024+i))
+		fsys[dir+"cgroup"] = &fstest.MapFile{Data: []byte(fmt.Sprintf("0::/system.slice/svc%d.service\n", i%max(n/20, 1)))}
+	}
+	return procs, fsys
+}
+
+// fixtureSampler returns a Sampler for cfg reading procs and fsys instead of
+// the host's processes, /proc and /sys files. CPU, memory, disk, network and
+// PSI counters still come from the host.
+func fixtureSampler(tb testing.TB, cfg config.Config, procs []Proc, fsys fstest.MapFS) *Sampler {
+	tb.Helper()
+	cfg.EnableGPU = false
+	s := NewWithConfig(cfg)
+	s.FS = fsys
+	s.Processes = func() ([]Proc, error) { return procs, nil }
+	return s
+}
@@ -0,0 +1,208 @@
+package sampler
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// mapFS builds an fstest.MapFS from path -> contents.
+func mapFS(files map[string]string) fstest.MapFS {
+	fsys := make(fstest.MapFS, len(files))
+	for name, data := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	return fsys
+}
+
+func TestTempsAndSensors(t *testing.T) {
+	s := fixtureSampler(t, config.Config{}, nil, mapFS(map[string]string{
+		"sys/class/thermal/thermal_zone0/temp":  "45000\n",
+		"sys/class/thermal/thermal_zone0/type":  "x86_pkg_temp\n",
+		"sys/class/thermal/thermal_zone1/temp":  "38500\n", // no type file
+		"sys/class/thermal/thermal_zone2/temp":  "garbage\n",
+		"sys/class/thermal/cooling_device0/cur": "1\n",
+		// The thermal core's mirror of zone 0: its temperature is a duplicate.
+		"sys/class/hwmon/hwmon0/name":        "x86_pkg_temp\n",
+		"sys/class/hwmon/hwmon0/temp1_input": "45000\n",
+		"sys/class/hwmon/hwmon1/name":        "nct6775\n",
+		"sys/class/hwmon/hwmon1/temp1_input": "41000\n",
+		"sys/class/hwmon/hwmon1/temp1_label": "SYSTIN\n",
+		"sys/class/hwmon/hwmon1/fan2_input":  "1150\n",
+		"sys/class/hwmon/hwmon1/in0_input":   "1104\n",
+		"sys/class/hwmon/hwmon1/fan3_input":  "", // disconnected header
+	}))
+
+	temps := s.temps()
+	wantTemps := []model.Temp{
+		{Zone: "thermal_zone0", Label: "x86_pkg_temp", Temp: 45},
+		{Zone: "thermal_zone1", Label: "thermal_zone1", Temp: 38.5},
+		{Zone: "thermal_zone2", Label: "thermal_zone2", Temp: 0},
+	}
+	if !reflect.DeepEqual(temps, wantTemps) {
+		t.Errorf("temps = %+v, want %+v", temps, wantTemps)
+	}
+
+	wantSensors := []model.Sensor{
+		{Chip: "nct6775", Label: "SYSTIN", Kind: model.SensorTemp, Value: 41},
+		{Chip: "nct6775", Label: "fan2", Kind: model.SensorFan, Value: 1150},
+		{Chip: "nct6775", Label: "in0", Kind: model.SensorVoltage, Value: 1.104},
+	}
+	if got := s.sensors(temps); !reflect.DeepEqual(got, wantSensors) {
+		t.Errorf("sensors = %+v, want %+v", got, wantSensors)
+	}
+}
+
+func TestInotify(t *testing.T) {
+	s := fixtureSampler(t, config.Config{}, nil, mapFS(map[string]string{
+		"proc/sys/fs/inotify/max_user_watches":   "524288\n",
+		"proc/sys/fs/inotify/max_user_instances": "128\n",
+	}))
+	want := model.Inotify{MaxUserWatches: 524288, MaxUserInstances: 128}
+	if got := s.inotify(); got != want {
+		t.Errorf("inotify = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadProcStatus(t *testing.T) {
+	fsys := mapFS(map[string]string{
+		"proc/42/status": "Name:\tpostgres\nUmask:\t0077\nState:\tD (disk sleep)\nTgid:\t42\nPPid:\t1\n" +
+			"VmPeak:\t  300000 kB\nVmHWM:\t  200000 kB\nVmRSS:\t  150000 kB\nThreads:\t7\n",
+	})
+	got, err := readProcStatus(fsys, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := procStatus{rss: 150000 << 10, hwm: 200000 << 10, peak: 300000 << 10, threads: 7, state: "D", ppid: 1}
+	if got != want {
+		t.Errorf("readProcStatus = %+v, want %+v", got, want)
+	}
+	if _, err := readProcStatus(fsys, 43); err == nil {
+		t.Error("readProcStatus of a missing PID: no error")
+	}
+}
+
+func TestReadProcCgroup(t *testing.T) {
+	s := fixtureSampler(t, config.Config{}, nil, mapFS(map[string]string{
+		"proc/1/cgroup": "0::/init.scope\n",
+		// Hybrid hierarchy: the v1 lines come first but v2 wins.
+		"proc/2/cgroup": "12:cpu,cpuacct:/system.slice/old.service\n1:name=systemd:/system.slice/old.service\n0::/system.slice/nginx.service\n",
+		// v1 only.
+		"proc/3/cgroup": "4:memory:/docker/abc123\n",
+		"proc/4/cgroup": "0::/\n",
+	}))
+	tests := []struct {
+		pid     int
+		want    cgroupRef
+		wantErr bool
+	}{
+		{1, cgroupRef{name: "init.scope", path: "/init.scope"}, false},
+		{2, cgroupRef{name: "nginx.service", path: "/system.slice/nginx.service"}, false},
+		{3, cgroupRef{name: "abc123", path: "/docker/abc123"}, false},
+		{4, cgroupRef{}, true},
+		{5, cgroupRef{}, true},
+	}
+	for _, tt := range tests {
+		got, err := s.readProcCgroup(tt.pid)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("readProcCgroup(%d) = %+v, %v; want %+v, error %v", tt.pid, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBatteryPower(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		state     string
+		watts     float64
+		remaining time.Duration
+		capWh     float64
+	}{
+		{"energy, discharging", map[string]string{
+			"energy_now": "25000000", "energy_full": "50000000", "power_now": "12500000",
+		}, "Discharging", 12.5, 2 * time.Hour, 50},
+		{"energy, charging", map[string]string{
+			"energy_now": "25000000", "energy_full": "50000000", "power_now": "25000000",
+		}, "Charging", 25, time.Hour, 50},
+		{"charge with signed current", map[string]string{
+			"charge_now": "2000000", "charge_full": "4000000", "current_now": "-1000000", "voltage_now": "12000000",
+		}, "Discharging", 12, 2 * time.Hour, 48},
+		{"full: no time", map[string]string{
+			"energy_now": "50000000", "energy_full": "50000000", "power_now": "0",
+		}, "Full", 0, 0, 50},
+		{"power only", map[string]string{"power_now": "7000000"}, "Discharging", 7, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]string)
+			for name, v := range tt.files {
+				files["BAT0/"+name] = v + "\n"
+			}
+			watts, remaining, capWh := batteryPower(mapFS(files), "BAT0", tt.state)
+			if watts != tt.watts || remaining != tt.remaining || capWh != tt.capWh {
+				t.Errorf("batteryPower = %v W, %s, %v Wh; want %v W, %s, %v Wh", watts, remaining, capWh, tt.watts, tt.remaining, tt.capWh)
+			}
+		})
+	}
+}
+
+func TestBatteries(t *testing.T) {
+	// A ThinkPad with an internal and a hot-swappable cell; the AC adapter
+	// and a wireless mouse are other power_supply entries.
+	s := fixtureSampler(t, config.Config{}, nil, mapFS(map[string]string{
+		"sys/class/power_supply/AC/online":          "0\n",
+		"sys/class/power_supply/hid-mouse/capacity": "40\n",
+		"sys/class/power_supply/BAT0/capacity":      "90\n",
+		"sys/class/power_supply/BAT0/status":        "Unknown\n",
+		"sys/class/power_supply/BAT0/energy_now":    "21600000\n",
+		"sys/class/power_supply/BAT0/energy_full":   "24000000\n",
+		"sys/class/power_supply/BAT1/capacity":      "30\n",
+		"sys/class/power_supply/BAT1/status":        "Discharging\n",
+		"sys/class/power_supply/BAT1/energy_now":    "21600000\n",
+		"sys/class/power_supply/BAT1/energy_full":   "72000000\n",
+		"sys/class/power_supply/BAT1/power_now":     "10800000\n",
+	}))
+	got := s.batteries()
+	if len(got) != 2 || got[0].Name != "BAT0" || got[1].Name != "BAT1" {
+		t.Fatalf("batteries = %+v, want BAT0 and BAT1", got)
+	}
+	if got[0].Percent != 90 || got[0].CapacityWh != 24 || got[0].State != "Unknown" {
+		t.Errorf("BAT0 = %+v", got[0])
+	}
+	if got[1].Percent != 30 || got[1].CapacityWh != 72 || got[1].PowerW != 10.8 || got[1].TimeRemaining != 2*time.Hour {
+		t.Errorf("BAT1 = %+v", got[1])
+	}
+
+	// Percent weighted by capacity: (90*24 + 30*72) / 96 = 45; 43.2 Wh
+	// left at 10.8 W is 4h.
+	primary := model.Sample{Batteries: got}.PrimaryBattery()
+	want := model.Battery{Percent: 45, State: "Discharging", PowerW: 10.8, CapacityWh: 96, TimeRemaining: 4 * time.Hour, SecondsRemaining: 4 * 3600}
+	if primary != want {
+		t.Errorf("PrimaryBattery = %+v, want %+v", primary, want)
+	}
+}
+
+// TestCoreFreqs has CPU 1 offline: the cores are cpu0, cpu2 and cpu3, and
+// each must get its own clock.
+func TestCoreFreqs(t *testing.T) {
+	s := fixtureSampler(t, config.Config{}, nil, mapFS(map[string]string{
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_cur_freq": "3400000\n",
+		"sys/devices/system/cpu/cpu1/cpufreq/scaling_cur_freq": "800000\n",
+		"sys/devices/system/cpu/cpu2/cpufreq/scaling_cur_freq": "2200000\n",
+		"sys/devices/system/cpu/cpu3/cpufreq/scaling_cur_freq": "1200000\n",
+	}))
+	cores := []cpu.TimesStat{{CPU: "cpu0"}, {CPU: "cpu2"}, {CPU: "cpu3"}}
+	if got, want := s.coreFreqs(cores), []float64{3400, 2200, 1200}; !reflect.DeepEqual(got, want) {
+		t.Errorf("coreFreqs = %v, want %v", got, want)
+	}
+	// Without cpufreq, as on many VMs.
+	if got := fixtureSampler(t, config.Config{}, nil, mapFS(nil)).coreFreqs(cores); got != nil {
+		t.Errorf("no cpufreq: coreFreqs = %v, want nil", got)
+	}
+}
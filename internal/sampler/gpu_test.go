@@ -0,0 +1,69 @@
+package sampler
+
+import (
+	"testing"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+func TestParseGPUsOrder(t *testing.T) {
+	// nvidia-smi lists in PCI order; with CUDA_DEVICE_ORDER or after a card
+	// reset, that isn't index order.
+	const out = `2, GPU-c, NVIDIA A100-SXM4-40GB, 97, 30000, 40960, 71, 00000000:81:00.0, 40, 0, 0, Disabled
+0, GPU-a, NVIDIA A100-SXM4-40GB, 3, 1024, 40960, 34, 00000000:07:00.0, 1, 0, 0, Enabled
+1, GPU-b, NVIDIA A100-SXM4-40GB, 50, 20000, 40960, 55, 00000000:0F:00.0, 20, 5, 6, Disabled
+`
+	gpus := parseGPUs(out, gpuBaseFields+gpuEngineFields+gpuMIGField)
+	if len(gpus) != 3 {
+		t.Fatalf("got %d GPUs, want 3", len(gpus))
+	}
+	for i, uuid := range []string{"GPU-a", "GPU-b", "GPU-c"} {
+		if g := gpus[i]; g.Index != i || g.UUID != uuid {
+			t.Errorf("gpus[%d] = index %d %s, want index %d %s", i, g.Index, g.UUID, i, uuid)
+		}
+	}
+	b := gpus[1]
+	if b.Util != 50 || b.MemUsedMB != 20000 || b.TempC != 55 || b.BusID != "00000000:0F:00.0" ||
+		b.MemUtil != 20 || b.EncoderUtil != 5 || b.DecoderUtil != 6 || b.MIGEnabled {
+		t.Errorf("GPU 1 = %+v", b)
+	}
+	if !gpus[0].MIGEnabled {
+		t.Error("GPU 0: MIG not enabled")
+	}
+}
+
+func TestGPUField(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{" 71", 71},
+		{"0", 0},
+		{" [N/A]", model.GPUUnavailable},
+		{"[Not Supported]", model.GPUUnavailable},
+		{" [Unknown Error]", model.GPUUnavailable},
+	}
+	for _, tt := range tests {
+		if got := gpuField(tt.in); got != tt.want {
+			t.Errorf("gpuField(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestParseGPUsUnavailable has a passively cooled card without a
+// temperature sensor and a consumer card without encoder counters.
+func TestParseGPUsUnavailable(t *testing.T) {
+	const out = `0, GPU-a, NVIDIA A16, 12, 2048, 16384, [N/A], 00000000:01:00.0, 3, [Not Supported], [Not Supported]
+`
+	gpus := parseGPUs(out, gpuBaseFields+gpuEngineFields)
+	if len(gpus) != 1 {
+		t.Fatalf("got %d GPUs, want 1", len(gpus))
+	}
+	g := gpus[0]
+	if g.TempC != model.GPUUnavailable || g.EncoderUtil != model.GPUUnavailable || g.DecoderUtil != model.GPUUnavailable {
+		t.Errorf("unavailable readings: temp %v, encoder %v, decoder %v; want %v", g.TempC, g.EncoderUtil, g.DecoderUtil, model.GPUUnavailable)
+	}
+	if g.Util != 12 || g.MemUtil != 3 {
+		t.Errorf("available readings: util %v, mem util %v", g.Util, g.MemUtil)
+	}
+}
@@ -0,0 +1,104 @@
+package sampler
+
+import (
+	"bufio"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// nvidiaProcs attaches each compute process to its GPU. compute-apps has no
+// GPU index, so rows are matched on the PCI bus id the GPU query reports.
+// Failures only lose the process lists, so they are logged, not reported.
+func (s *Sampler) nvidiaProcs(gpus []model.GPU) {
+	out, err := runCmd(s.Runner, s.cfg.GPUTimeout, "nvidia-smi",
+		"--query-compute-apps=gpu_bus_id,pid,used_memory,process_name", "--format=csv,noheader,nounits")
+	if err != nil {
+		slog.Debug("nvidia-smi compute-apps query failed", "err", err)
+		return
+	}
+	procs := parseNvidiaProcs(out)
+	for i := range gpus {
+		gpus[i].Procs = procs[normBusID(gpus[i].BusID)]
+	}
+}
+
+// parseNvidiaProcs parses compute-apps rows into processes keyed by bus id,
+// each list sorted by memory, largest first.
+func parseNvidiaProcs(out string) map[string][]model.GPUProc {
+	procs := make(map[string][]model.GPUProc)
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		// process_name is last so a comma in it can't shift the other fields.
+		parts := strings.SplitN(sc.Text(), ",", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			continue
+		}
+		bus := normBusID(parts[0])
+		procs[bus] = append(procs[bus], model.GPUProc{
+			PID:       pid,
+			Command:   filepath.Base(strings.TrimSpace(parts[3])),
+			MemUsedMB: parseFloat(parts[2]),
+		})
+	}
+	for _, list := range procs {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].MemUsedMB > list[j].MemUsedMB })
+	}
+	return procs
+}
+
+// normBusID makes bus ids comparable: nvidia-smi prints an 8-digit PCI
+// domain in some queries ("00000000:01:00.0") and 4 digits in others.
+func normBusID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if dom, rest, ok := strings.Cut(id, ":"); ok && len(dom) > 4 {
+		id = dom[len(dom)-4:] + ":" + rest
+	}
+	return id
+}
+
+// rocmProcs attaches KFD processes to the AMD GPU. rocm-smi --showpids
+// only gives each process's GPU count, not which GPUs, so the list is only
+// attached on single-GPU hosts.
+func (s *Sampler) rocmProcs(gpus []model.GPU) {
+	if len(gpus) != 1 {
+		return
+	}
+	out, err := runCmd(s.Runner, max(s.cfg.GPUTimeout, minROCmTimeout), "rocm-smi", "--showpids")
+	if err != nil {
+		slog.Debug("rocm-smi --showpids failed", "err", err)
+		return
+	}
+	gpus[0].Procs = parseROCmProcs(out)
+}
+
+// parseROCmProcs parses the KFD process table of rocm-smi --showpids:
+//
+//	PID     PROCESS NAME  GPU(s)  VRAM USED  SDMA USED  CU OCCUPANCY
+//	12345   python3       1       1073741824 0          0
+func parseROCmProcs(out string) []model.GPUProc {
+	var procs []model.GPUProc
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(f[0])
+		if err != nil {
+			continue
+		}
+		const mb = 1024 * 1024
+		procs = append(procs, model.GPUProc{PID: pid, Command: f[1], MemUsedMB: parseFloat(f[3]) / mb})
+	}
+	sort.SliceStable(procs, func(i, j int) bool { return procs[i].MemUsedMB > procs[j].MemUsedMB })
+	return procs
+}
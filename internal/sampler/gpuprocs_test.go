@@ -0,0 +1,66 @@
+package sampler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+func TestParseNvidiaProcs(t *testing.T) {
+	// nvidia-smi --query-compute-apps=gpu_bus_id,pid,used_memory,process_name
+	// --format=csv,noheader,nounits on a two-GPU host.
+	const out = `00000000:01:00.0, 2211, 512, /usr/bin/python3
+00000000:01:00.0, 2305, 10240, /opt/app/bin/trainer, with commas
+00000000:41:00.0, 3120, [N/A], ./ollama
+garbage line
+00000000:41:00.0, notapid, 10, x
+`
+	got := parseNvidiaProcs(out)
+	want := map[string][]model.GPUProc{
+		"0000:01:00.0": {
+			{PID: 2305, Command: "trainer, with commas", MemUsedMB: 10240},
+			{PID: 2211, Command: "python3", MemUsedMB: 512},
+		},
+		"0000:41:00.0": {
+			{PID: 3120, Command: "ollama"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNvidiaProcs =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestNormBusID(t *testing.T) {
+	for _, id := range []string{"00000000:01:00.0", "0000:01:00.0", " 00000000:01:00.0 ", "0000:01:00.0"} {
+		if got := normBusID(id); got != "0000:01:00.0" {
+			t.Errorf("normBusID(%q) = %q", id, got)
+		}
+	}
+	if got := normBusID("00000000:0A:00.0"); got != "0000:0a:00.0" {
+		t.Errorf("normBusID upper case = %q", got)
+	}
+}
+
+func TestParseROCmProcs(t *testing.T) {
+	// rocm-smi --showpids
+	const out = `
+
+============================ ROCm System Management Interface ============================
+===================================== KFD Processes ======================================
+KFD process information:
+PID	PROCESS NAME	GPU(s)	VRAM USED	SDMA USED	CU OCCUPANCY
+12345	python3     	1     	1073741824	0        	0
+23456	rccl-tests  	1     	2147483648	0        	UNKNOWN
+==========================================================================================
+=================================== End of ROCm SMI Log ===================================
+`
+	got := parseROCmProcs(out)
+	want := []model.GPUProc{
+		{PID: 23456, Command: "rccl-tests", MemUsedMB: 2048},
+		{PID: 12345, Command: "python3", MemUsedMB: 1024},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseROCmProcs = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package sampler
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// readerHealth logs reader failures without flooding the log every tick: the
+// first failure of a reader is a warning, repeats are debug, and recovery is
+// logged once at info. The latest error of each failing reader is kept for
+// Sample.Errors.
+type readerHealth struct {
+	mu      sync.Mutex
+	failing map[string]error
+}
+
+func (h *readerHealth) report(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failing == nil {
+		h.failing = make(map[string]error)
+	}
+	_, failing := h.failing[name]
+	switch {
+	case err != nil && !failing:
+		slog.Warn("reader failed", "reader", name, "err", err)
+	case err != nil:
+		slog.Debug("reader still failing", "reader", name, "err", err)
+	case failing:
+		delete(h.failing, name)
+		slog.Info("reader recovered", "reader", name)
+	}
+	if err != nil {
+		h.failing[name] = err
+	}
+}
+
+// errors returns "reader: error" for every reader currently failing, sorted
+// by reader name, or nil when all are healthy.
+func (h *readerHealth) errors() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []string
+	for name, err := range h.failing {
+		out = append(out, name+": "+err.Error())
+	}
+	slices.Sort(out)
+	return out
+}
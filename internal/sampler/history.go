@@ -0,0 +1,63 @@
+package sampler
+
+import (
+	"sync"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// history is a fixed-size ring of the most recent samples. Stream writes
+// it; History may be called from any goroutine.
+type history struct {
+	mu    sync.RWMutex
+	buf   []model.Sample
+	next  int // index the next sample is written to
+	count int
+}
+
+func newHistory(size int) *history {
+	if size <= 0 {
+		return nil
+	}
+	return &history{buf: make([]model.Sample, size)}
+}
+
+func (h *history) add(s model.Sample) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.buf[h.next] = s
+	h.next = (h.next + 1) % len(h.buf)
+	h.count = min(h.count+1, len(h.buf))
+	h.mu.Unlock()
+}
+
+// last returns up to n of the most recent samples, oldest first.
+func (h *history) last(n int) []model.Sample {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n = min(n, h.count)
+	out := make([]model.Sample, n)
+	for i := range out {
+		out[i] = h.buf[(h.next-n+i+len(h.buf))%len(h.buf)]
+	}
+	return out
+}
+
+// History returns the samples Stream has produced, oldest first, up to the
+// configured history size (config.Config.History). It is nil when history
+// is disabled. Samples are shallow copies: callers must not modify their
+// slices.
+func (s *Sampler) History() []model.Sample {
+	return s.history.last(s.cfg.History)
+}
+
+// Latest returns up to n of the most recent samples, oldest first; it is
+// History without copying the samples that aren't wanted.
+func (s *Sampler) Latest(n int) []model.Sample {
+	return s.history.last(n)
+}
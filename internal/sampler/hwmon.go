@@ -0,0 +1,76 @@
+package sampler
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// hwmonInputs maps each hwmon input glob to its sensor kind and the divisor
+// turning the raw value into model units (millidegrees and millivolts; fans
+// are already RPM).
+var hwmonInputs = []struct {
+	glob  string
+	kind  string
+	scale float64
+}{
+	{"temp*_input", model.SensorTemp, 1000},
+	{"fan*_input", model.SensorFan, 1},
+	{"in*_input", model.SensorVoltage, 1000},
+}
+
+// sensors reads every hwmon chip's temperature, fan and voltage inputs.
+// The thermal core registers a hwmon chip named after each zone's type, so
+// temperatures of chips named like a zone in temps are skipped as
+// duplicates. Inputs that fail to read (disconnected fan headers often
+// return EIO or ENODATA) are left out.
+func (s *Sampler) sensors(temps []model.Temp) []model.Sensor {
+	zones := make(map[string]bool, len(temps))
+	for _, t := range temps {
+		zones[t.Label] = true
+	}
+	dirs, _ := fs.Glob(s.FS, "sys/class/hwmon/hwmon*")
+	var out []model.Sensor
+	for _, dir := range dirs {
+		chip := readTrimmed(s.FS, path.Join(dir, "name"))
+		if chip == "" {
+			chip = path.Base(dir)
+		}
+		for _, in := range hwmonInputs {
+			if in.kind == model.SensorTemp && zones[chip] {
+				continue
+			}
+			paths, _ := fs.Glob(s.FS, path.Join(dir, in.glob))
+			for _, p := range paths {
+				raw := readTrimmed(s.FS, p)
+				if raw == "" {
+					continue
+				}
+				input := strings.TrimSuffix(path.Base(p), "_input")
+				label := readTrimmed(s.FS, path.Join(dir, input+"_label"))
+				if label == "" {
+					label = input
+				}
+				out = append(out, model.Sensor{
+					Chip:  chip,
+					Label: label,
+					Kind:  in.kind,
+					Value: parseFloat(raw) / in.scale,
+				})
+			}
+		}
+	}
+	return out
+}
+
+// readTrimmed returns the whitespace-trimmed contents of name, or "" if it
+// can't be read.
+func readTrimmed(fsys fs.FS, name string) string {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,70 @@
+package sampler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// intel_gpu_top never exits on its own, so it runs with a short sample period
+// under the same -gpu-timeout budget as nvidia-smi and its first JSON record
+// is taken from whatever it printed before being killed.
+const intelPeriod = "250" // ms
+
+// hasIntelGPU reports whether a DRM card is an Intel (vendor 0x8086) device.
+func hasIntelGPU() bool {
+	vendors, _ := filepath.Glob("/sys/class/drm/card[0-9]*/device/vendor")
+	for _, v := range vendors {
+		if b, err := os.ReadFile(v); err == nil && strings.TrimSpace(string(b)) == "0x8086" {
+			return true
+		}
+	}
+	return false
+}
+
+// queryIntel reads integrated Intel graphics via intel_gpu_top (needs root or
+// perf_event_paranoid <= 0). Util is the busiest engine, matching how the
+// other vendors report one headline figure. Intel GPUs share system RAM, so
+// the MemUsedMB/MemTotalMB fields are left zero.
+func (s *Sampler) queryIntel() []model.GPU {
+	out, err := runCmdPartial(s.Runner, s.cfg.GPUTimeout, "intel_gpu_top", "-J", "-s", intelPeriod)
+	rec, ok := firstIntelRecord(out)
+	if !ok {
+		if err == nil {
+			err = context.DeadlineExceeded // no complete record in time
+		}
+		s.gpuErr("intel_gpu_top", err)
+		return nil
+	}
+	s.health.report("intel_gpu_top", nil)
+	var util float64
+	for _, e := range rec.Engines {
+		util = max(util, e.Busy)
+	}
+	return []model.GPU{{Name: "Intel GPU", Util: util}}
+}
+
+type intelRecord struct {
+	Engines map[string]struct {
+		Busy float64 `json:"busy"`
+	} `json:"engines"`
+}
+
+// firstIntelRecord decodes the first object of intel_gpu_top's JSON array,
+// which is still open (and may be cut mid-record) when the tool is killed.
+func firstIntelRecord(out []byte) (intelRecord, bool) {
+	i := bytes.IndexByte(out, '{')
+	if i < 0 {
+		return intelRecord{}, false
+	}
+	var rec intelRecord
+	if err := json.NewDecoder(bytes.NewReader(out[i:])).Decode(&rec); err != nil || rec.Engines == nil {
+		return intelRecord{}, false
+	}
+	return rec, true
+}
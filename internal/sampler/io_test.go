@@ -0,0 +1,39 @@
+package sampler
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// TestCounterReset feeds consecutive counter snapshots, some of which went
+// backwards (a VPN reconnect, a re-created interface, a 32-bit wrap). Those
+// ticks must read as no traffic, not as an underflowed 2^64-byte burst.
+func TestCounterReset(t *testing.T) {
+	tests := []struct {
+		name           string
+		prev, cur      net.IOCountersStat
+		wantRx, wantTx float64 // Mbps over 2s
+	}{
+		{"steady", net.IOCountersStat{BytesRecv: 1_000_000, BytesSent: 500_000}, net.IOCountersStat{BytesRecv: 3_500_000, BytesSent: 1_000_000}, 10, 2},
+		{"idle", net.IOCountersStat{BytesRecv: 1_000_000, BytesSent: 500_000}, net.IOCountersStat{BytesRecv: 1_000_000, BytesSent: 500_000}, 0, 0},
+		{"reset", net.IOCountersStat{BytesRecv: 9_000_000_000, BytesSent: 500_000}, net.IOCountersStat{BytesRecv: 4096, BytesSent: 750_000}, 0, 1},
+		{"32-bit wrap", net.IOCountersStat{BytesRecv: 1<<32 - 100, BytesSent: 1<<32 - 100}, net.IOCountersStat{BytesRecv: 200, BytesSent: 200}, 0, 0},
+	}
+	for _, tt := range tests {
+		rx := mbps(tt.cur.BytesRecv, tt.prev.BytesRecv, 2)
+		tx := mbps(tt.cur.BytesSent, tt.prev.BytesSent, 2)
+		if rx != tt.wantRx || tx != tt.wantTx {
+			t.Errorf("%s: rx %v, tx %v Mbps; want %v, %v", tt.name, rx, tx, tt.wantRx, tt.wantTx)
+		}
+	}
+
+	// Disk byte and IOPS deltas use delta.
+	for _, tt := range []struct{ cur, prev, want uint64 }{
+		{10, 4, 6}, {4, 4, 0}, {4, 10, 0}, {0, 1<<64 - 1, 0},
+	} {
+		if got := delta(tt.cur, tt.prev); got != tt.want {
+			t.Errorf("delta(%d, %d) = %d, want %d", tt.cur, tt.prev, got, tt.want)
+		}
+	}
+}
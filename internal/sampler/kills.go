@@ -0,0 +1,174 @@
+package sampler
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// killPollInterval is how often killLoop re-reads the journal (-kills).
+// Kills are rare and journalctl is comparatively expensive, so this runs far
+// slower than the main tick.
+const killPollInterval = 30 * time.Second
+
+// killCmdTimeout bounds one journalctl query.
+const killCmdTimeout = 3 * time.Second
+
+// killEventLimit is how many journal lines are scanned per source.
+const killEventLimit = 50
+
+// Kill event sources, reported in model.KillEvent.Source.
+const (
+	killSourceEarlyOOM = "earlyoom"
+	killSourceOOMD     = "oomd"
+	killSourceKernel   = "kernel"
+)
+
+// killSource is one journal query plus the pattern that marks a kill in it.
+// pattern may capture "pid" and "comm" by name; oomd kills whole cgroups, so
+// its "comm" is the unit path and PID stays 0.
+type killSource struct {
+	name    string
+	args    []string // journalctl selector, e.g. -u earlyoom or -k
+	pattern *regexp.Regexp
+}
+
+var (
+	// sending SIGTERM to process 4242 uid 1000 "chrome": badness 900, VmRSS 2000 MiB
+	// Killing process 4242 (chrome)
+	earlyoomKill = regexp.MustCompile(`(?i)(?:kill(?:ing)? process|to process) (?P<pid>\d+)(?: uid \d+)? [("](?P<comm>[^)"]+)[)"]`)
+	// Killed /user.slice/user-1000.slice/app.scope due to memory pressure for ...
+	oomdKill = regexp.MustCompile(`Killed (?P<comm>\S+) due to memory (?:pressure|used)`)
+	// Out of memory: Killed process 4242 (chrome) total-vm:...
+	// Memory cgroup out of memory: Killed process 4242 (chrome) ...
+	kernelKill = regexp.MustCompile(`[Oo]ut of memory: Killed process (?P<pid>\d+) \((?P<comm>[^)]+)\)`)
+)
+
+// killSources lists the journals GetKillEvents reads. Unit names come from
+// the config (-earlyoom-unit, -oomd-unit); an empty name disables a source.
+func killSources(cfg config.Config) []killSource {
+	var srcs []killSource
+	if cfg.EarlyOOMUnit != "" {
+		srcs = append(srcs, killSource{killSourceEarlyOOM, []string{"-u", cfg.EarlyOOMUnit}, earlyoomKill})
+	}
+	if cfg.OOMDUnit != "" {
+		srcs = append(srcs, killSource{killSourceOOMD, []string{"-u", cfg.OOMDUnit}, oomdKill})
+	}
+	return append(srcs, killSource{killSourceKernel, []string{"-k"}, kernelKill})
+}
+
+// GetKillEvents returns recent OOM kills by earlyoom, systemd-oomd and the
+// kernel, newest first. An error is returned only if every source failed
+// (e.g. no journalctl, or no permission to read the journal).
+func GetKillEvents(cfg config.Config) ([]model.KillEvent, error) {
+	return killEvents(ExecRunner{}, cfg)
+}
+
+// killEvents is GetKillEvents with journalctl run through r.
+func killEvents(r CommandRunner, cfg config.Config) ([]model.KillEvent, error) {
+	now := time.Now()
+	var events []model.KillEvent
+	var errs []error
+	srcs := killSources(cfg)
+	for _, src := range srcs {
+		args := append(append([]string{}, src.args...), "-n", strconv.Itoa(killEventLimit), "--no-pager", "-o", "short-iso")
+		out, err := runCmd(r, killCmdTimeout, "journalctl", args...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		events = append(events, parseKillEvents(out, src, now)...)
+	}
+	if len(errs) == len(srcs) {
+		return nil, errors.Join(errs...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+	return events, nil
+}
+
+func (s *Sampler) killLoop(ctx context.Context) {
+	s.updateKills()
+	ticker := time.NewTicker(killPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.updateKills()
+		}
+	}
+}
+
+// updateKills refreshes the cached kill events. On failure the previous
+// events are kept; the section age shows they are no longer current.
+func (s *Sampler) updateKills() {
+	events, err := killEvents(s.Runner, s.cfg)
+	s.health.report("kills", err)
+	if err != nil {
+		return
+	}
+	s.killMu.Lock()
+	s.killData = events
+	s.killAt = time.Now()
+	s.killMu.Unlock()
+}
+
+// parseKillEvents extracts src's kill events from journalctl output. now
+// supplies the year for legacy lines without one.
+func parseKillEvents(out string, src killSource, now time.Time) []model.KillEvent {
+	var events []model.KillEvent
+	pidIdx, commIdx := src.pattern.SubexpIndex("pid"), src.pattern.SubexpIndex("comm")
+	for _, line := range strings.Split(out, "\n") {
+		m := src.pattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ev := model.KillEvent{
+			Time:    parseJournalTime(line, now),
+			Source:  src.name,
+			Message: strings.TrimSpace(line),
+		}
+		if pidIdx >= 0 {
+			ev.PID, _ = strconv.Atoi(m[pidIdx])
+		}
+		if commIdx >= 0 {
+			ev.Command = m[commIdx]
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// parseJournalTime reads the timestamp journalctl prefixes each line with.
+// -o short-iso gives "2006-01-02T15:04:05-0700" (or "+07:00" on newer
+// systemd). The legacy short format ("Jan _2 15:04:05") has no year, so the
+// current one is assumed, stepping back a year for dates that would lie in
+// the future (a December kill read in January). Zero if neither parses.
+func parseJournalTime(line string, now time.Time) time.Time {
+	stamp, _, _ := strings.Cut(line, " ")
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339} {
+		if t, err := time.Parse(layout, stamp); err == nil {
+			return t
+		}
+	}
+	if len(line) < 15 {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("Jan _2 15:04:05", line[:15], now.Location())
+	if err != nil {
+		return time.Time{}
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
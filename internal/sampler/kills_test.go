@@ -0,0 +1,145 @@
+package sampler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+)
+
+// Captured journalctl -n 50 --no-pager -o short-iso output, one per source.
+const (
+	earlyoomJournal = `2024-03-04T10:15:02+0100 host earlyoom[812]: mem avail:   312 of 15842 MiB ( 1.97%), swap free:    0 of 2047 MiB ( 0.00%)
+2024-03-04T10:15:02+0100 host earlyoom[812]: low memory! at or below SIGTERM limits: mem 10.00%, swap 10.00%
+2024-03-04T10:15:02+0100 host earlyoom[812]: sending SIGTERM to process 4242 uid 1000 "chrome": badness 900, VmRSS 2000 MiB
+2024-03-04T10:15:03+0100 host earlyoom[812]: process exited after 0.2 seconds
+`
+	oomdJournal = `2024-03-04T11:20:41+01:00 host systemd-oomd[640]: Killed /user.slice/user-1000.slice/user@1000.service/app.slice/app-firefox.scope due to memory pressure for /user.slice/user-1000.slice/user@1000.service being 71.42% > 50.00% for > 20s with reclaim activity
+`
+	kernelJournal = `2024-03-04T09:01:13+0100 host kernel: oom-kill:constraint=CONSTRAINT_NONE,nodemask=(null),cpuset=/,mems_allowed=0,global_oom,task_memcg=/user.slice,task=java,pid=5150,uid=1000
+2024-03-04T09:01:13+0100 host kernel: Out of memory: Killed process 5150 (java) total-vm:12404244kB, anon-rss:8122340kB, file-rss:0kB, shmem-rss:0kB, UID:1000 pgtables:17224kB oom_score_adj:0
+2024-03-04T09:05:00+0100 host kernel: Memory cgroup out of memory: Killed process 6001 (node) total-vm:1000kB, anon-rss:900kB
+`
+)
+
+func TestKillEvents(t *testing.T) {
+	journals := map[string]string{"earlyoom.service": earlyoomJournal, "systemd-oomd.service": oomdJournal, "-k": kernelJournal}
+	r := runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+		if name != "journalctl" {
+			t.Errorf("ran %s, want journalctl", name)
+		}
+		// args start with the source selector: -u <unit> or -k.
+		key := args[0]
+		if key == "-u" {
+			key = args[1]
+		}
+		return journals[key], nil
+	})
+	cfg := config.Default()
+	cfg.EarlyOOMUnit = "earlyoom.service"
+	cfg.OOMDUnit = "systemd-oomd.service"
+
+	events, err := killEvents(r, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type ev struct {
+		source, comm string
+		pid          int
+		time         string
+	}
+	var got []ev
+	for _, e := range events {
+		got = append(got, ev{e.Source, e.Command, e.PID, e.Time.UTC().Format(time.RFC3339)})
+		if !strings.Contains(e.Message, e.Command) || strings.HasSuffix(e.Message, "\n") {
+			t.Errorf("message %q is not the trimmed kill line", e.Message)
+		}
+	}
+	want := []ev{ // newest first
+		{killSourceOOMD, "/user.slice/user-1000.slice/user@1000.service/app.slice/app-firefox.scope", 0, "2024-03-04T10:20:41Z"},
+		{killSourceEarlyOOM, "chrome", 4242, "2024-03-04T09:15:02Z"},
+		{killSourceKernel, "node", 6001, "2024-03-04T08:05:00Z"},
+		{killSourceKernel, "java", 5150, "2024-03-04T08:01:13Z"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestKillEventsErrors(t *testing.T) {
+	cfg := config.Default()
+	cfg.EarlyOOMUnit = "earlyoom.service"
+	denied := errors.New("exit status 1")
+
+	// One readable source is enough.
+	r := runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+		if args[0] == "-k" {
+			return kernelJournal, nil
+		}
+		return "", denied
+	})
+	if events, err := killEvents(r, cfg); err != nil || len(events) != 2 {
+		t.Errorf("one source failing: %d events, err %v; want 2, nil", len(events), err)
+	}
+
+	r = runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) { return "", denied })
+	if _, err := killEvents(r, cfg); !errors.Is(err, denied) {
+		t.Errorf("every source failing: err %v, want %v", err, denied)
+	}
+}
+
+func TestParseJournalTime(t *testing.T) {
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		{"2024-01-05T10:00:00+0100 host kernel: x", time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"2024-01-05T10:00:00+01:00 host kernel: x", time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"Jan  5 10:00:00 host kernel: x", time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)},
+		// A December line read in January is from last year.
+		{"Dec 31 23:59:59 host kernel: x", time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{"-- No entries --", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseJournalTime(tt.line, now); !got.Equal(tt.want) {
+			t.Errorf("parseJournalTime(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestParseKillEventsMixed feeds earlyoom lines in both journal formats, as
+// when the journal is read on a host whose journalctl predates short-iso.
+func TestParseKillEventsMixed(t *testing.T) {
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+	out := `2024-01-05T10:15:02+0100 host earlyoom[812]: sending SIGTERM to process 4242 uid 1000 "chrome": badness 900, VmRSS 2000 MiB
+2024-01-05T10:15:02+01:00 host earlyoom[812]: Killing process 4300 (firefox)
+Jan  5 11:30:00 host earlyoom[812]: sending SIGKILL to process 4400 uid 1000 "java": badness 950, VmRSS 6000 MiB
+Dec 31 23:59:59 host earlyoom[812]: Killing process 4500 (node)
+`
+	src := killSources(config.Config{EarlyOOMUnit: "earlyoom"})[0]
+	want := map[int]time.Time{
+		4242: time.Date(2024, 1, 5, 9, 15, 2, 0, time.UTC),
+		4300: time.Date(2024, 1, 5, 9, 15, 2, 0, time.UTC),
+		4400: time.Date(2024, 1, 5, 11, 30, 0, 0, time.UTC),
+		4500: time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC),
+	}
+	events := parseKillEvents(out, src, now)
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for _, e := range events {
+		if w, ok := want[e.PID]; !ok || !e.Time.Equal(w) {
+			t.Errorf("PID %d (%s): time %v, want %v", e.PID, e.Command, e.Time, w)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package sampler
+
+import (
+	"bufio"
+	"encoding/xml"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// nvidiaMIG lists the MIG instances of the GPUs in MIG mode: memory from
+// nvidia-smi -q -x, profile names and UUIDs from nvidia-smi -L. Like the
+// process lists, failures are logged, not reported. Hosts without MIG run
+// neither command.
+func (s *Sampler) nvidiaMIG(gpus []model.GPU) {
+	if !slices.ContainsFunc(gpus, func(g model.GPU) bool { return g.MIGEnabled }) {
+		return
+	}
+	out, err := runCmd(s.Runner, s.cfg.GPUTimeout, "nvidia-smi", "-q", "-x")
+	if err != nil {
+		slog.Debug("nvidia-smi -q -x failed", "err", err)
+		return
+	}
+	mig, err := parseMIGDevices(out)
+	if err != nil {
+		slog.Debug("nvidia-smi -q -x: bad XML", "err", err)
+		return
+	}
+	var names map[int]map[int]model.MIGInstance
+	if out, err := runCmd(s.Runner, s.cfg.GPUTimeout, "nvidia-smi", "-L"); err == nil {
+		names = parseMIGList(out)
+	} else {
+		slog.Debug("nvidia-smi -L failed", "err", err)
+	}
+	for i := range gpus {
+		g := &gpus[i]
+		if !g.MIGEnabled {
+			continue
+		}
+		g.MIG = mig[normBusID(g.BusID)]
+		for j := range g.MIG {
+			if n, ok := names[g.Index][g.MIG[j].Index]; ok {
+				g.MIG[j].Profile, g.MIG[j].UUID = n.Profile, n.UUID
+			}
+		}
+	}
+}
+
+// smiLog is the part of nvidia-smi -q -x output parseMIGDevices needs.
+type smiLog struct {
+	GPUs []struct {
+		ID  string `xml:"id,attr"` // PCI bus id
+		MIG []struct {
+			Index           int `xml:"index"`
+			GPUInstance     int `xml:"gpu_instance_id"`
+			ComputeInstance int `xml:"compute_instance_id"`
+			Memory          struct {
+				Total string `xml:"total"`
+				Used  string `xml:"used"`
+			} `xml:"fb_memory_usage"`
+		} `xml:"mig_devices>mig_device"`
+	} `xml:"gpu"`
+}
+
+// parseMIGDevices returns the MIG devices in nvidia-smi -q -x output, keyed
+// by normalized bus id. Memory is given as e.g. "9984 MiB".
+func parseMIGDevices(out string) (map[string][]model.MIGInstance, error) {
+	var doc smiLog
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		return nil, err
+	}
+	mig := make(map[string][]model.MIGInstance)
+	for _, g := range doc.GPUs {
+		for _, d := range g.MIG {
+			bus := normBusID(g.ID)
+			mig[bus] = append(mig[bus], model.MIGInstance{
+				Index:           d.Index,
+				GPUInstance:     d.GPUInstance,
+				ComputeInstance: d.ComputeInstance,
+				MemUsedMB:       gpuField(strings.TrimSuffix(d.Memory.Used, "MiB")),
+				MemTotalMB:      gpuField(strings.TrimSuffix(d.Memory.Total, "MiB")),
+			})
+		}
+	}
+	return mig, nil
+}
+
+// parseMIGList returns the profile and UUID of each MIG device listed by
+// nvidia-smi -L, by GPU index and MIG device index:
+//
+//	GPU 0: NVIDIA A100-SXM4-80GB (UUID: GPU-5d5ba0d6-...)
+//	  MIG 3g.40gb     Device  0: (UUID: MIG-c6d4f1ef-...)
+func parseMIGList(out string) map[int]map[int]model.MIGInstance {
+	list := make(map[int]map[int]model.MIGInstance)
+	gpu := -1
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		switch {
+		case len(f) >= 2 && f[0] == "GPU":
+			n, err := strconv.Atoi(strings.TrimSuffix(f[1], ":"))
+			if err != nil {
+				gpu = -1
+				continue
+			}
+			gpu = n
+			list[gpu] = make(map[int]model.MIGInstance)
+		case len(f) >= 4 && f[0] == "MIG" && f[2] == "Device" && gpu >= 0:
+			idx, err := strconv.Atoi(strings.TrimSuffix(f[3], ":"))
+			if err != nil {
+				continue
+			}
+			var uuid string
+			if _, rest, ok := strings.Cut(sc.Text(), "UUID: "); ok {
+				uuid, _, _ = strings.Cut(rest, ")")
+			}
+			list[gpu][idx] = model.MIGInstance{Index: idx, Profile: f[1], UUID: uuid}
+		}
+	}
+	return list
+}
@@ -0,0 +1,69 @@
+package sampler
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// numaNodes reads per-node memory from
+// /sys/devices/system/node/node*/meminfo. It returns nil unless there are
+// at least two nodes, since one node only repeats the global figures.
+func (s *Sampler) numaNodes() []model.NUMANode {
+	paths, _ := fs.Glob(s.FS, "sys/devices/system/node/node*/meminfo")
+	if len(paths) < 2 {
+		return nil
+	}
+	nodes := make([]model.NUMANode, 0, len(paths))
+	for _, p := range paths {
+		id, err := strconv.Atoi(strings.TrimPrefix(path.Base(path.Dir(p)), "node"))
+		if err != nil {
+			continue
+		}
+		b, err := fs.ReadFile(s.FS, p)
+		if err != nil {
+			continue
+		}
+		n := parseNodeMeminfo(b)
+		n.ID = id
+		nodes = append(nodes, n)
+	}
+	if len(nodes) < 2 {
+		return nil
+	}
+	// Glob order puts node10 before node2.
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// parseNodeMeminfo parses lines like "Node 0 MemTotal:  16384000 kB".
+func parseNodeMeminfo(b []byte) model.NUMANode {
+	var n model.NUMANode
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 || f[0] != "Node" {
+			continue
+		}
+		kb, err := strconv.ParseUint(f[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch f[2] {
+		case "MemTotal:":
+			n.TotalBytes = kb * 1024
+		case "MemFree:":
+			n.FreeBytes = kb * 1024
+		}
+	}
+	if n.TotalBytes > n.FreeBytes {
+		n.UsedBytes = n.TotalBytes - n.FreeBytes
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package sampler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// nodeMeminfo is a captured /sys/devices/system/node/node1/meminfo, trimmed.
+const nodeMeminfo = `Node 1 MemTotal:       65843036 kB
+Node 1 MemFree:        12080132 kB
+Node 1 MemUsed:        53762904 kB
+Node 1 SwapCached:            0 kB
+Node 1 Active:         30123456 kB
+Node 1 HugePages_Total:     0
+Node 1 HugePages_Free:      0
+`
+
+func TestNUMANodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []model.NUMANode
+	}{
+		{
+			name: "two nodes",
+			files: map[string]string{
+				"sys/devices/system/node/node0/meminfo": "Node 0 MemTotal:       16384000 kB\nNode 0 MemFree:         4096000 kB\n",
+				"sys/devices/system/node/node1/meminfo": nodeMeminfo,
+			},
+			want: []model.NUMANode{
+				{ID: 0, TotalBytes: 16384000 << 10, FreeBytes: 4096000 << 10, UsedBytes: 12288000 << 10},
+				{ID: 1, TotalBytes: 65843036 << 10, FreeBytes: 12080132 << 10, UsedBytes: 53762904 << 10},
+			},
+		},
+		{
+			name: "numeric order",
+			files: map[string]string{
+				"sys/devices/system/node/node10/meminfo": "Node 10 MemTotal: 2048 kB\nNode 10 MemFree: 1024 kB\n",
+				"sys/devices/system/node/node2/meminfo":  "Node 2 MemTotal: 4096 kB\nNode 2 MemFree: 1024 kB\n",
+			},
+			want: []model.NUMANode{
+				{ID: 2, TotalBytes: 4096 << 10, FreeBytes: 1024 << 10, UsedBytes: 3072 << 10},
+				{ID: 10, TotalBytes: 2048 << 10, FreeBytes: 1024 << 10, UsedBytes: 1024 << 10},
+			},
+		},
+		{
+			// Memory-less nodes (CPUs only) report zeros.
+			name: "memory-less node",
+			files: map[string]string{
+				"sys/devices/system/node/node0/meminfo": "Node 0 MemTotal: 4096 kB\nNode 0 MemFree: 1024 kB\n",
+				"sys/devices/system/node/node1/meminfo": "Node 1 MemTotal: 0 kB\nNode 1 MemFree: 0 kB\n",
+			},
+			want: []model.NUMANode{
+				{ID: 0, TotalBytes: 4096 << 10, FreeBytes: 1024 << 10, UsedBytes: 3072 << 10},
+				{ID: 1},
+			},
+		},
+		{
+			name:  "single node",
+			files: map[string]string{"sys/devices/system/node/node0/meminfo": "Node 0 MemTotal: 4096 kB\nNode 0 MemFree: 1024 kB\n"},
+		},
+		{
+			name:  "no NUMA sysfs",
+			files: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fixtureSampler(t, config.Config{}, nil, mapFS(tt.files)).numaNodes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("numaNodes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package sampler
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Unsupported reports why this host can only be partly sampled: a non-Linux
+// OS, or Linux without a readable /proc (as in some minimal containers). Most
+// readers then fail or read zeros, so callers should warn up front. It
+// returns nil when /proc is available.
+func Unsupported() error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("%s is not supported: most metrics come from /proc and /sys and will be missing or zero", runtime.GOOS)
+	}
+	if _, err := os.Stat("/proc/stat"); err != nil {
+		return fmt.Errorf("/proc is not available, most metrics will be missing or zero: %w", err)
+	}
+	return nil
+}
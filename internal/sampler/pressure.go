@@ -0,0 +1,62 @@
+package sampler
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// readPressure reads /proc/pressure/{cpu,memory,io}. Kernels without PSI
+// (pre-4.20, or psi=0) have no such files and get a zero, unsupported value.
+func readPressure() model.Pressure {
+	var p model.Pressure
+	cpu, okCPU := readPSIFile("/proc/pressure/cpu")
+	memory, okMem := readPSIFile("/proc/pressure/memory")
+	io, okIO := readPSIFile("/proc/pressure/io")
+	if !okCPU && !okMem && !okIO {
+		return p
+	}
+	p.Supported = true
+	p.CPU, p.Memory, p.IO = cpu, memory, io
+	return p
+}
+
+// readPSIFile parses lines like
+//
+//	some avg10=4.63 avg60=4.72 avg300=4.78 total=90369213
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPSIFile(path string) (model.PressureStat, bool) {
+	var st model.PressureStat
+	f, err := os.Open(path)
+	if err != nil {
+		return st, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		avg := make(map[string]float64, 3)
+		for _, kv := range fields[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				avg[k] = n
+			}
+		}
+		switch fields[0] {
+		case "some":
+			st.Some10, st.Some60 = avg["avg10"], avg["avg60"]
+		case "full":
+			st.Full10 = avg["avg10"]
+		}
+	}
+	return st, true
+}
@@ -0,0 +1,66 @@
+package sampler
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// kernelCounters are the cumulative context switches, interrupts and forks
+// since boot from /proc/stat.
+type kernelCounters struct {
+	ctxt, intr, forks uint64
+	ok                bool
+}
+
+// readKernelCounters reads ctxt, intr (the total, first field) and processes
+// from /proc/stat.
+func readKernelCounters() kernelCounters {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return kernelCounters{}
+	}
+	defer f.Close()
+	return parseKernelCounters(bufio.NewScanner(f))
+}
+
+func parseKernelCounters(sc *bufio.Scanner) kernelCounters {
+	var c kernelCounters
+	var seen int
+	// The intr line lists every IRQ and can be very long on big hosts.
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() && seen < 3 {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "ctxt":
+			c.ctxt = v
+			seen++
+		case "intr":
+			c.intr = v
+			seen++
+		case "processes":
+			c.forks = v
+			seen++
+		}
+	}
+	c.ok = seen == 3
+	return c
+}
+
+// kernelRates turns two snapshots into per-second rates, with the same
+// reset/missing-snapshot handling as swapRates.
+func kernelRates(cur, prev kernelCounters, dur float64) (ctxt, intr, forks float64) {
+	if !cur.ok || !prev.ok || dur <= 0 {
+		return 0, 0, 0
+	}
+	rate := func(c, p uint64) float64 { return float64(delta(c, p)) / dur }
+	return rate(cur.ctxt, prev.ctxt), rate(cur.intr, prev.intr), rate(cur.forks, prev.forks)
+}
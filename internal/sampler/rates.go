@@ -0,0 +1,22 @@
+package sampler
+
+import "github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+
+// rates returns the per-second change from prev to cur, or nil when there is
+// no previous sample (or no time has passed) to diff against. Unlike delta,
+// these are gauges and may go down.
+func rates(prev *model.Sample, cur model.Sample) *model.Rates {
+	if prev == nil {
+		return nil
+	}
+	dt := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+	if dt <= 0 {
+		return nil
+	}
+	change := func(c, p uint64) float64 { return (float64(c) - float64(p)) / dt }
+	return &model.Rates{
+		MemUsedBytesPerSec:  change(cur.Memory.UsedBytes, prev.Memory.UsedBytes),
+		SwapUsedBytesPerSec: change(cur.Memory.SwapUsed, prev.Memory.SwapUsed),
+		OpenFDsPerSec:       change(cur.OpenFDs, prev.OpenFDs),
+	}
+}
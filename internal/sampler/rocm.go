@@ -0,0 +1,151 @@
+package sampler
+
+import (
+	"bufio"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// minROCmTimeout floors -gpu-timeout for rocm-smi: it is a Python script and
+// its startup alone takes a few hundred milliseconds.
+const minROCmTimeout = time.Second
+
+// hasTool reports whether name is on PATH. GPU tools are probed once at
+// startup instead of on every GPU tick.
+func hasTool(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// queryROCm reads AMD GPUs via rocm-smi, preferring --json and falling back
+// to parsing the plain "GPU[0] : key: value" output of older releases.
+func (s *Sampler) queryROCm() []model.GPU {
+	args := []string{"--showuse", "--showmeminfo", "vram", "--showtemp", "--showproductname"}
+	if !s.rocmNoJSON {
+		out, err := runCmd(s.Runner, max(s.cfg.GPUTimeout, minROCmTimeout), "rocm-smi", append(args, "--json")...)
+		if err == nil {
+			var cards map[string]map[string]any
+			if jerr := json.Unmarshal([]byte(jsonStart(out)), &cards); jerr == nil {
+				s.health.report("rocm-smi", nil)
+				gpus := rocmGPUs(stringify(cards))
+				s.rocmProcs(gpus)
+				return gpus
+			}
+		}
+		if out == "" {
+			s.gpuErr("rocm-smi", err)
+			return nil
+		}
+		s.rocmNoJSON = true
+	}
+	out, err := runCmd(s.Runner, max(s.cfg.GPUTimeout, minROCmTimeout), "rocm-smi", args...)
+	if err != nil && out == "" {
+		s.gpuErr("rocm-smi", err)
+		return nil
+	}
+	s.health.report("rocm-smi", nil)
+	gpus := rocmGPUs(parseROCmText(out))
+	s.rocmProcs(gpus)
+	return gpus
+}
+
+// jsonStart skips any warnings rocm-smi prints before the JSON document.
+func jsonStart(out string) string {
+	if i := strings.IndexByte(out, '{'); i > 0 {
+		return out[i:]
+	}
+	return out
+}
+
+func stringify(cards map[string]map[string]any) map[string]map[string]string {
+	res := make(map[string]map[string]string, len(cards))
+	for card, kv := range cards {
+		m := make(map[string]string, len(kv))
+		for k, v := range kv {
+			switch v := v.(type) {
+			case string:
+				m[k] = v
+			case float64:
+				m[k] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		res[card] = m
+	}
+	return res
+}
+
+var rocmLine = regexp.MustCompile(`^GPU\[(\d+)\]\s*:\s*(.+?):\s*(.*)$`)
+
+// parseROCmText turns "GPU[0]		: GPU use (%): 12" lines into the same
+// card -> key -> value shape as the JSON output.
+func parseROCmText(out string) map[string]map[string]string {
+	cards := make(map[string]map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		m := rocmLine.FindStringSubmatch(strings.TrimSpace(sc.Text()))
+		if m == nil {
+			continue
+		}
+		card := "card" + m[1]
+		if cards[card] == nil {
+			cards[card] = make(map[string]string)
+		}
+		cards[card][strings.TrimSpace(m[2])] = strings.TrimSpace(m[3])
+	}
+	return cards
+}
+
+// rocmGPUs maps rocm-smi fields onto model.GPU. Key names vary between
+// releases (and temperature sensors between cards), so lookups go by prefix.
+func rocmGPUs(cards map[string]map[string]string) []model.GPU {
+	type rocmCard struct {
+		name  string
+		index int
+	}
+	var list []rocmCard
+	for card := range cards {
+		idx, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil || !strings.HasPrefix(card, "card") {
+			continue
+		}
+		list = append(list, rocmCard{card, idx})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].index < list[j].index })
+
+	var gpus []model.GPU
+	for _, c := range list {
+		card := c.name
+		kv := cards[card]
+		find := func(prefixes ...string) string {
+			for _, p := range prefixes {
+				for k, v := range kv {
+					if strings.HasPrefix(k, p) {
+						return v
+					}
+				}
+			}
+			return ""
+		}
+		name := find("Card series", "Card SKU", "Card model")
+		if name == "" {
+			name = "AMD " + card
+		}
+		const mb = 1024 * 1024
+		gpus = append(gpus, model.GPU{
+			Index:      c.index,
+			Name:       name,
+			Util:       parseFloat(find("GPU use (%)")),
+			MemUsedMB:  parseFloat(find("VRAM Total Used Memory (B)")) / mb,
+			MemTotalMB: parseFloat(find("VRAM Total Memory (B)")) / mb,
+			TempC:      parseFloat(find("Temperature (Sensor edge)", "Temperature (Sensor junction)", "Temperature")),
+		})
+	}
+	return gpus
+}
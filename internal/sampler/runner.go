@@ -0,0 +1,57 @@
+package sampler
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"time"
+)
+
+// CommandRunner runs an external tool (nvidia-smi, rocm-smi, intel_gpu_top,
+// journalctl) and returns its combined stdout and stderr. When ctx ends first
+// it returns ctx.Err() together with whatever the tool printed until then,
+// so tools that stream until killed can still be read.
+type CommandRunner interface {
+	Run(ctx context.Context, name string, args ...string) (string, error)
+}
+
+// ExecRunner is the CommandRunner that executes real binaries.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// A killed tool's children may hold the pipes open; don't wait on them.
+	cmd.WaitDelay = 100 * time.Millisecond
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return out.String(), ctx.Err()
+	}
+	return out.String(), err
+}
+
+// runCmd runs a tool that exits on its own. A run cut off by the timeout
+// returns no output, only the deadline error.
+func runCmd(r CommandRunner, timeout time.Duration, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := r.Run(ctx, name, args...)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", ctx.Err()
+	}
+	return out, err
+}
+
+// runCmdPartial runs a tool that streams until killed and returns the output
+// it produced before the timeout, so a hung or long-running tool is bounded.
+func runCmdPartial(r CommandRunner, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := r.Run(ctx, name, args...)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = nil
+	}
+	return []byte(out), err
+}
@@ -0,0 +1,149 @@
+package sampler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+)
+
+// TestGPUTimeout checks that nvidia-smi runs under -gpu-timeout and that a
+// hung one is cut off there.
+func TestGPUTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.GPUTimeout = 3 * time.Second
+	s := NewWithConfig(cfg)
+	var budget time.Duration
+	s.Runner = runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+		if deadline, ok := ctx.Deadline(); ok && name == "nvidia-smi" && budget == 0 {
+			budget = time.Until(deadline)
+		}
+		return fakeNvidia(ctx, name, args...)
+	})
+	if gpus := s.queryNvidia(); len(gpus) != 1 {
+		t.Fatalf("got %d GPUs, want 1", len(gpus))
+	}
+	if budget <= 2*time.Second || budget > 3*time.Second {
+		t.Errorf("nvidia-smi ran with %s left, want about 3s", budget)
+	}
+
+	s.cfg.GPUTimeout = 20 * time.Millisecond
+	s.Runner = runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	start := time.Now()
+	if gpus := s.queryNvidia(); gpus != nil {
+		t.Errorf("hung nvidia-smi: got %v, want no GPUs", gpus)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("hung nvidia-smi held the query for %s", d)
+	}
+}
+
+// TestGPUInterval checks that gpuLoop polls every -gpu-interval.
+func TestGPUInterval(t *testing.T) {
+	cfg := config.Default()
+	cfg.GPUInterval = 20 * time.Millisecond
+	s := NewWithConfig(cfg)
+	s.hasNvidiaSMI = true
+	var queries atomic.Int32
+	s.Runner = runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+		if name == "nvidia-smi" && len(args) > 0 && strings.HasPrefix(args[0], "--query-gpu") {
+			queries.Add(1)
+		}
+		return fakeNvidia(ctx, name, args...)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	s.gpuLoop(ctx)
+	// 15 ticks at most; allow for a slow, loaded test machine.
+	if n := queries.Load(); n < 4 {
+		t.Errorf("%d GPU polls in 300ms at a 20ms interval", n)
+	}
+}
+
+// TestGPUFieldFallback checks that only an invalid-field error drops
+// optional nvidia-smi fields, and only the group it names.
+func TestGPUFieldFallback(t *testing.T) {
+	values := map[string]string{
+		"index": "0", "uuid": "GPU-a", "name": "NVIDIA T4", "utilization.gpu": "40",
+		"memory.used": "1024", "memory.total": "15360", "temperature.gpu": "50",
+		"pci.bus_id": "00000000:01:00.0", "utilization.memory": "10",
+		"utilization.encoder": "0", "utilization.decoder": "0", "mig.mode.current": "Disabled",
+	}
+	tests := []struct {
+		name              string
+		fail              func(fields []string) (string, bool)
+		gpus              int
+		noEngines, noMIG  bool
+		fullQueryNextPoll bool
+	}{
+		{
+			name: "old driver without MIG",
+			fail: func(fields []string) (string, bool) {
+				return `Field "mig.mode.current" is not a valid field to query.`, slices.Contains(fields, "mig.mode.current")
+			},
+			gpus: 1, noMIG: true,
+		},
+		{
+			name: "old driver without engine fields",
+			fail: func(fields []string) (string, bool) {
+				return `Field "utilization.encoder" is not a valid field to query.`, slices.Contains(fields, "utilization.encoder")
+			},
+			gpus: 1, noEngines: true,
+		},
+		{
+			name: "GPU lost",
+			fail: func([]string) (string, bool) {
+				return "Unable to determine the device handle for GPU 0000:01:00.0: GPU is lost.  Reboot the system to recover this GPU", true
+			},
+			fullQueryNextPoll: true,
+		},
+		{
+			name: "driver not loaded",
+			fail: func([]string) (string, bool) {
+				return "NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver.", true
+			},
+			fullQueryNextPoll: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWithConfig(config.Default())
+			var last []string
+			s.Runner = runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+				query, ok := strings.CutPrefix(args[0], "--query-gpu=")
+				if name != "nvidia-smi" || !ok {
+					return "", nil
+				}
+				last = strings.Split(query, ",")
+				if out, failed := tt.fail(last); failed {
+					return out, errors.New("exit status 2")
+				}
+				row := make([]string, len(last))
+				for i, f := range last {
+					row[i] = values[f]
+				}
+				return strings.Join(row, ", ") + "\n", nil
+			})
+			if gpus := s.queryNvidia(); len(gpus) != tt.gpus {
+				t.Errorf("got %d GPUs, want %d", len(gpus), tt.gpus)
+			}
+			if s.gpuNoEngines != tt.noEngines || s.gpuNoMIG != tt.noMIG {
+				t.Errorf("noEngines %v, noMIG %v; want %v, %v", s.gpuNoEngines, s.gpuNoMIG, tt.noEngines, tt.noMIG)
+			}
+			if tt.fullQueryNextPoll {
+				s.queryNvidia()
+				if want := strings.Split(gpuBaseFields+gpuEngineFields+gpuMIGField, ","); !slices.Equal(last, want) {
+					t.Errorf("next poll queried %v, want every field", last)
+				}
+			}
+		})
+	}
+}
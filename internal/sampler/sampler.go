@@ -3,16 +3,24 @@ package sampler
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
@@ -25,51 +33,341 @@ import (
 // Sampler periodically emits Samples built from procfs and best-effort GPU/Batt reads.
 type Sampler struct {
 	Interval time.Duration
+	// Runner executes the GPU tools and journalctl. It defaults to
+	// ExecRunner; replace it before calling Stream, e.g. with canned output.
+	Runner CommandRunner
+	// FS is where /proc and /sys are read from for batteries, thermal zones,
+	// inotify limits and process status and cgroups, with paths relative to
+	// the root ("proc/1/cgroup"). It defaults to os.DirFS("/"); tests can
+	// substitute a fstest.MapFS.
+	FS fs.FS
+	// Processes lists the processes to rank. It defaults to
+	// GopsutilProcesses; tests and benchmarks can substitute synthetic ones.
+	Processes func() ([]Proc, error)
 
-	prevTotal  float64
-	prevIdle   float64
+	cfg config.Config
+
+	prevCPU cpu.TimesStat
+	// iowaitPct and stealPct are the latest system-wide breakdown from
+	// cpuPercents.
+	iowaitPct  float64
+	stealPct   float64
 	prevCore   []cpu.TimesStat
 	prevDisk   map[string]disk.IOCountersStat
 	prevNet    []net.IOCountersStat
+	prevNetIf  map[string]net.IOCountersStat
 	prevProcIO map[int]procIO
 	prevFD     map[int]int
+	prevSwap   swapCounters
+	prevKernel kernelCounters
+
+	prevThreadTicks map[int]uint64
+	prevProcCPU     map[int]procCPU
+
+	// Scheduler stats for -schedstat
+	prevSched     []schedCounters
+	prevProcSched map[int]schedCounters
+
+	// Softirq state for -net-softirq
+	prevSoftirq    softirqCounts
+	coreSoftirqPct []float64
+
+	// balloon is set on VMs with a virtio-balloon device (detected once).
+	balloon bool
+
+	// Running totals for -totals
+	totals model.Totals
+	// Previous sample for -rates; nil until the first one is taken.
+	prevSample *model.Sample
+
+	// memTotal is the latest MemTotal, used to turn cgroup memory.current
+	// into a percentage.
+	memTotal uint64
+
+	// lastTick is when the previous sample was taken; elapsed is the time
+	// since then, which delta-based rates are computed over. It exceeds
+	// Interval when a slow sample made the loop skip ticks.
+	lastTick time.Time
+	elapsed  time.Duration
+	// lagWarned is when a lagging sample was last logged.
+	lagWarned time.Time
+	// dropped counts samples the consumer of Stream never received.
+	dropped atomic.Uint64
+	// history keeps the last cfg.History samples; nil when that is 0.
+	history *history
+
+	// tick counts samples taken; ranAt, spans and cached hold when each
+	// -cadence collector last ran, the time between its last two runs and
+	// what it returned.
+	tick   int
+	ranAt  map[string]time.Time
+	spans  map[string]time.Duration
+	cached cadenced
 
 	// Cgroup cache
-	cgroupCache map[int]string
+	cgroupCache map[int]cgroupRef
 	cacheTick   int
+	prevCgIO    map[string]cgroupIO
+	prevCgCPU   map[string]cgroupCPUStat
+
+	// Per-user aggregation: PID -> effective UID (cleared with the cgroup
+	// cache) and UID -> login name.
+	uidCache  map[int]int
+	userNames map[int]string
+
+	// filter is the compiled -filter regex (nil = report everything)
+	filter *regexp.Regexp
+
+	// health tracks failing readers for logging
+	health readerHealth
+
+	// Static host configuration, read once in New
+	oomConfig model.OOMConfig
 
 	// GPU async
 	gpuData []model.GPU
-	gpuMu   sync.RWMutex
+	// GPU tools found at startup; gpuNoEngines and gpuNoMIG are set once the
+	// NVIDIA driver rejects per-engine fields or the MIG mode field.
+	hasNvidiaSMI bool
+	hasROCmSMI   bool
+	// hasIntelGPUTop needs both the tool and an Intel DRM card.
+	hasIntelGPUTop bool
+	gpuNoEngines   bool
+	gpuNoMIG       bool
+	rocmNoJSON     bool
+	gpuAt          time.Time
+	gpuMu          sync.RWMutex
+
+	// Socket counts (async, -connections)
+	connData model.Connections
+	connAt   time.Time
+	connMu   sync.RWMutex
+
+	// Filesystem capacity (async, -disk-usage)
+	diskData []model.Disk
+	diskAt   time.Time
+	diskMu   sync.RWMutex
+
+	// Recent OOM kills (async, -kills)
+	killData []model.KillEvent
+	killAt   time.Time
+	killMu   sync.RWMutex
 }
 
+// lagWarnEvery rate-limits the warning about samples overrunning the
+// interval, which tends to flap while the host is loaded.
+const lagWarnEvery = time.Minute
+
+// staleFactor marks an async section stale once it is this many poll periods old.
+const staleFactor = 3
+
+// New builds a sampler with default options and the given interval.
 func New(interval time.Duration) *Sampler {
-	return &Sampler{
-		Interval:    interval,
-		prevDisk:    make(map[string]disk.IOCountersStat),
-		prevProcIO:  make(map[int]procIO),
-		prevFD:      make(map[int]int),
-		cgroupCache: make(map[int]string),
+	cfg := config.Default()
+	cfg.Interval = interval
+	return NewWithConfig(cfg)
+}
+
+// CompileFilter compiles a process filter regex (-filter). The TUI's live
+// filter goes through it too, so both accept the same syntax.
+func CompileFilter(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile(expr)
+}
+
+// NewWithConfig builds a sampler honoring the runtime options in cfg.
+func NewWithConfig(cfg config.Config) *Sampler {
+	// Callers validate the config first; a hand-built one may leave the
+	// interval or GPU timings zero, which would panic the ticker or kill
+	// every tool run.
+	def := config.Default()
+	cfg.Interval = config.ClampInterval(cfg.Interval)
+	if cfg.GPUInterval <= 0 {
+		cfg.GPUInterval = def.GPUInterval
+	}
+	if cfg.GPUTimeout <= 0 {
+		cfg.GPUTimeout = def.GPUTimeout
+	}
+	s := &Sampler{
+		oomConfig:  readOOMConfig(),
+		Interval:   cfg.Interval,
+		Runner:     ExecRunner{},
+		FS:         os.DirFS("/"),
+		Processes:  GopsutilProcesses,
+		cfg:        cfg,
+		history:    newHistory(cfg.History),
+		prevDisk:   make(map[string]disk.IOCountersStat),
+		prevProcIO: make(map[int]procIO),
+		prevFD:     make(map[int]int),
+
+		prevThreadTicks: make(map[int]uint64),
+		prevProcCPU:     make(map[int]procCPU),
+		totals:          model.Totals{Since: time.Now()},
+		prevProcSched:   make(map[int]schedCounters),
+		balloon:         hasVirtioBalloon(),
+		hasNvidiaSMI:    cfg.EnableGPU && hasTool("nvidia-smi"),
+		hasROCmSMI:      cfg.EnableGPU && hasTool("rocm-smi"),
+		hasIntelGPUTop:  cfg.EnableGPU && hasTool("intel_gpu_top") && hasIntelGPU(),
+		ranAt:           make(map[string]time.Time),
+		spans:           make(map[string]time.Duration),
+		cgroupCache:     make(map[int]cgroupRef),
+		uidCache:        make(map[int]int),
+		userNames:       make(map[int]string),
+		prevCgIO:        make(map[string]cgroupIO),
+		prevCgCPU:       make(map[string]cgroupCPUStat),
+	}
+	if cfg.Filter != "" {
+		re, err := CompileFilter(cfg.Filter)
+		if err != nil {
+			// Callers validate the config first; don't silently drop everything.
+			slog.Error("ignoring invalid -filter", "filter", cfg.Filter, "err", err)
+		}
+		s.filter = re
 	}
+	// Nothing clears this entry, so every sample's Errors carries it.
+	if err := Unsupported(); err != nil {
+		s.health.report("platform", err)
+	}
+	// Prime the delta-based counters so the first sample, taken one interval
+	// from now by Stream's ticker, already has real CPU and I/O rates.
+	s.cpuPercents()
+	s.ioNet()
+	s.prevSwap = readSwapCounters()
+	s.prevKernel = readKernelCounters()
+	return s
+}
+
+// Proc is what topProcs reads about one process; the methods are those of
+// gopsutil's *process.Process.
+type Proc interface {
+	PID() int
+	Name() (string, error)
+	Cmdline() (string, error)
+	CreateTime() (int64, error)
+	Times() (*cpu.TimesStat, error)
+	MemoryPercent() (float32, error)
+	Nice() (int32, error)
+	IOCounters() (*process.IOCountersStat, error)
+	Uids() ([]int32, error)
 }
 
+// gopsutilProc adapts *process.Process to Proc.
+type gopsutilProc struct{ *process.Process }
+
+func (p gopsutilProc) PID() int { return int(p.Pid) }
+
+// GopsutilProcesses lists the host's processes through gopsutil.
+func GopsutilProcesses() ([]Proc, error) {
+	procs, err := process.Processes()
+	out := make([]Proc, len(procs))
+	for i, p := range procs {
+		out[i] = gopsutilProc{p}
+	}
+	return out, err
+}
+
+// procIO caches a process's cumulative I/O bytes between ticks. The map is
+// rebuilt every tick, so exited PIDs drop out; start (ms since epoch) guards
+// against PID reuse.
 type procIO struct {
 	read  uint64
 	write uint64
+	start int64
+}
+
+// procCPU caches a process's cumulative CPU time (utime+stime, in seconds)
+// between ticks, guarded against PID reuse like procIO.
+type procCPU struct {
+	secs  float64
+	start int64
+}
+
+// intervalCPU is a process's CPU percent over the last dt seconds, from its
+// CPU time now and at the previous tick. A process seen for the first time
+// gets its lifetime average instead, which is all there is to go on.
+// Lifetime averages (gopsutil's CPUPercent) hide a long-running daemon that
+// starts spinning, which is what -protect and -cap-cgroup must catch.
+func intervalCPU(cur procCPU, prev procCPU, seen bool, dt float64, now time.Time) float64 {
+	if seen && prev.start == cur.start && cur.secs >= prev.secs && dt > 0 {
+		return (cur.secs - prev.secs) / dt * 100
+	}
+	if cur.start <= 0 {
+		return 0
+	}
+	if age := now.Sub(time.UnixMilli(cur.start)).Seconds(); age > 0 {
+		return cur.secs / age * 100
+	}
+	return 0
 }
 
 // Stream returns a channel that will receive snapshots until ctx is done.
+// The channel is closed only after the tick loop and the background GPU,
+// connection, disk and kill loops have returned. Helper goroutines they
+// abandoned may outlive it, such as a statfs call stuck on a hung mount. A
+// sample taken while ctx was being cancelled is dropped rather than sent.
+//
+// Sending never blocks the tick loop, so a slow consumer can't distort the
+// interval-based rates: the channel holds one sample, and a newer one
+// replaces it if the consumer hasn't taken it yet (see DroppedSamples).
 func (s *Sampler) Stream(ctx context.Context) <-chan model.Sample {
-	ch := make(chan model.Sample)
-	go s.gpuLoop(ctx)
+	ch := make(chan model.Sample, 1)
+	var wg sync.WaitGroup
+	if s.cfg.EnableGPU {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.gpuLoop(ctx)
+		}()
+	}
+	if s.cfg.Connections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.connLoop(ctx)
+		}()
+	}
+	if s.cfg.DiskUsage {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.diskLoop(ctx)
+		}()
+	}
+	if s.cfg.Kills {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.killLoop(ctx)
+		}()
+	}
 	go func() {
-		ticker := time.NewTicker(s.Interval)
-		defer ticker.Stop()
+		// Ticks stay on a fixed grid. A sample that overruns skips
+		// the ticks it missed rather than firing them late back to back,
+		// which would squeeze the next deltas into a fraction of a second.
+		// With -adaptive the grid's spacing changes between samples.
+		next := time.Now().Add(s.Interval)
+		timer := time.NewTimer(s.Interval)
+		defer timer.Stop()
 		defer close(ch)
+		defer wg.Wait()
 		for {
 			select {
-			case t := <-ticker.C:
-				ch <- s.sample(t)
+			case t := <-timer.C:
+				samp := s.sample(t)
+				if ctx.Err() != nil {
+					return
+				}
+				s.history.add(samp)
+				s.send(ch, samp)
+				if s.cfg.Adaptive {
+					s.Interval = s.nextInterval(samp)
+				}
+				next = next.Add(s.Interval)
+				if late := time.Since(next); late >= 0 {
+					skipped := late/s.Interval + 1
+					slog.Debug("sampler skipped ticks", "ticks", int(skipped))
+					next = next.Add(skipped * s.Interval)
+				}
+				timer.Reset(time.Until(next))
 			case <-ctx.Done():
 				return
 			}
@@ -78,80 +376,356 @@ func (s *Sampler) Stream(ctx context.Context) <-chan model.Sample {
 	return ch
 }
 
+// send delivers samp without blocking. If the consumer hasn't taken the
+// previous sample yet, that one is dropped so the consumer gets the newest.
+// Stream is the only sender, so the final send always finds room.
+func (s *Sampler) send(ch chan model.Sample, samp model.Sample) {
+	select {
+	case ch <- samp:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+		s.dropped.Add(1)
+	default:
+	}
+	ch <- samp
+}
+
+// DroppedSamples is how many samples were replaced before the consumer of
+// Stream received them.
+func (s *Sampler) DroppedSamples() uint64 {
+	return s.dropped.Load()
+}
+
 func (s *Sampler) sample(now time.Time) model.Sample {
-	memStat, _ := mem.VirtualMemory()
-	swapStat, _ := mem.SwapMemory()
+	rt := readerTimer{start: time.Now()}
+	s.elapsed = s.Interval
+	if !s.lastTick.IsZero() {
+		s.elapsed = now.Sub(s.lastTick)
+	}
+	s.lastTick = now
 
-	cpuPct, corePct := s.cpuPercents()
-	loadAvg, _ := load.Avg()
+	var memStat mem.VirtualMemoryStat
+	var swapStat mem.SwapMemoryStat
+	var swapIn, swapOut float64
+	rt.time("mem", func() {
+		v, err := mem.VirtualMemory()
+		if err == nil {
+			memStat = *v
+			s.memTotal = v.Total
+		}
+		s.health.report("mem", err)
+		sw, err := mem.SwapMemory()
+		if err == nil {
+			swapStat = *sw
+		}
+		s.health.report("swap", err)
+		cur := readSwapCounters()
+		swapIn, swapOut = swapRates(cur, s.prevSwap, s.elapsed.Seconds())
+		s.prevSwap = cur
+	})
 
-	ioStat := s.ioNet()
+	var cpuPct float64
+	var corePct []float64
+	var freqs []float64
+	var loadAvg load.AvgStat
+	var ctxtRate, intrRate, forkRate float64
+	rt.time("cpu", func() {
+		cpuPct, corePct = s.cpuPercents()
+		cur := readKernelCounters()
+		ctxtRate, intrRate, forkRate = kernelRates(cur, s.prevKernel, s.elapsed.Seconds())
+		s.prevKernel = cur
+		freqs = s.coreFreqs(s.prevCore)
+		v, err := load.Avg()
+		if err == nil {
+			loadAvg = *v
+		}
+		s.health.report("load", err)
+	})
+
+	// The remaining readers touch disjoint state, so they run concurrently,
+	// one goroutine per group; readers that feed each other (procs ->
+	// per-process schedstat -> threads, temps -> sensors) share a group.
+	c := &s.cached
+	procsDue := s.due("procs", now)
+	battDue := s.cfg.EnableBatt && s.due("battery", now)
+	inotifyDue := s.due("inotify", now)
+	tempsDue := s.due("temps", now)
+	sensorsDue := s.due("sensors", now)
+	numaDue := s.due("numa", now)
 
 	// Clear cgroup cache occasionally (every ~60 ticks) to handle PID reuse
 	s.cacheTick++
 	if s.cacheTick > 60 {
-		s.cgroupCache = make(map[int]string)
+		s.cgroupCache = make(map[int]cgroupRef)
+		s.uidCache = make(map[int]int)
 		s.cacheTick = 0
 	}
-	top, throttled, cgroups := s.topProcs()
 
-	s.gpuMu.RLock()
-	gpus := s.gpuData
-	s.gpuMu.RUnlock()
+	var wg sync.WaitGroup
+	spawn := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	var ioStat model.IO
+	spawn(func() { rt.time("io", func() { ioStat = s.ioNet() }) })
+	if procsDue {
+		spawn(func() {
+			rt.time("procs", func() { c.top, c.niced, c.cpuThrottled, c.cgroups, c.users = s.topProcs() })
+			if s.cfg.Schedstat {
+				rt.time("schedstat-procs", func() { s.addProcSchedLatency(c.top) })
+			}
+			if s.cfg.Threads {
+				rt.time("threads", func() { c.threads = s.threads(c.top) })
+			}
+		})
+	}
+	var schedAvg float64
+	var schedPerCore []float64
+	if s.cfg.Schedstat {
+		spawn(func() { rt.time("schedstat", func() { schedAvg, schedPerCore = s.schedLatency() }) })
+	}
+	var netSoftirq []model.NetSoftirq
+	if s.cfg.NetSoftirq {
+		spawn(func() { rt.time("softirq", func() { netSoftirq = s.netSoftirq() }) })
+	}
+	if battDue {
+		spawn(func() { rt.time("battery", func() { c.batts = s.batteries() }) })
+	}
+	if inotifyDue {
+		spawn(func() {
+			rt.time("inotify", func() {
+				c.inotify = s.inotify()
+				c.openFDs, c.maxFDs = readFileNr()
+			})
+		})
+	}
+	if tempsDue || sensorsDue {
+		spawn(func() {
+			if tempsDue {
+				rt.time("temps", func() { c.temps = s.temps() })
+			}
+			if sensorsDue {
+				rt.time("sensors", func() { c.sensors = s.sensors(c.temps) })
+			}
+		})
+	}
+	if numaDue {
+		spawn(func() { rt.time("numa", func() { c.numa = s.numaNodes() }) })
+	}
+	var pressure model.Pressure
+	spawn(func() { rt.time("pressure", func() { pressure = readPressure() }) })
+	wg.Wait()
+	top, niced, cpuThrottled, cgroups, users, threads := c.top, c.niced, c.cpuThrottled, c.cgroups, c.users, c.threads
+	top, niced, cpuThrottled = withUptime(top, now), withUptime(niced, now), withUptime(cpuThrottled, now)
+	batts, inotify, openFDs, maxFDs := c.batts, c.inotify, c.openFDs, c.maxFDs
+	temps, sensors, numa := c.temps, c.sensors, c.numa
 
-	batt := s.battery()
-	inotify := s.inotify()
-	temps := s.temps()
+	var gpus []model.GPU
+	var sections []model.SectionAge
+	if s.cfg.EnableGPU {
+		s.gpuMu.RLock()
+		gpus = s.gpuData
+		sections = append(sections, sectionAge("gpu", s.gpuAt, s.cfg.GPUInterval, now))
+		s.gpuMu.RUnlock()
+	}
+
+	var conns *model.Connections
+	if s.cfg.Connections {
+		s.connMu.RLock()
+		c := s.connData
+		conns = &c
+		sections = append(sections, sectionAge("connections", s.connAt, connPollInterval, now))
+		s.connMu.RUnlock()
+	}
+
+	var disks []model.Disk
+	if s.cfg.DiskUsage {
+		s.diskMu.RLock()
+		disks = s.diskData
+		sections = append(sections, sectionAge("disks", s.diskAt, diskPollInterval, now))
+		s.diskMu.RUnlock()
+	}
+
+	var kills []model.KillEvent
+	if s.cfg.Kills {
+		s.killMu.RLock()
+		kills = s.killData
+		sections = append(sections, sectionAge("kills", s.killAt, killPollInterval, now))
+		s.killMu.RUnlock()
+	}
+
+	var balloon *model.Balloon
+	if s.balloon {
+		balloon = readBalloon(memStat.Total)
+	}
 
-	return model.Sample{
-		Timestamp: now,
-		Interval:  s.Interval,
+	sections = append(sections, s.cadenceAges(now)...)
+	s.tick++
+
+	var totals *model.Totals
+	if s.cfg.Totals {
+		t := s.totals
+		totals = &t
+	}
+
+	samp := model.Sample{
+		SchemaVersion: model.SchemaVersion,
+		Timestamp:     now,
+		Interval:      s.Interval,
 		CPU: model.CPU{
 			Total:   cpuPct,
 			PerCore: corePct,
+			Iowait:  s.iowaitPct,
+			Steal:   s.stealPct,
+			Freqs:   freqs,
 			Load1:   loadAvg.Load1,
 			Load5:   loadAvg.Load5,
 			Load15:  loadAvg.Load15,
+
+			ContextSwitchesPerSec: ctxtRate,
+			InterruptsPerSec:      intrRate,
+			ForksPerSec:           forkRate,
+			SchedLatencyUs:        schedAvg,
+			PerCoreSchedLatencyUs: schedPerCore,
+			NetSoftirq:            netSoftirq,
 		},
 		Memory: model.Memory{
-			UsedBytes:  memStat.Used,
-			TotalBytes: memStat.Total,
-			SwapUsed:   swapStat.Used,
-			SwapTotal:  swapStat.Total,
-			Cached:     memStat.Cached,
-			Buffers:    memStat.Buffers,
+			UsedBytes:      usedMemory(memStat),
+			TotalBytes:     memStat.Total,
+			AvailableBytes: memStat.Available,
+			RawUsedBytes:   memStat.Used,
+			SwapUsed:       swapStat.Used,
+			SwapTotal:      swapStat.Total,
+			Cached:         memStat.Cached,
+			Buffers:        memStat.Buffers,
+			SwapInPerSec:   swapIn,
+			SwapOutPerSec:  swapOut,
 		},
-		IO:        ioStat,
-		GPUs:      gpus,
-		Battery:   batt,
-		Top:       top,
-		Throttled: throttled,
-		Cgroups:   cgroups,
-		Inotify:   inotify,
-		Temps:     temps,
+		IO:           ioStat,
+		GPUs:         gpus,
+		Sections:     sections,
+		Batteries:    batts,
+		Top:          top,
+		Niced:        niced,
+		Throttled:    niced, // deprecated alias, see model.Sample
+		CPUThrottled: cpuThrottled,
+		Threads:      threads,
+		Cgroups:      cgroups,
+		Users:        users,
+		Inotify:      inotify,
+		OpenFDs:      openFDs,
+		MaxFDs:       maxFDs,
+		Temps:        temps,
+		Sensors:      sensors,
+		OOM:          s.oomConfig,
+		Pressure:     pressure,
+		Balloon:      balloon,
+		NUMA:         numa,
+		Connections:  conns,
+		Disks:        disks,
+		Kills:        kills,
+		Self:         rt.stats(),
+		Totals:       totals,
+		Errors:       s.health.errors(),
+	}
+	samp.Battery = samp.PrimaryBattery() // deprecated alias, see model.Sample
+	if s.cfg.Rates {
+		samp.Rates = rates(s.prevSample, samp)
+		s.prevSample = &samp
+	}
+	samp.Lagging = samp.Self.SampleDuration > s.Interval
+	if samp.Lagging && now.Sub(s.lagWarned) >= lagWarnEvery {
+		s.lagWarned = now
+		slog.Warn("sampling takes longer than the interval; skipping ticks",
+			"took", samp.Self.SampleDuration, "interval", s.Interval)
+	}
+	return samp
+}
+
+// sectionAge describes the freshness of a section refreshed every period.
+func sectionAge(name string, at time.Time, period time.Duration, now time.Time) model.SectionAge {
+	return model.SectionAge{
+		Name:        name,
+		CollectedAt: at,
+		Stale:       at.IsZero() || now.Sub(at) > staleFactor*period,
 	}
 }
 
+// readerTimer records how long each reader takes within a single sample.
+// Readers run concurrently, so time is safe to call from several goroutines.
+type readerTimer struct {
+	start   time.Time
+	mu      sync.Mutex
+	readers []model.ReaderTiming
+}
+
+func (t *readerTimer) time(name string, fn func()) {
+	start := time.Now()
+	fn()
+	d := time.Since(start)
+	t.mu.Lock()
+	t.readers = append(t.readers, model.ReaderTiming{Name: name, Duration: d})
+	t.mu.Unlock()
+}
+
+func (t *readerTimer) stats() model.SelfStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return model.SelfStats{SampleDuration: time.Since(t.start), Readers: t.readers}
+}
+
+// busySecs is the CPU time spent neither idle nor waiting on I/O.
+func busySecs(t cpu.TimesStat) float64 {
+	return t.Total() - t.Idle - t.Iowait
+}
+
+// cpuShares returns the busy, iowait and steal percentages of the CPU time
+// elapsed between prev and cur, or zeros if none elapsed. Iowait counts as
+// idle in busy, and a counter that went backwards reads as 0.
+func cpuShares(cur, prev cpu.TimesStat) (busy, iowait, steal float64) {
+	dt := cur.Total() - prev.Total()
+	if dt <= 0 {
+		return 0, 0, 0
+	}
+	busy = 100 * (busySecs(cur) - busySecs(prev)) / dt
+	iowait = max(0, 100*(cur.Iowait-prev.Iowait)/dt)
+	steal = max(0, 100*(cur.Steal-prev.Steal)/dt)
+	return busy, iowait, steal
+}
+
 // CPU percentages from times delta.
 func (s *Sampler) cpuPercents() (total float64, perCore []float64) {
-	times, _ := cpu.Times(false)
+	times, err := cpu.Times(false)
+	s.health.report("cpu", err)
 	if len(times) == 0 {
 		return 0, nil
 	}
 	cur := times[0]
-	curTotal := cur.Total()
-	curIdle := cur.Idle + cur.Iowait
-	if s.prevTotal > 0 {
-		dt := curTotal - s.prevTotal
-		di := curIdle - s.prevIdle
-		if dt > 0 {
-			total = 100 * (1 - di/dt)
+	primed := s.prevCPU.Total() > 0
+	s.iowaitPct, s.stealPct = 0, 0
+	if primed {
+		total, s.iowaitPct, s.stealPct = cpuShares(cur, s.prevCPU)
+	}
+	if s.cfg.Totals {
+		busy, prevBusy := busySecs(cur), busySecs(s.prevCPU)
+		s.totals.BootCPUSeconds = busy
+		if primed && busy >= prevBusy {
+			s.totals.CPUSeconds += busy - prevBusy
 		}
 	}
-	s.prevTotal, s.prevIdle = curTotal, curIdle
+	s.prevCPU = cur
 
 	coreTimes, _ := cpu.Times(true)
 	perCore = make([]float64, len(coreTimes))
+	if s.cfg.NetSoftirq && len(s.coreSoftirqPct) != len(coreTimes) {
+		s.coreSoftirqPct = make([]float64, len(coreTimes))
+	}
 	for i, c := range coreTimes {
 		if i >= len(s.prevCore) {
 			perCore[i] = 0
@@ -162,6 +736,9 @@ func (s *Sampler) cpuPercents() (total float64, perCore []float64) {
 		di := (c.Idle + c.Iowait) - (prev.Idle + prev.Iowait)
 		if dt > 0 {
 			perCore[i] = 100 * (1 - di/dt)
+			if s.cfg.NetSoftirq {
+				s.coreSoftirqPct[i] = 100 * (c.Softirq - prev.Softirq) / dt
+			}
 		}
 	}
 	s.prevCore = coreTimes
@@ -170,66 +747,182 @@ func (s *Sampler) cpuPercents() (total float64, perCore []float64) {
 
 func (s *Sampler) ioNet() model.IO {
 	// Disk
-	diskCounters, _ := disk.IOCounters()
+	diskCounters, err := disk.IOCounters()
+	s.health.report("disk", err)
 	var rdBytesDelta, wrBytesDelta uint64
+	var rdBytesRaw, wrBytesRaw uint64
 	var perDev []model.IODevice
+	dur := s.elapsed.Seconds()
+	if dur <= 0 {
+		dur = 1
+	}
 	for name, st := range diskCounters {
-		if strings.HasPrefix(name, "loop") {
+		if s.excludedDisk(name) {
 			continue
 		}
+		rdBytesRaw += st.ReadBytes
+		wrBytesRaw += st.WriteBytes
 		prev, ok := s.prevDisk[name]
 		if ok {
-			if st.ReadBytes > prev.ReadBytes {
-				rdBytesDelta += st.ReadBytes - prev.ReadBytes
-			}
-			if st.WriteBytes > prev.WriteBytes {
-				wrBytesDelta += st.WriteBytes - prev.WriteBytes
-			}
-			dt := s.Interval.Seconds()
-			if dt <= 0 {
-				dt = 1
-			}
+			rd, wr := delta(st.ReadBytes, prev.ReadBytes), delta(st.WriteBytes, prev.WriteBytes)
+			rdBytesDelta += rd
+			wrBytesDelta += wr
 			perDev = append(perDev, model.IODevice{
-				Name:     name,
-				ReadMBs:  float64(st.ReadBytes-prev.ReadBytes) / (1024 * 1024) / dt,
-				WriteMBs: float64(st.WriteBytes-prev.WriteBytes) / (1024 * 1024) / dt,
+				Name:      name,
+				ReadMBs:   float64(rd) / (1024 * 1024) / dur,
+				WriteMBs:  float64(wr) / (1024 * 1024) / dur,
+				ReadIOPS:  float64(delta(st.ReadCount, prev.ReadCount)) / dur,
+				WriteIOPS: float64(delta(st.WriteCount, prev.WriteCount)) / dur,
 			})
 		}
 		s.prevDisk[name] = st
 	}
-	dur := s.Interval.Seconds()
-	if dur <= 0 {
-		dur = 1
-	}
+	sort.Slice(perDev, func(i, j int) bool { return perDev[i].Name < perDev[j].Name })
 	ioStat := model.IO{
 		DiskReadMBs:  float64(rdBytesDelta) / (1024 * 1024) / dur,
 		DiskWriteMBs: float64(wrBytesDelta) / (1024 * 1024) / dur,
 		PerDevice:    perDev,
 	}
 
+	if s.cfg.Totals {
+		s.totals.DiskReadBytes += rdBytesDelta
+		s.totals.DiskWriteBytes += wrBytesDelta
+		s.totals.BootDiskReadBytes, s.totals.BootDiskWriteBytes = rdBytesRaw, wrBytesRaw
+	}
+
 	// Net
-	netCounters, _ := net.IOCounters(false)
+	netCounters, err := net.IOCounters(false)
+	s.health.report("net", err)
 	if len(netCounters) > 0 && len(s.prevNet) > 0 {
-		rx := netCounters[0].BytesRecv - s.prevNet[0].BytesRecv
-		tx := netCounters[0].BytesSent - s.prevNet[0].BytesSent
-		ioStat.NetRxMbps = float64(rx*8) / 1e6 / dur
-		ioStat.NetTxMbps = float64(tx*8) / 1e6 / dur
+		ioStat.NetRxMbps = mbps(netCounters[0].BytesRecv, s.prevNet[0].BytesRecv, dur)
+		ioStat.NetTxMbps = mbps(netCounters[0].BytesSent, s.prevNet[0].BytesSent, dur)
+	}
+	if s.cfg.Totals && len(netCounters) > 0 {
+		cur := netCounters[0]
+		s.totals.BootNetRxBytes, s.totals.BootNetTxBytes = cur.BytesRecv, cur.BytesSent
+		// A counter that went backwards was reset; skip that tick's delta.
+		if len(s.prevNet) > 0 && cur.BytesRecv >= s.prevNet[0].BytesRecv && cur.BytesSent >= s.prevNet[0].BytesSent {
+			s.totals.NetRxBytes += cur.BytesRecv - s.prevNet[0].BytesRecv
+			s.totals.NetTxBytes += cur.BytesSent - s.prevNet[0].BytesSent
+		}
 	}
 	if len(netCounters) > 0 {
 		s.prevNet = netCounters
 	}
+	ioStat.PerInterface = s.netInterfaces(dur)
 	return ioStat
 }
 
-func (s *Sampler) topProcs() (top []model.Process, throttled []model.Process, cgs []model.Cgroup) {
-	procs, _ := process.Processes()
-	type cgAgg struct{ cpu float64 }
+// isLoopback checks the interface's ARP hardware type (772 = ARPHRD_LOOPBACK),
+// falling back to the conventional name.
+func isLoopback(name string) bool {
+	if b, err := os.ReadFile(filepath.Join("/sys/class/net", name, "type")); err == nil {
+		return strings.TrimSpace(string(b)) == "772"
+	}
+	return name == "lo"
+}
+
+// excludedDisk reports whether a block device is skipped by -disk-exclude
+// (loop, device-mapper, ramdisks and optical by default) and not brought back
+// by -disk-include. dm-* devices sit on top of physical disks, so counting
+// both would double the totals.
+func (s *Sampler) excludedDisk(name string) bool {
+	return matchDisk(s.cfg.DiskExclude, name) && !matchDisk(s.cfg.DiskInclude, name)
+}
+
+// matchDisk reports whether name matches any of the globs in patterns. A
+// pattern without wildcards matches as a prefix, as -disk-exclude did before
+// it took globs.
+func matchDisk(patterns []string, name string) bool {
+	for _, p := range patterns {
+		switch {
+		case p == "":
+		case !strings.ContainsAny(p, `*?[\`):
+			if strings.HasPrefix(name, p) {
+				return true
+			}
+		default:
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// delta is cur-prev for a cumulative counter, or 0 if it went backwards.
+func delta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// mbps converts a byte-counter delta to megabits per second. A counter that
+// went backwards (wrap, VPN reconnect, interface re-created) would underflow,
+// so it counts as no traffic for that tick, as on the disk path.
+func mbps(cur, prev uint64, dur float64) float64 {
+	if cur <= prev {
+		return 0
+	}
+	return float64((cur-prev)*8) / 1e6 / dur
+}
+
+// netInterfaces computes per-interface throughput and error/drop rates.
+// Counters that went backwards (driver reset, interface re-created) count as
+// zero for the tick; interfaces that disappeared simply drop out.
+func (s *Sampler) netInterfaces(dur float64) []model.NetInterface {
+	counters, _ := net.IOCounters(true)
+	rate := func(cur, prev uint64) float64 {
+		if cur < prev {
+			return 0
+		}
+		return float64(cur-prev) / dur
+	}
+	next := make(map[string]net.IOCountersStat, len(counters))
+	var out []model.NetInterface
+	for _, c := range counters {
+		next[c.Name] = c
+		prev, ok := s.prevNetIf[c.Name]
+		if !ok {
+			continue
+		}
+		ni := model.NetInterface{
+			Name:            c.Name,
+			RxMbps:          mbps(c.BytesRecv, prev.BytesRecv, dur),
+			TxMbps:          mbps(c.BytesSent, prev.BytesSent, dur),
+			Loopback:        isLoopback(c.Name),
+			RxErrorsPerSec:  rate(c.Errin, prev.Errin),
+			TxErrorsPerSec:  rate(c.Errout, prev.Errout),
+			RxDroppedPerSec: rate(c.Dropin, prev.Dropin),
+			TxDroppedPerSec: rate(c.Dropout, prev.Dropout),
+		}
+		ni.Degraded = ni.RxErrorsPerSec+ni.TxErrorsPerSec+ni.RxDroppedPerSec+ni.TxDroppedPerSec > 0
+		out = append(out, ni)
+	}
+	s.prevNetIf = next
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// topProcs builds the ranked process lists and cgroup aggregates. niced holds
+// processes with a positive nice value; cpuThrottled holds processes whose
+// cgroup hit its CPU quota this interval.
+func (s *Sampler) topProcs() (top, niced, cpuThrottled []model.Process, cgs []model.Cgroup, users []model.UserUsage) {
+	procs, err := s.Processes()
+	s.health.report("procs", err)
+	type cgAgg struct {
+		cpu  float64
+		mem  float64
+		name string
+	}
 	cgMap := make(map[string]*cgAgg)
+	procCgroup := make(map[int]string) // listed PID -> cgroup path
+	byUID := make(map[int]*model.UserUsage)
 	newProcIO := make(map[int]procIO)
-	dt := s.Interval.Seconds()
-	if dt <= 0 {
-		dt = 1
-	}
+	newProcCPU := make(map[int]procCPU)
+	dt := s.span("procs")
+	now := time.Now()
 
 	for _, p := range procs {
 		// Skip kernel threads without name
@@ -237,71 +930,177 @@ func (s *Sampler) topProcs() (top []model.Process, throttled []model.Process, cg
 		if name == "" {
 			continue
 		}
-		cpuPct, _ := p.CPUPercent()
 		memPct, _ := p.MemoryPercent()
-		nice, _ := p.Nice()
+		// gopsutil returns the raw getpriority(2) value, 20 - nice. A failed
+		// read must not look like nice 20.
+		nice := 0
+		if prio, err := p.Nice(); err == nil {
+			nice = 20 - int(prio)
+		}
 		cmd, _ := p.Cmdline()
 		if cmd == "" {
 			cmd = name
 		}
-		fdCount, _ := p.NumFDs()
-		fdDiff := int(fdCount) - s.prevFD[int(p.Pid)]
 
+		start, _ := p.CreateTime() // ms since the epoch; 0 if unknown
+		var cpuPct float64
+		if times, err := p.Times(); err == nil {
+			cur := procCPU{secs: times.User + times.System, start: start}
+			prev, seen := s.prevProcCPU[p.PID()]
+			cpuPct = intervalCPU(cur, prev, seen, dt, now)
+			newProcCPU[p.PID()] = cur
+		}
 		var rRate, wRate float64
 		if ioCounters, err := p.IOCounters(); err == nil && ioCounters != nil {
-			prev := s.prevProcIO[int(p.Pid)]
-			if prev.read > 0 && ioCounters.ReadBytes >= prev.read && dt > 0 {
-				rRate = float64(ioCounters.ReadBytes-prev.read) / 1024.0 / dt
-			}
-			if prev.write > 0 && ioCounters.WriteBytes >= prev.write && dt > 0 {
-				wRate = float64(ioCounters.WriteBytes-prev.write) / 1024.0 / dt
+			// A different start time means the PID was reused; its counters
+			// are unrelated to the cached ones.
+			if prev, ok := s.prevProcIO[p.PID()]; ok && prev.start == start {
+				if ioCounters.ReadBytes >= prev.read {
+					rRate = float64(ioCounters.ReadBytes-prev.read) / 1024.0 / dt
+				}
+				if ioCounters.WriteBytes >= prev.write {
+					wRate = float64(ioCounters.WriteBytes-prev.write) / 1024.0 / dt
+				}
 			}
-			newProcIO[int(p.Pid)] = procIO{read: ioCounters.ReadBytes, write: ioCounters.WriteBytes}
+			newProcIO[p.PID()] = procIO{read: ioCounters.ReadBytes, write: ioCounters.WriteBytes, start: start}
 		}
 
+		// -filter narrows the reported lists; cgroup totals below still
+		// count every process.
+		listed := s.filter == nil || s.filter.MatchString(name) || s.filter.MatchString(cmd)
+
 		entry := model.Process{
-			PID:      int(p.Pid),
-			Nice:     int(nice),
+			PID:      p.PID(),
+			Nice:     nice,
 			CPU:      cpuPct,
 			Memory:   float64(memPct),
 			Command:  truncate(cmd, 60),
-			FDCount:  int(fdCount),
 			ReadKBs:  rRate,
 			WriteKBs: wRate,
-			FDDiff:   fdDiff,
 		}
-		top = append(top, entry)
-		if nice > 0 {
-			throttled = append(throttled, entry)
+		if start > 0 {
+			entry.StartTime = time.UnixMilli(start)
+		}
+		if listed {
+			top = append(top, entry)
+			if nice > 0 {
+				niced = append(niced, entry)
+			}
 		}
-		// cgroup aggregate (best-effort)
-		// Best-effort cgroup aggregation: parse /proc/<pid>/cgroup last path component.
-		if cgPath, err := s.readProcCgroup(int(p.Pid)); err == nil {
-			if _, ok := cgMap[cgPath]; !ok {
-				cgMap[cgPath] = &cgAgg{}
+		// Like cgroups, users are charged for every process, listed or not.
+		if uid, ok := s.procUID(p); ok {
+			u, ok := byUID[uid]
+			if !ok {
+				u = &model.UserUsage{}
+				byUID[uid] = u
+			}
+			u.CPU += cpuPct
+			u.Memory += float64(memPct)
+			u.Processes++
+		}
+		// Best-effort cgroup aggregation, keyed on the full path so equally
+		// named leaves in different slices stay separate.
+		if !s.cfg.EnableCgroups {
+			continue
+		}
+		if cg, err := s.readProcCgroup(p.PID()); err == nil {
+			agg, ok := cgMap[cg.path]
+			if !ok {
+				agg = &cgAgg{name: cg.name}
+				cgMap[cg.path] = agg
+			}
+			agg.cpu += cpuPct
+			agg.mem += float64(memPct)
+			if listed {
+				procCgroup[p.PID()] = cg.path
 			}
-			cgMap[cgPath].cpu += cpuPct
 		}
 	}
 
-	sort.Slice(top, func(i, j int) bool { return top[i].CPU > top[j].CPU })
-	if len(top) > 64 {
-		top = top[:64]
+	newCgIO := make(map[string]cgroupIO)
+	newCgCPU := make(map[string]cgroupCPUStat)
+	for p, agg := range cgMap {
+		cg := model.Cgroup{Name: agg.name, Path: p, CPU: agg.cpu}
+		s.cgroupMemory(&cg, p, agg.mem)
+		if cur, err := readCgroupIOStat(p); err == nil {
+			if prev, ok := s.prevCgIO[p]; ok {
+				if cur.read >= prev.read {
+					cg.IOReadBytesPerSec = float64(cur.read-prev.read) / dt
+				}
+				if cur.write >= prev.write {
+					cg.IOWriteBytesPerSec = float64(cur.write-prev.write) / dt
+				}
+			}
+			newCgIO[p] = cur
+		}
+		if cur, ok := readCgroupCPUStat(p); ok {
+			cg.NrThrottled = cur.nrThrottled
+			if prev, ok := s.prevCgCPU[p]; ok {
+				cg.ThrottledPerSec = float64(delta(cur.nrThrottled, prev.nrThrottled)) / dt
+				cg.ThrottledMsPerSec = float64(delta(cur.throttledUsec, prev.throttledUsec)) / 1000 / dt
+			}
+			newCgCPU[p] = cur
+		}
+		if cur, max, err := readCgroupPids(p); err == nil {
+			cg.PIDsCurrent, cg.PIDsMax = cur, max
+			cg.PIDsNearLimit = max > 0 && float64(cur) >= pidsNearLimit*float64(max)
+		}
+		cgs = append(cgs, cg)
 	}
-	sort.Slice(throttled, func(i, j int) bool { return throttled[i].CPU > throttled[j].CPU })
-	if len(throttled) > 32 {
-		throttled = throttled[:32]
+	s.prevCgIO = newCgIO
+	s.prevCgCPU = newCgCPU
+	sort.SliceStable(cgs, func(i, j int) bool {
+		if cgs[i].CPU != cgs[j].CPU {
+			return cgs[i].CPU > cgs[j].CPU
+		}
+		return cgs[i].Path < cgs[j].Path
+	})
+	throttledCg := make(map[string]bool)
+	for _, cg := range cgs {
+		if cg.ThrottledPerSec > 0 {
+			throttledCg[cg.Path] = true
+		}
 	}
+	cgs = limit(cgs, share(s.cfg.Top, 4))
+	users = s.userUsages(byUID)
 
-	for name, agg := range cgMap {
-		cgs = append(cgs, model.Cgroup{Name: name, CPU: agg.cpu})
+	// Status fields (peak memory, threads, state) are normally read only for
+	// the survivors; ranking by peak needs them for every process first.
+	byPeak := s.cfg.Sort == "peak" || s.cfg.Sort2 == "peak"
+	if byPeak {
+		s.addProcStatus(top)
 	}
-	sort.Slice(cgs, func(i, j int) bool { return cgs[i].CPU > cgs[j].CPU })
-	if len(cgs) > 16 {
-		cgs = cgs[:16]
+	// Likewise fd counts: listing /proc/<pid>/fd is the costliest per-process
+	// read, so it is skipped for processes that won't be reported.
+	byFD := s.cfg.Sort == "fd" || s.cfg.Sort2 == "fd"
+	if byFD {
+		s.addFDs(top)
 	}
+	SortProcesses(top, s.cfg.Sort, s.cfg.Sort2)
+	top = s.applyThresholds(top)
+	for _, p := range top {
+		if throttledCg[procCgroup[p.PID]] {
+			cpuThrottled = append(cpuThrottled, p)
+		}
+	}
+	niced = s.applyThresholds(niced)
+	top = limit(top, s.cfg.Top)
+	SortProcesses(niced, s.cfg.Sort, s.cfg.Sort2)
+	niced = limit(niced, share(s.cfg.Top, 2))
+	cpuThrottled = limit(cpuThrottled, share(s.cfg.Top, 2))
+	if !byPeak {
+		s.addProcStatus(top)
+	}
+	s.addProcStatus(niced)
+	s.addProcStatus(cpuThrottled)
+	if !byFD {
+		s.addFDs(top)
+	}
+	s.addFDs(niced)
+	s.addFDs(cpuThrottled)
 
 	s.prevProcIO = newProcIO
+	s.prevProcCPU = newProcCPU
 	s.prevFD = make(map[int]int)
 	for _, p := range top {
 		s.prevFD[p.PID] = p.FDCount
@@ -314,7 +1113,7 @@ func (s *Sampler) gpuLoop(ctx context.Context) {
 	s.updateGPU()
 
 	// Poll GPU slower than main loop to reduce overhead/stutter
-	ticker := time.NewTicker(2 * time.Second)
+	ticker := time.NewTicker(s.cfg.GPUInterval)
 	defer ticker.Stop()
 
 	for {
@@ -331,58 +1130,158 @@ func (s *Sampler) updateGPU() {
 	data := s.queryGPU()
 	s.gpuMu.Lock()
 	s.gpuData = data
+	s.gpuAt = time.Now()
 	s.gpuMu.Unlock()
 }
 
+// nvidia-smi query fields. Per-engine utilization (memory controller,
+// NVENC, NVDEC) and the MIG mode are not known to older drivers, which
+// reject the whole query naming the unknown field; that field's group is then
+// dropped for the rest of the run.
+const (
+	gpuBaseFields   = "index,uuid,name,utilization.gpu,memory.used,memory.total,temperature.gpu,pci.bus_id"
+	gpuEngineFields = ",utilization.memory,utilization.encoder,utilization.decoder"
+	gpuMIGField     = ",mig.mode.current"
+)
+
+// queryGPU collects GPUs from every vendor tool found at startup, so hosts
+// with both NVIDIA and AMD cards report all of them.
 func (s *Sampler) queryGPU() []model.GPU {
-	out, _ := runCmd(400*time.Millisecond, "nvidia-smi",
-		"--query-gpu=name,utilization.gpu,memory.used,memory.total,temperature.gpu",
-		"--format=csv,noheader,nounits")
-	if out == "" {
-		return nil
+	var gpus []model.GPU
+	if s.hasNvidiaSMI {
+		gpus = append(gpus, s.queryNvidia()...)
+	}
+	if s.hasROCmSMI {
+		gpus = append(gpus, s.queryROCm()...)
+	}
+	if s.hasIntelGPUTop {
+		gpus = append(gpus, s.queryIntel()...)
 	}
+	return gpus
+}
+
+func (s *Sampler) queryNvidia() []model.GPU {
+	for {
+		fields := gpuBaseFields
+		if !s.gpuNoEngines {
+			fields += gpuEngineFields
+		}
+		if !s.gpuNoMIG {
+			fields += gpuMIGField
+		}
+		out, err := runCmd(s.Runner, s.cfg.GPUTimeout, "nvidia-smi",
+			"--query-gpu="+fields, "--format=csv,noheader,nounits")
+		if err == nil {
+			s.health.report("nvidia-smi", nil)
+			gpus := parseGPUs(out, fields)
+			s.nvidiaProcs(gpus)
+			s.nvidiaMIG(gpus)
+			return gpus
+		}
+		rejected := rejectedGPUField(out)
+		if rejected == "" || (s.gpuNoEngines && s.gpuNoMIG) {
+			// Timed out, the base query failed, or a transient driver error
+			// such as a lost GPU: keep every field and retry next poll.
+			s.gpuErr("nvidia-smi", err)
+			return nil
+		}
+		// Drop the rejected field's group and retry. A field that is not
+		// optional drops the newest group still queried.
+		switch {
+		case !s.gpuNoEngines && slices.Contains(strings.Split(gpuEngineFields, ","), rejected):
+			slog.Info("nvidia-smi rejected per-engine utilization fields; using base query", "output", strings.TrimSpace(out))
+			s.gpuNoEngines = true
+		case !s.gpuNoMIG:
+			slog.Info("nvidia-smi rejected the MIG mode field; MIG instances won't be listed", "output", strings.TrimSpace(out))
+			s.gpuNoMIG = true
+		default:
+			slog.Info("nvidia-smi rejected per-engine utilization fields; using base query", "output", strings.TrimSpace(out))
+			s.gpuNoEngines = true
+		}
+	}
+}
+
+// rejectedGPUField returns the field nvidia-smi named in an invalid-field
+// error, e.g. `Field "mig.mode.current" is not a valid field to query.`, or
+// "" if out is some other failure.
+func rejectedGPUField(out string) string {
+	_, rest, ok := strings.Cut(out, `Field "`)
+	if !ok {
+		return ""
+	}
+	field, rest, ok := strings.Cut(rest, `"`)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(rest), "is not a valid field") {
+		return ""
+	}
+	return field
+}
+
+// gpuErr reports a failed GPU query. A tool that disappeared since startup
+// is only logged at debug.
+func (s *Sampler) gpuErr(tool string, err error) {
+	if errors.Is(err, exec.ErrNotFound) {
+		slog.Debug("GPU tool not found", "tool", tool)
+		return
+	}
+	s.health.report(tool, err)
+}
+
+// parseGPUs parses --query-gpu rows; fields is the query, which says where
+// the optional columns are.
+func parseGPUs(out, fields string) []model.GPU {
+	cols := strings.Split(fields, ",")
+	engines := slices.Index(cols, "utilization.memory")
+	mig := slices.Index(cols, "mig.mode.current")
 	var gpus []model.GPU
 	sc := bufio.NewScanner(strings.NewReader(out))
 	for sc.Scan() {
 		parts := strings.Split(sc.Text(), ",")
-		if len(parts) < 5 {
+		if len(parts) < 8 {
 			continue
 		}
-		name := strings.TrimSpace(parts[0])
-		util := parseFloat(parts[1])
-		memUsed := parseFloat(parts[2])
-		memTotal := parseFloat(parts[3])
-		temp := parseFloat(parts[4])
-		gpus = append(gpus, model.GPU{
-			Name:       name,
-			Util:       util,
-			MemUsedMB:  memUsed,
-			MemTotalMB: memTotal,
-			TempC:      temp,
-		})
+		idx, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			continue
+		}
+		g := model.GPU{
+			Index:      idx,
+			UUID:       strings.TrimSpace(parts[1]),
+			Name:       strings.TrimSpace(parts[2]),
+			Util:       gpuField(parts[3]),
+			MemUsedMB:  gpuField(parts[4]),
+			MemTotalMB: gpuField(parts[5]),
+			TempC:      gpuField(parts[6]),
+			BusID:      strings.TrimSpace(parts[7]),
+		}
+		if engines > 0 && len(parts) > engines+2 {
+			g.MemUtil = gpuField(parts[engines])
+			g.EncoderUtil = gpuField(parts[engines+1])
+			g.DecoderUtil = gpuField(parts[engines+2])
+		}
+		if mig > 0 && len(parts) > mig {
+			g.MIGEnabled = strings.TrimSpace(parts[mig]) == "Enabled"
+		}
+		gpus = append(gpus, g)
 	}
+	// nvidia-smi lists by PCI order, which need not match the index order
+	// (CUDA_DEVICE_ORDER, a card reset).
+	sort.SliceStable(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
 	return gpus
 }
 
-func (s *Sampler) battery() model.Battery {
-	battPaths, _ := filepath.Glob("/sys/class/power_supply/BAT*/capacity")
-	for _, capPath := range battPaths {
-		base := filepath.Dir(capPath)
-		capBytes, err := os.ReadFile(capPath)
-		if err != nil {
-			continue
-		}
-		pct := parseFloat(string(capBytes))
-		stateBytes, _ := os.ReadFile(filepath.Join(base, "status"))
-		state := strings.TrimSpace(string(stateBytes))
-		return model.Battery{Percent: pct, State: state}
+// gpuField parses one nvidia-smi reading. Values it can't provide come out
+// bracketed ("[N/A]", "[Not Supported]", "[Unknown Error]") and are
+// model.GPUUnavailable rather than 0.
+func gpuField(v string) float64 {
+	if strings.HasPrefix(strings.TrimSpace(v), "[") {
+		return model.GPUUnavailable
 	}
-	return model.Battery{}
+	return parseFloat(v)
 }
 
 func (s *Sampler) inotify() model.Inotify {
-	readUint := func(path string) uint64 {
-		b, err := os.ReadFile(path)
+	readUint := func(name string) uint64 {
+		b, err := fs.ReadFile(s.FS, name)
 		if err != nil {
 			return 0
 		}
@@ -390,27 +1289,88 @@ func (s *Sampler) inotify() model.Inotify {
 		return v
 	}
 	return model.Inotify{
-		MaxUserWatches:   readUint("/proc/sys/fs/inotify/max_user_watches"),
-		MaxUserInstances: readUint("/proc/sys/fs/inotify/max_user_instances"),
-		NrWatches:        readUint("/proc/sys/fs/inotify/nr_watches"),
+		MaxUserWatches:   readUint("proc/sys/fs/inotify/max_user_watches"),
+		MaxUserInstances: readUint("proc/sys/fs/inotify/max_user_instances"),
+		NrWatches:        readUint("proc/sys/fs/inotify/nr_watches"),
+	}
+}
+
+// readOOMConfig reads vm.* OOM tunables and looks for earlyoom/systemd-oomd.
+// These rarely change, so it runs once at startup.
+func readOOMConfig() model.OOMConfig {
+	readInt := func(name string) int {
+		b, err := os.ReadFile("/proc/sys/vm/" + name)
+		if err != nil {
+			return 0
+		}
+		v, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+		return v
+	}
+	cfg := model.OOMConfig{
+		OvercommitMemory: readInt("overcommit_memory"),
+		OvercommitRatio:  readInt("overcommit_ratio"),
+		Swappiness:       readInt("swappiness"),
+		MinFreeKbytes:    uint64(readInt("min_free_kbytes")),
+		PanicOnOOM:       readInt("panic_on_oom"),
+	}
+	comms, _ := filepath.Glob("/proc/[0-9]*/comm")
+	for _, p := range comms {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(string(b)) {
+		case "earlyoom":
+			cfg.EarlyOOMActive = true
+		case "systemd-oomd":
+			cfg.OOMDActive = true
+		}
 	}
+	return cfg
 }
 
 func (s *Sampler) temps() []model.Temp {
 	var temps []model.Temp
-	paths, _ := filepath.Glob("/sys/class/thermal/thermal_zone*/temp")
+	paths, _ := fs.Glob(s.FS, "sys/class/thermal/thermal_zone*/temp")
 	for _, p := range paths {
-		b, err := os.ReadFile(p)
+		b, err := fs.ReadFile(s.FS, p)
 		if err != nil {
 			continue
 		}
 		val := parseFloat(string(b)) / 1000
-		zone := filepath.Base(filepath.Dir(p))
-		temps = append(temps, model.Temp{Zone: zone, Temp: val})
+		dir := path.Dir(p)
+		zone := path.Base(dir)
+		label := readTrimmed(s.FS, path.Join(dir, "type"))
+		if label == "" {
+			label = zone
+		}
+		temps = append(temps, model.Temp{Zone: zone, Label: label, Temp: val})
 	}
 	return temps
 }
 
+// coreFreqs returns the current frequency (MHz) of each core in cores, the
+// per-core times PerCore was computed from, read from
+// /sys/devices/system/cpu/cpuN/cpufreq/scaling_cur_freq. Cores are matched by
+// their "cpuN" name, not their position, since offline CPUs leave gaps in
+// the numbering.
+func (s *Sampler) coreFreqs(cores []cpu.TimesStat) []float64 {
+	freqs := make([]float64, len(cores))
+	found := false
+	for i, c := range cores {
+		b, err := fs.ReadFile(s.FS, "sys/devices/system/cpu/"+c.CPU+"/cpufreq/scaling_cur_freq")
+		if err != nil {
+			continue
+		}
+		freqs[i] = parseFloat(string(b)) / 1000
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return freqs
+}
+
 // Helpers
 func parseFloat(s string) float64 {
 	s = strings.TrimSpace(s)
@@ -426,42 +1386,205 @@ func truncate(s string, max int) string {
 	return s[:max-1] + "…"
 }
 
-func runCmd(timeout time.Duration, name string, args ...string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
-	if ctx.Err() == context.DeadlineExceeded {
-		return "", ctx.Err()
+// limit truncates xs to n entries; n <= 0 means unlimited. The niced/throttled
+// and cgroup lists are capped at half and a quarter of -top respectively.
+func limit[T any](xs []T, n int) []T {
+	if n > 0 && len(xs) > n {
+		return xs[:n]
+	}
+	return xs
+}
+
+// share derives a secondary cap from -top, keeping "unlimited" unlimited and
+// never rounding a finite cap down to zero (which would mean unlimited).
+func share(top, div int) int {
+	if top <= 0 {
+		return 0
+	}
+	return max(1, top/div)
+}
+
+// applyThresholds drops processes under -min-cpu / -min-mem, keeping order.
+func (s *Sampler) applyThresholds(procs []model.Process) []model.Process {
+	if s.cfg.MinCPU <= 0 && s.cfg.MinMem <= 0 {
+		return procs
 	}
-	return string(out), err
+	kept := procs[:0]
+	for _, p := range procs {
+		if p.CPU < s.cfg.MinCPU || p.Memory < s.cfg.MinMem {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+// usedMemory is total minus available, as free(1) reports it. Kernels
+// without MemAvailable (pre-3.14) get gopsutil's raw Used instead.
+func usedMemory(v mem.VirtualMemoryStat) uint64 {
+	if v.Available == 0 || v.Available > v.Total {
+		return v.Used
+	}
+	return v.Total - v.Available
+}
+
+// addFDs fills FDCount and FDDiff (change since the last tick) by counting
+// /proc/<pid>/fd entries. Unreadable directories (other users' processes
+// without privileges, exited PIDs) leave both at zero.
+func (s *Sampler) addFDs(procs []model.Process) {
+	for i := range procs {
+		n, err := countFDs(procs[i].PID)
+		if err != nil {
+			continue
+		}
+		procs[i].FDCount = n
+		procs[i].FDDiff = n - s.prevFD[procs[i].PID]
+	}
+}
+
+func countFDs(pid int) (int, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	return len(names), err
 }
 
-// readProcCgroup returns the last path component of the first cgroup entry.
-func (s *Sampler) readProcCgroup(pid int) (string, error) {
+// readFileNr returns allocated and maximum file handles system-wide from
+// /proc/sys/fs/file-nr ("allocated unused max"; unused is 0 on modern kernels).
+func readFileNr() (open, max uint64) {
+	b, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return 0, 0
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	alloc, _ := strconv.ParseUint(fields[0], 10, 64)
+	unused, _ := strconv.ParseUint(fields[1], 10, 64)
+	max, _ = strconv.ParseUint(fields[2], 10, 64)
+	if unused <= alloc {
+		open = alloc - unused
+	}
+	return open, max
+}
+
+// addProcStatus fills memory, thread count and state from /proc/<pid>/status;
+// PIDs that vanished since the scan are left zero.
+func (s *Sampler) addProcStatus(procs []model.Process) {
+	for i := range procs {
+		st, err := readProcStatus(s.FS, procs[i].PID)
+		if err != nil {
+			continue
+		}
+		procs[i].RSSBytes = st.rss
+		procs[i].PeakRSSBytes = st.hwm
+		procs[i].PeakVirtBytes = st.peak
+		procs[i].Threads = st.threads
+		procs[i].State = st.state
+		procs[i].PPID = st.ppid
+	}
+}
+
+// withUptime returns a copy of procs with Uptime measured up to now. -cadence
+// reuses the lists on later ticks, and samples already handed out share
+// them, so they are not updated in place.
+func withUptime(procs []model.Process, now time.Time) []model.Process {
+	if procs == nil {
+		return nil
+	}
+	out := make([]model.Process, len(procs))
+	for i, p := range procs {
+		if !p.StartTime.IsZero() {
+			p.Uptime = now.Sub(p.StartTime)
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// procStatus holds the /proc/<pid>/status fields we report.
+type procStatus struct {
+	rss, hwm, peak uint64 // VmRSS, VmHWM, VmPeak in bytes
+	threads        int
+	state          string // ps letter: R, S, D, Z, T, I...
+	ppid           int
+}
+
+func readProcStatus(fsys fs.FS, pid int) (procStatus, error) {
+	var st procStatus
+	b, err := fs.ReadFile(fsys, fmt.Sprintf("proc/%d/status", pid))
+	if err != nil {
+		return st, err
+	}
+	sc := bufio.NewScanner(strings.NewReader(string(b)))
+	for sc.Scan() {
+		key, val, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(val)
+		if len(fields) == 0 {
+			continue
+		}
+		n, _ := strconv.ParseUint(fields[0], 10, 64)
+		switch key {
+		case "State":
+			st.state = fields[0]
+		case "Threads":
+			st.threads = int(n)
+		case "PPid":
+			st.ppid = int(n)
+		case "VmRSS":
+			st.rss = n * 1024
+		case "VmHWM":
+			st.hwm = n * 1024
+		case "VmPeak":
+			st.peak = n * 1024
+		}
+	}
+	return st, nil
+}
+
+// readProcCgroup returns pid's cgroup: its full path relative to the
+// hierarchy root and the last path component for display.
+func (s *Sampler) readProcCgroup(pid int) (cgroupRef, error) {
 	if v, ok := s.cgroupCache[pid]; ok {
 		return v, nil
 	}
-	path := fmt.Sprintf("/proc/%d/cgroup", pid)
-	f, err := os.Open(path)
+	f, err := s.FS.Open(fmt.Sprintf("proc/%d/cgroup", pid))
 	if err != nil {
-		return "", err
+		return cgroupRef{}, err
 	}
 	defer f.Close()
+	// Prefer the unified (v2) "0::" entry; on hybrid hosts the v1 controller
+	// lines come first and describe a different hierarchy.
+	var ref cgroupRef
 	sc := bufio.NewScanner(f)
 	for sc.Scan() {
-		line := sc.Text()
-		parts := strings.Split(line, ":")
+		parts := strings.SplitN(sc.Text(), ":", 3)
 		if len(parts) != 3 {
 			continue
 		}
 		p := parts[2]
-		segs := strings.Split(p, "/")
-		for i := len(segs) - 1; i >= 0; i-- {
-			if segs[i] != "" {
-				s.cgroupCache[pid] = segs[i]
-				return segs[i], nil
-			}
+		name := path.Base(p)
+		if name == "/" || name == "." {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			ref = cgroupRef{name: name, path: p}
+			break
 		}
+		if ref.path == "" {
+			ref = cgroupRef{name: name, path: p}
+		}
+	}
+	if ref.path == "" {
+		return cgroupRef{}, fmt.Errorf("no cgroup")
 	}
-	return "", fmt.Errorf("no cgroup")
+	s.cgroupCache[pid] = ref
+	return ref, nil
 }
@@ -0,0 +1,333 @@
+package sampler
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestIntervalCPU(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	start := now.Add(-100 * time.Hour).UnixMilli()
+	tests := []struct {
+		name string
+		cur  procCPU
+		prev procCPU
+		seen bool
+		want float64
+	}{
+		// Up for 100h, mostly idle, now spinning a full core: the lifetime
+		// average would say under 1%.
+		{"long-running daemon spinning", procCPU{secs: 3600 + 2, start: start}, procCPU{secs: 3600, start: start}, true, 100},
+		{"two cores", procCPU{secs: 14, start: start}, procCPU{secs: 10, start: start}, true, 200},
+		{"idle", procCPU{secs: 10, start: start}, procCPU{secs: 10, start: start}, true, 0},
+		{"first seen: lifetime average", procCPU{secs: 3600, start: start}, procCPU{}, false, 1},
+		{"PID reused: lifetime average", procCPU{secs: 3600, start: start}, procCPU{secs: 1, start: start - 1}, true, 1},
+		{"counter went backwards", procCPU{secs: 3600, start: start}, procCPU{secs: 4000, start: start}, true, 1},
+		{"unknown start", procCPU{secs: 5}, procCPU{}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := intervalCPU(tt.cur, tt.prev, tt.seen, 2, now)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("intervalCPU = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCPUShares(t *testing.T) {
+	// prev is a host 1000s into boot; each case adds 100s of CPU time.
+	prev := cpu.TimesStat{User: 400, System: 100, Idle: 450, Iowait: 30, Steal: 20}
+	add := func(user, system, idle, iowait, steal float64) cpu.TimesStat {
+		return cpu.TimesStat{User: prev.User + user, System: prev.System + system, Idle: prev.Idle + idle,
+			Iowait: prev.Iowait + iowait, Steal: prev.Steal + steal}
+	}
+	tests := []struct {
+		name                string
+		cur                 cpu.TimesStat
+		busy, iowait, steal float64
+	}{
+		{"busy", add(60, 20, 20, 0, 0), 80, 0, 0},
+		// Iowait is idle time, not busy time.
+		{"waiting on disk", add(10, 5, 25, 60, 0), 15, 60, 0},
+		// Steal is time the hypervisor gave to another guest; it counts as busy.
+		{"noisy neighbour", add(30, 10, 35, 0, 25), 65, 0, 25},
+		{"iowait went backwards", add(50, 10, 40+30, -30, 0), 60, 0, 0},
+		{"no time elapsed", prev, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			busy, iowait, steal := cpuShares(tt.cur, prev)
+			if math.Abs(busy-tt.busy) > 1e-9 || math.Abs(iowait-tt.iowait) > 1e-9 || math.Abs(steal-tt.steal) > 1e-9 {
+				t.Errorf("cpuShares = %v busy, %v iowait, %v steal; want %v, %v, %v",
+					busy, iowait, steal, tt.busy, tt.iowait, tt.steal)
+			}
+		})
+	}
+}
+
+func TestExcludedDisk(t *testing.T) {
+	devices := []string{"sda", "sda1", "nvme0n1", "nvme0n1p2", "vda", "loop0", "loop12", "dm-0", "dm-3", "ram0", "sr0", "zram0", "md127"}
+	tests := []struct {
+		name             string
+		exclude, include []string
+		counted          []string
+	}{
+		{"default", config.Default().DiskExclude, nil,
+			[]string{"sda", "sda1", "nvme0n1", "nvme0n1p2", "vda", "zram0", "md127"}},
+		{"include device-mapper", config.Default().DiskExclude, []string{"dm-*"},
+			[]string{"sda", "sda1", "nvme0n1", "nvme0n1p2", "vda", "dm-0", "dm-3", "zram0", "md127"}},
+		{"include one volume", config.Default().DiskExclude, []string{"dm-3"},
+			[]string{"sda", "sda1", "nvme0n1", "nvme0n1p2", "vda", "dm-3", "zram0", "md127"}},
+		// A pattern without wildcards is a prefix, as before -disk-exclude took globs.
+		{"prefix", []string{"loop", "zram"}, nil,
+			[]string{"sda", "sda1", "nvme0n1", "nvme0n1p2", "vda", "dm-0", "dm-3", "ram0", "sr0", "md127"}},
+		{"partitions", []string{"sd?[0-9]", "nvme*p*"}, nil,
+			[]string{"sda", "nvme0n1", "vda", "loop0", "loop12", "dm-0", "dm-3", "ram0", "sr0", "zram0", "md127"}},
+		{"nothing excluded", nil, []string{"sda"}, devices},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DiskExclude, cfg.DiskInclude = tt.exclude, tt.include
+			s := &Sampler{cfg: cfg}
+			var counted []string
+			for _, d := range devices {
+				if !s.excludedDisk(d) {
+					counted = append(counted, d)
+				}
+			}
+			if !slices.Equal(counted, tt.counted) {
+				t.Errorf("counted %v, want %v", counted, tt.counted)
+			}
+		})
+	}
+}
+
+// runnerFunc is a CommandRunner backed by a function, standing in for
+// nvidia-smi, journalctl and the other tools.
+type runnerFunc func(ctx context.Context, name string, args ...string) (string, error)
+
+func (f runnerFunc) Run(ctx context.Context, name string, args ...string) (string, error) {
+	return f(ctx, name, args...)
+}
+
+// fakeNvidia answers the GPU queries with one card and everything else
+// with no output.
+var fakeNvidia = runnerFunc(func(ctx context.Context, name string, args ...string) (string, error) {
+	if name == "nvidia-smi" && len(args) > 0 && strings.HasPrefix(args[0], "--query-gpu") {
+		return "0, GPU-a, NVIDIA T4, 40, 1024, 15360, 50, 00000000:01:00.0, 10, 0, 0, Disabled\n", nil
+	}
+	return "", nil
+})
+
+// TestSampleConcurrent drives the concurrent collectors, and Stream with
+// its background loops, with every optional reader on. Run it with -race.
+func TestSampleConcurrent(t *testing.T) {
+	cfg := config.Default()
+	cfg.Interval = 50 * time.Millisecond
+	cfg.Threads = true
+	cfg.Schedstat = true
+	cfg.NetSoftirq = true
+	cfg.Connections = true
+	cfg.DiskUsage = true
+	cfg.Kills = true
+	cfg.Totals = true
+	cfg.Rates = true
+	cfg.GPUInterval = 500 * time.Millisecond
+	s := NewWithConfig(cfg)
+	s.Runner = fakeNvidia
+	s.hasNvidiaSMI = true
+
+	for i := 0; i < 3; i++ {
+		samp := s.sample(time.Now())
+		if samp.Memory.TotalBytes == 0 {
+			t.Errorf("sample %d: no memory total", i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := s.Stream(ctx)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-stream:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no sample from Stream")
+		}
+	}
+	cancel()
+	for range stream {
+	}
+}
+
+func TestTopProcsFilter(t *testing.T) {
+	start := time.Now().Add(-time.Minute).UnixMilli()
+	procs := []Proc{
+		&fakeProc{pid: 10, name: "chrome", cmd: "/opt/google/chrome/chrome --type=renderer", start: start},
+		&fakeProc{pid: 11, name: "firefox-esr", start: start},
+		&fakeProc{pid: 12, name: "chromium", cmd: "/usr/lib/chromium/chromium", start: start},
+		&fakeProc{pid: 13, name: "bash", cmd: "bash -c firefox", start: start},
+		&fakeProc{pid: 14, name: "Xorg", start: start},
+	}
+	cfg := config.Default()
+	cfg.Filter = "^(chrome|firefox)"
+	cfg.EnableCgroups = false
+	s := fixtureSampler(t, cfg, procs, fstest.MapFS{})
+
+	top, _, _, _, users := s.topProcs()
+	var got []int
+	for _, p := range top {
+		got = append(got, p.PID)
+	}
+	sort.Ints(got)
+	if want := []int{10, 11}; !slices.Equal(got, want) {
+		t.Errorf("listed PIDs = %v, want %v", got, want)
+	}
+	// Users are still charged for the processes the filter hides.
+	var n int
+	for _, u := range users {
+		n += u.Processes
+	}
+	if n != len(procs) {
+		t.Errorf("user totals count %d processes, want %d", n, len(procs))
+	}
+}
+
+// TestTopProcsFakeSource scans a fake process list in which some calls fail,
+// as they do for processes exiting mid-scan. PIDs are above any pid_max so
+// the host's /proc/<pid>/fd is never consulted.
+func TestTopProcsFakeSource(t *testing.T) {
+	start := time.Now().Add(-time.Hour).UnixMilli()
+	const base = 9_000_000
+	fail := func(methods ...string) map[string]bool {
+		m := make(map[string]bool)
+		for _, name := range methods {
+			m[name] = true
+		}
+		return m
+	}
+	procs := []Proc{
+		&fakeProc{pid: base + 1, name: "postgres", cmd: "postgres: checkpointer", start: start, cpuSecs: 36, mem: 2.5, nice: 5, uid: 70},
+		&fakeProc{pid: base + 2, name: "gone", start: start, fail: fail("Name")},
+		&fakeProc{pid: base + 3, name: "rsync", start: start, cpuSecs: 72, fail: fail("Cmdline", "IOCounters", "MemoryPercent", "Uids")},
+		&fakeProc{pid: base + 4, name: "zombie", start: start, fail: fail("Times", "CreateTime", "Nice")},
+		&fakeProc{pid: base + 5, name: "exited", start: start, cpuSecs: 1}, // no status file any more
+	}
+	fsys := fstest.MapFS{
+		"proc/9000001/status": procStatusFile("postgres", "D (disk sleep)", 1, 12, 4096),
+		"proc/9000003/status": procStatusFile("rsync", "R (running)", 9000001, 1, 1024),
+		"proc/9000004/status": procStatusFile("zombie", "Z (zombie)", 9000001, 1, 0),
+	}
+	cfg := config.Default()
+	cfg.EnableCgroups = false
+	s := fixtureSampler(t, cfg, procs, fsys)
+
+	top, niced, _, _, users := s.topProcs()
+	byPID := make(map[int]model.Process)
+	for _, p := range top {
+		byPID[p.PID] = p
+	}
+	if len(top) != 4 {
+		t.Errorf("listed %d processes, want 4 (all but the one whose name failed)", len(top))
+	}
+	if _, ok := byPID[base+2]; ok {
+		t.Error("listed the process whose name could not be read")
+	}
+
+	pg := byPID[base+1]
+	if pg.Threads != 12 || pg.State != "D" || pg.PPID != 1 || pg.RSSBytes != 4096<<10 {
+		t.Errorf("postgres status: threads %d, state %q, ppid %d, rss %d", pg.Threads, pg.State, pg.PPID, pg.RSSBytes)
+	}
+	if pg.Command != "postgres: checkpointer" || pg.Nice != 5 || pg.Memory != 2.5 || pg.StartTime.IsZero() {
+		t.Errorf("postgres = %+v", pg)
+	}
+	// First seen: CPU is the lifetime average, 36s over an hour.
+	if math.Abs(pg.CPU-1) > 0.01 {
+		t.Errorf("postgres CPU = %v, want 1", pg.CPU)
+	}
+	if len(niced) != 1 || niced[0].PID != base+1 {
+		t.Errorf("niced = %+v, want only postgres", niced)
+	}
+
+	rsync := byPID[base+3]
+	if rsync.Command != "rsync" || rsync.State != "R" || math.Abs(rsync.CPU-2) > 0.01 {
+		t.Errorf("rsync with failed calls = %+v", rsync)
+	}
+	zombie := byPID[base+4]
+	if zombie.State != "Z" || zombie.CPU != 0 || !zombie.StartTime.IsZero() {
+		t.Errorf("zombie = %+v", zombie)
+	}
+	exited := byPID[base+5]
+	if exited.Threads != 0 || exited.State != "" {
+		t.Errorf("exited = %+v, want no status fields", exited)
+	}
+
+	// Users are charged for every named process whose UID could be read.
+	var n int
+	for _, u := range users {
+		n += u.Processes
+	}
+	if n != 3 {
+		t.Errorf("user totals count %d processes, want 3", n)
+	}
+}
+
+// TestCgroupsSameLeaf has two units named app.service in different slices:
+// they must stay separate, aggregated by full path.
+func TestCgroupsSameLeaf(t *testing.T) {
+	start := time.Now().Add(-time.Hour).UnixMilli()
+	const base = 9_000_100
+	procs := []Proc{
+		&fakeProc{pid: base + 1, name: "app", start: start, cpuSecs: 36},
+		&fakeProc{pid: base + 2, name: "app", start: start, cpuSecs: 72},
+		&fakeProc{pid: base + 3, name: "app", start: start, cpuSecs: 360},
+	}
+	fsys := fstest.MapFS{
+		"proc/9000101/cgroup": {Data: []byte("0::/system.slice/app.service\n")},
+		"proc/9000102/cgroup": {Data: []byte("0::/system.slice/app.service\n")},
+		"proc/9000103/cgroup": {Data: []byte("0::/user.slice/user-1000.slice/user@1000.service/app.slice/app.service\n")},
+	}
+	cfg := config.Default()
+	cfg.EnableCgroups = true
+	s := fixtureSampler(t, cfg, procs, fsys)
+
+	_, _, _, cgs, _ := s.topProcs()
+	got := make(map[string]model.Cgroup)
+	for _, cg := range cgs {
+		got[cg.Path] = cg
+	}
+	tests := []struct {
+		path string
+		cpu  float64
+	}{
+		{"/system.slice/app.service", 3},
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/app.service", 10},
+	}
+	if len(cgs) != len(tests) {
+		t.Fatalf("got %d cgroups %+v, want %d", len(cgs), cgs, len(tests))
+	}
+	for _, tt := range tests {
+		cg, ok := got[tt.path]
+		if !ok {
+			t.Errorf("no cgroup %s", tt.path)
+			continue
+		}
+		if cg.Name != "app.service" || math.Abs(cg.CPU-tt.cpu) > 0.01 {
+			t.Errorf("%s: name %q, CPU %v; want app.service, %v", tt.path, cg.Name, cg.CPU, tt.cpu)
+		}
+	}
+	// Sorted by CPU, busiest first.
+	if cgs[0].Path != tests[1].path {
+		t.Errorf("first cgroup %s, want %s", cgs[0].Path, tests[1].path)
+	}
+}
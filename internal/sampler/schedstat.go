@@ -0,0 +1,89 @@
+package sampler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// schedCounters are cumulative run-queue counters: nanoseconds spent waiting
+// to run and the number of timeslices run.
+type schedCounters struct {
+	waitNs uint64
+	slices uint64
+}
+
+// latencyUs returns the average wait per timeslice between two readings.
+func latencyUs(cur, prev schedCounters) float64 {
+	if cur.slices <= prev.slices || cur.waitNs < prev.waitNs {
+		return 0
+	}
+	return float64(cur.waitNs-prev.waitNs) / float64(cur.slices-prev.slices) / 1000
+}
+
+// schedLatency reads /proc/schedstat (needs CONFIG_SCHEDSTATS) and returns the
+// average run-queue latency overall and per CPU, in microseconds.
+func (s *Sampler) schedLatency() (avg float64, perCore []float64) {
+	f, err := os.Open("/proc/schedstat")
+	if err != nil {
+		return 0, nil
+	}
+	defer f.Close()
+
+	var cur []schedCounters
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		// cpuN yld_count 0 sched_count sched_goidle ttwu_count ttwu_local rq_cpu_time run_delay pcount
+		if len(fields) < 10 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		wait, _ := strconv.ParseUint(fields[8], 10, 64)
+		slices, _ := strconv.ParseUint(fields[9], 10, 64)
+		cur = append(cur, schedCounters{waitNs: wait, slices: slices})
+	}
+
+	if len(s.prevSched) == len(cur) {
+		var sum, prevSum schedCounters
+		perCore = make([]float64, len(cur))
+		for i := range cur {
+			perCore[i] = latencyUs(cur[i], s.prevSched[i])
+			sum.waitNs += cur[i].waitNs
+			sum.slices += cur[i].slices
+			prevSum.waitNs += s.prevSched[i].waitNs
+			prevSum.slices += s.prevSched[i].slices
+		}
+		avg = latencyUs(sum, prevSum)
+	}
+	s.prevSched = cur
+	return avg, perCore
+}
+
+// addProcSchedLatency fills SchedLatencyUs from /proc/<pid>/schedstat
+// ("runtime_ns wait_ns timeslices") for the given processes.
+func (s *Sampler) addProcSchedLatency(procs []model.Process) {
+	next := make(map[int]schedCounters, len(procs))
+	for i := range procs {
+		pid := procs[i].PID
+		b, err := os.ReadFile(fmt.Sprintf("/proc/%d/schedstat", pid))
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(b))
+		if len(fields) < 3 {
+			continue
+		}
+		wait, _ := strconv.ParseUint(fields[1], 10, 64)
+		slices, _ := strconv.ParseUint(fields[2], 10, 64)
+		cur := schedCounters{waitNs: wait, slices: slices}
+		if prev, ok := s.prevProcSched[pid]; ok {
+			procs[i].SchedLatencyUs = latencyUs(cur, prev)
+		}
+		next[pid] = cur
+	}
+	s.prevProcSched = next
+}
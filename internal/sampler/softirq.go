@@ -0,0 +1,102 @@
+package sampler
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// A core is flagged as network-bound when at least this share of its time
+// goes to softirq processing and most of those softirqs are NET_RX/NET_TX.
+const (
+	netSoftirqCorePct = 30.0
+	netSoftirqShare   = 0.5
+)
+
+// softirqCounts holds per-CPU cumulative softirq counts from /proc/softirqs.
+type softirqCounts struct {
+	rx, tx, all []uint64
+}
+
+func readSoftirqs() (softirqCounts, bool) {
+	f, err := os.Open("/proc/softirqs")
+	if err != nil {
+		return softirqCounts{}, false
+	}
+	defer f.Close()
+
+	var c softirqCounts
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024) // one column per CPU
+	for sc.Scan() {
+		name, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue // CPU header
+		}
+		fields := strings.Fields(rest)
+		vals := make([]uint64, len(fields))
+		for i, f := range fields {
+			vals[i], _ = strconv.ParseUint(f, 10, 64)
+		}
+		if c.all == nil {
+			c.all = make([]uint64, len(vals))
+		}
+		for i := 0; i < len(vals) && i < len(c.all); i++ {
+			c.all[i] += vals[i]
+		}
+		switch strings.TrimSpace(name) {
+		case "NET_RX":
+			c.rx = vals
+		case "NET_TX":
+			c.tx = vals
+		}
+	}
+	return c, c.all != nil
+}
+
+// netSoftirq reports NET_RX/NET_TX softirq rates per CPU. This is kernel
+// network processing that isn't charged to any process, so it explains a core
+// that is busy while the process list looks idle.
+func (s *Sampler) netSoftirq() []model.NetSoftirq {
+	cur, ok := readSoftirqs()
+	if !ok {
+		return nil
+	}
+	prev := s.prevSoftirq
+	s.prevSoftirq = cur
+	if len(prev.all) != len(cur.all) || len(cur.rx) != len(cur.all) || len(cur.tx) != len(cur.all) ||
+		len(prev.rx) != len(cur.rx) || len(prev.tx) != len(cur.tx) {
+		return nil
+	}
+
+	dur := s.elapsed.Seconds()
+	if dur <= 0 {
+		dur = 1
+	}
+	delta := func(a, b uint64) float64 {
+		if a < b {
+			return 0
+		}
+		return float64(a - b)
+	}
+	out := make([]model.NetSoftirq, len(cur.all))
+	for i := range cur.all {
+		rx := delta(cur.rx[i], prev.rx[i])
+		tx := delta(cur.tx[i], prev.tx[i])
+		all := delta(cur.all[i], prev.all[i])
+		n := model.NetSoftirq{
+			CPU:      i,
+			RxPerSec: rx / dur,
+			TxPerSec: tx / dur,
+		}
+		if i < len(s.coreSoftirqPct) {
+			n.SoftirqPct = s.coreSoftirqPct[i]
+		}
+		n.Dominated = n.SoftirqPct >= netSoftirqCorePct && all > 0 && (rx+tx)/all >= netSoftirqShare
+		out[i] = n
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package sampler
+
+import (
+	"math"
+	"sort"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// processKey returns the value a process is ranked by for the given sort column.
+// Values are rounded to the one decimal the UI displays so that float noise
+// between ticks doesn't reorder rows that look identical on screen.
+func processKey(p model.Process, key string) float64 {
+	var v float64
+	switch key {
+	case "mem":
+		v = p.Memory
+	case "io":
+		v = p.ReadKBs + p.WriteKBs
+	case "fd":
+		v = float64(p.FDCount)
+	case "peak":
+		v = float64(p.PeakRSSBytes) / (1024 * 1024)
+	default: // "cpu"
+		v = p.CPU
+	}
+	return math.Round(v*10) / 10
+}
+
+// SortProcesses orders procs descending by key, then by key2 (if set), and
+// finally by ascending PID so tied rows keep a deterministic position.
+func SortProcesses(procs []model.Process, key, key2 string) {
+	sort.SliceStable(procs, func(i, j int) bool {
+		a, b := procs[i], procs[j]
+		if va, vb := processKey(a, key), processKey(b, key); va != vb {
+			return va > vb
+		}
+		if key2 != "" && key2 != key {
+			if va, vb := processKey(a, key2), processKey(b, key2); va != vb {
+				return va > vb
+			}
+		}
+		return a.PID < b.PID
+	})
+}
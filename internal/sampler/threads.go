@@ -0,0 +1,86 @@
+package sampler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+const (
+	// clockTicks is USER_HZ, the unit of utime/stime in /proc/<pid>/stat.
+	// It is 100 on every mainstream Linux build.
+	clockTicks = 100
+	// maxThreads caps the per-thread list so a JVM doesn't flood the output.
+	maxThreads = 64
+)
+
+// threads enumerates /proc/<pid>/task/* for the given processes and returns
+// the busiest threads by CPU. Each entry carries the owning PID and its TID.
+func (s *Sampler) threads(procs []model.Process) []model.Process {
+	dt := s.span("procs")
+	next := make(map[int]uint64)
+	var out []model.Process
+	for _, p := range procs {
+		tasks, _ := filepath.Glob(fmt.Sprintf("/proc/%d/task/[0-9]*", p.PID))
+		for _, dir := range tasks {
+			tid, err := strconv.Atoi(filepath.Base(dir))
+			if err != nil {
+				continue
+			}
+			ticks, err := readTaskTicks(dir)
+			if err != nil {
+				continue // thread exited mid-scan
+			}
+			next[tid] = ticks
+			var cpuPct float64
+			if prev, ok := s.prevThreadTicks[tid]; ok && ticks >= prev {
+				cpuPct = float64(ticks-prev) / clockTicks / dt * 100
+			}
+			comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+			name := strings.TrimSpace(string(comm))
+			if name == "" {
+				name = p.Command
+			}
+			out = append(out, model.Process{
+				PID:     p.PID,
+				TID:     tid,
+				Nice:    p.Nice,
+				CPU:     cpuPct,
+				Command: name,
+			})
+		}
+	}
+	s.prevThreadTicks = next
+
+	SortProcesses(out, "cpu", "")
+	if len(out) > maxThreads {
+		out = out[:maxThreads]
+	}
+	return out
+}
+
+// readTaskTicks returns utime+stime from a task's stat file.
+func readTaskTicks(dir string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// comm may contain spaces or parens; fields resume after the last ')'.
+	line := string(b)
+	i := strings.LastIndexByte(line, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("malformed stat")
+	}
+	fields := strings.Fields(line[i+1:])
+	// fields[0] is state (field 3), so utime (14) and stime (15) are 11 and 12.
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("short stat")
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	return utime + stime, nil
+}
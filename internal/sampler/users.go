@@ -0,0 +1,59 @@
+package sampler
+
+import (
+	"os/user"
+	"sort"
+	"strconv"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+)
+
+// procUID returns the effective UID of p, the owner ps and top show. It is
+// cached per PID and cleared together with the cgroup cache, since reading
+// it costs a /proc/<pid>/status parse.
+func (s *Sampler) procUID(p Proc) (int, bool) {
+	pid := p.PID()
+	if uid, ok := s.uidCache[pid]; ok {
+		return uid, true
+	}
+	uids, err := p.Uids()
+	if err != nil || len(uids) < 2 {
+		return 0, false
+	}
+	uid := int(uids[1])
+	s.uidCache[pid] = uid
+	return uid, true
+}
+
+// userName resolves uid to its login name, falling back to the number for
+// users without a passwd entry (e.g. container UIDs). Names are cached for
+// the life of the sampler.
+func (s *Sampler) userName(uid int) string {
+	if name, ok := s.userNames[uid]; ok {
+		return name
+	}
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	s.userNames[uid] = name
+	return name
+}
+
+// userUsages turns per-UID totals into a list ordered like the cgroups:
+// busiest CPU first, ties by UID.
+func (s *Sampler) userUsages(byUID map[int]*model.UserUsage) []model.UserUsage {
+	out := make([]model.UserUsage, 0, len(byUID))
+	for uid, u := range byUID {
+		u.UID = uid
+		u.User = s.userName(uid)
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CPU != out[j].CPU {
+			return out[i].CPU > out[j].CPU
+		}
+		return out[i].UID < out[j].UID
+	})
+	return limit(out, share(s.cfg.Top, 4))
+}
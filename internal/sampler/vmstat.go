@@ -0,0 +1,60 @@
+package sampler
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// swapCounters are the cumulative pages swapped in/out since boot.
+type swapCounters struct {
+	in, out uint64
+	ok      bool
+}
+
+// readSwapCounters reads pswpin/pswpout from /proc/vmstat.
+func readSwapCounters() swapCounters {
+	f, err := os.Open("/proc/vmstat")
+	if err != nil {
+		return swapCounters{}
+	}
+	defer f.Close()
+	return parseSwapCounters(bufio.NewScanner(f))
+}
+
+func parseSwapCounters(sc *bufio.Scanner) swapCounters {
+	var c swapCounters
+	var seen int
+	for sc.Scan() && seen < 2 {
+		k, v, ok := strings.Cut(sc.Text(), " ")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "pswpin":
+			c.in, _ = strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			seen++
+		case "pswpout":
+			c.out, _ = strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			seen++
+		}
+	}
+	c.ok = seen == 2
+	return c
+}
+
+// swapRates turns two snapshots into pages/sec. A counter that went
+// backwards (or a missing snapshot) yields zero, like the disk/net deltas.
+func swapRates(cur, prev swapCounters, dur float64) (in, out float64) {
+	if !cur.ok || !prev.ok || dur <= 0 {
+		return 0, 0
+	}
+	if cur.in >= prev.in {
+		in = float64(cur.in-prev.in) / dur
+	}
+	if cur.out >= prev.out {
+		out = float64(cur.out-prev.out) / dur
+	}
+	return in, out
+}
@@ -0,0 +1,45 @@
+package sampler
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// vmstat returns a /proc/vmstat excerpt with the given swap counters.
+func vmstat(in, out string) *bufio.Scanner {
+	return bufio.NewScanner(strings.NewReader("nr_free_pages 812345\nnr_zone_inactive_anon 1024\n" +
+		"pgpgin 9912345\npgpgout 12345678\npswpin " + in + "\npswpout " + out + "\npgalloc_dma 0\n"))
+}
+
+func TestParseSwapCounters(t *testing.T) {
+	if got, want := parseSwapCounters(vmstat("1200", "3400")), (swapCounters{in: 1200, out: 3400, ok: true}); got != want {
+		t.Errorf("parseSwapCounters = %+v, want %+v", got, want)
+	}
+	// Without swap support the counters are missing altogether.
+	if got := parseSwapCounters(bufio.NewScanner(strings.NewReader("nr_free_pages 1\npgpgin 2\n"))); got.ok {
+		t.Errorf("no pswpin/pswpout: %+v, want not ok", got)
+	}
+}
+
+func TestSwapRates(t *testing.T) {
+	prev := parseSwapCounters(vmstat("1000", "5000"))
+	tests := []struct {
+		name            string
+		cur, prev       swapCounters
+		dur             float64
+		wantIn, wantOut float64
+	}{
+		{"swapping", parseSwapCounters(vmstat("1500", "7000")), prev, 2, 250, 1000},
+		{"idle", prev, prev, 2, 0, 0},
+		{"counter reset", parseSwapCounters(vmstat("10", "7000")), prev, 2, 0, 1000},
+		{"no previous snapshot", prev, swapCounters{}, 2, 0, 0},
+		{"no elapsed time", parseSwapCounters(vmstat("1500", "7000")), prev, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		in, out := swapRates(tt.cur, tt.prev, tt.dur)
+		if in != tt.wantIn || out != tt.wantOut {
+			t.Errorf("%s: in %v, out %v pages/s; want %v, %v", tt.name, in, out, tt.wantIn, tt.wantOut)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package ui
+
+import (
+	"errors"
+	"syscall"
+)
+
+// signalProcess fails: sending signals is only implemented on Unix.
+func signalProcess(pid int, sig syscall.Signal) error { return errors.ErrUnsupported }
@@ -0,0 +1,203 @@
+package ui
+
+import "github.com/charmbracelet/lipgloss"
+
+// Theme is a TUI palette (-theme). Colors are lipgloss hex strings; ""
+// leaves the terminal's own color.
+type Theme struct {
+	Primary   string // titles, labels, gauges
+	Secondary string // active tab, niced rows
+	Success   string // below -warn-pct
+	Warning   string // between -warn-pct and -crit-pct
+	Critical  string // above -crit-pct, alerts
+	Cool      string // temperatures well below -temp-crit
+	Warm      string
+	Hot       string // within 15°C of -temp-crit
+	Accent    string // badges, help sections
+	Border    string
+	Label     string // subdued text
+	Text      string // values and body text
+	Muted     string // help text, secondary rows
+	Dim       string
+	Track     string // empty part of gauges, inactive tabs
+	OnColor   string // text drawn on a colored background
+	Memory    string // memory sparkline
+	NetTX     string // network transmit sparkline
+	Shadow    string // modal backdrop
+}
+
+// themes are the palettes -theme can select. mono has no colors at all;
+// RunTUI also drops the color profile for it, so emphasis comes from bold
+// and reverse video alone.
+var themes = map[string]Theme{
+	"dark": {
+		Primary:   "#00D7FF", // Cyan
+		Secondary: "#FF005F", // Pink/Red
+		Success:   "#00FF87", // Green
+		Warning:   "#FFD700", // Gold
+		Critical:  "#FF0000", // Red for critical alerts
+		Cool:      "#00BFFF", // Deep sky blue for cool temps
+		Warm:      "#FFA500", // Orange for warm temps
+		Hot:       "#FF4500", // OrangeRed for hot temps
+		Accent:    "#9D4EDD", // Purple accent
+		Border:    "#444444", // Dark Grey
+		Label:     "#888888", // Light Grey
+		Text:      "#FFFFFF",
+		Muted:     "#CCCCCC",
+		Dim:       "#666666",
+		Track:     "#333333",
+		OnColor:   "#FFFFFF",
+		Memory:    "#BD93F9",
+		NetTX:     "#0077FF",
+		Shadow:    "#111111",
+	},
+	"light": {
+		Primary:   "#005F87",
+		Secondary: "#D7005F",
+		Success:   "#008700",
+		Warning:   "#AF8700",
+		Critical:  "#D70000",
+		Cool:      "#0087AF",
+		Warm:      "#D78700",
+		Hot:       "#D75F00",
+		Accent:    "#8700AF",
+		Border:    "#BCBCBC",
+		Label:     "#6C6C6C",
+		Text:      "#1C1C1C",
+		Muted:     "#4E4E4E",
+		Dim:       "#8A8A8A",
+		Track:     "#D0D0D0",
+		OnColor:   "#FFFFFF",
+		Memory:    "#8700D7",
+		NetTX:     "#005FD7",
+		Shadow:    "#EEEEEE",
+	},
+	"mono": {},
+}
+
+// Palette colors, set by applyTheme.
+var (
+	primaryColor   string
+	secondaryColor string
+	successColor   string
+	warningColor   string
+	borderColor    string
+	labelColor     string
+	criticalColor  string
+	coolColor      string
+	warmColor      string
+	hotColor       string
+	accentColor    string
+	textColor      string
+	mutedColor     string
+	dimColor       string
+	trackColor     string
+	onColor        string
+	memoryColor    string
+	netTxColor     string
+	shadowColor    string
+)
+
+// Styles, rebuilt from the palette by applyTheme.
+var (
+	titleStyle       lipgloss.Style
+	subtleStyle      lipgloss.Style
+	labelStyle       lipgloss.Style
+	headerStyle      lipgloss.Style
+	cardStyle        lipgloss.Style
+	focusedCardStyle lipgloss.Style // for the focused panel
+	alertCardStyle   lipgloss.Style
+	gaugeLabelStyle  lipgloss.Style
+	valStyle         lipgloss.Style
+	criticalStyle    lipgloss.Style
+	pulseStyle       lipgloss.Style // attention-grabbing alerts (used with tickCount animation)
+	tableHeaderStyle lipgloss.Style
+	badgeStyle       lipgloss.Style // counts and status indicators
+	miniGaugeStyle   lipgloss.Style // container for inline gauges
+	rowStyle         lipgloss.Style
+	dimStyle         lipgloss.Style
+)
+
+func init() { applyTheme(themes["dark"]) }
+
+// applyTheme makes t the palette of every style. It is not safe to call
+// while a View is rendering.
+func applyTheme(t Theme) {
+	primaryColor, secondaryColor = t.Primary, t.Secondary
+	successColor, warningColor, criticalColor = t.Success, t.Warning, t.Critical
+	coolColor, warmColor, hotColor = t.Cool, t.Warm, t.Hot
+	accentColor, borderColor, labelColor = t.Accent, t.Border, t.Label
+	textColor, mutedColor, dimColor, trackColor = t.Text, t.Muted, t.Dim, t.Track
+	onColor, memoryColor, netTxColor, shadowColor = t.OnColor, t.Memory, t.NetTX, t.Shadow
+
+	// Text Styles
+	titleStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(onColor)).
+		Background(lipgloss.Color(primaryColor)).
+		Padding(0, 1).
+		Bold(true)
+
+	subtleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(labelColor))
+
+	labelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(primaryColor)).Bold(true)
+
+	headerStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, true, false).
+		BorderForeground(lipgloss.Color(borderColor)).
+		MarginBottom(1)
+
+	// Container Styles
+	cardStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(borderColor)).
+		Padding(0, 1).
+		MarginRight(1).
+		MarginBottom(0)
+
+	focusedCardStyle = lipgloss.NewStyle().
+		Border(lipgloss.DoubleBorder()).
+		BorderForeground(lipgloss.Color(primaryColor)).
+		Padding(0, 1).
+		MarginRight(1).
+		MarginBottom(0)
+
+	alertCardStyle = lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(lipgloss.Color(criticalColor)).
+		Padding(0, 1).
+		MarginRight(1).
+		MarginBottom(0)
+
+	// Metrics Styles
+	gaugeLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(primaryColor)).Bold(true)
+	valStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textColor)).Bold(true)
+
+	// Alert/critical styles
+	criticalStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(criticalColor)).
+		Bold(true)
+
+	pulseStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(onColor)).
+		Background(lipgloss.Color(criticalColor)).
+		Bold(true).
+		Padding(0, 1)
+
+	tableHeaderStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(primaryColor)).
+		Bold(true).
+		Underline(true)
+
+	badgeStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(onColor)).
+		Background(lipgloss.Color(accentColor)).
+		Padding(0, 1).
+		Bold(true)
+
+	miniGaugeStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(labelColor))
+
+	// Table Styles
+	rowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textColor))
+	dimStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(dimColor))
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/sampler"
+)
+
+// treeNode is a process with its listed children. proc carries the totals
+// of the whole subtree; size counts the descendants.
+type treeNode struct {
+	proc model.Process
+	kids []*treeNode
+	size int
+}
+
+// treeRows arranges procs as a process tree for the tree view (T). A
+// process whose parent is also in procs is nested under it, and each row's
+// CPU, memory and I/O include all of its listed descendants, so siblings
+// are ranked by what their whole subtree uses. The children of a PID in
+// collapsed are hidden and its row is marked with their count. Rows keep
+// their PIDs, so selection, details and signals act on the process itself.
+func treeRows(procs []model.Process, key, key2 string, collapsed map[int]bool) []model.Process {
+	nodes := make(map[int]*treeNode, len(procs))
+	for _, p := range procs {
+		nodes[p.PID] = &treeNode{proc: p}
+	}
+	var roots []*treeNode
+	for _, p := range procs {
+		n := nodes[p.PID]
+		if parent, ok := nodes[p.PPID]; ok && p.PPID != p.PID {
+			parent.kids = append(parent.kids, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	for _, n := range roots {
+		n.total()
+	}
+	var out []model.Process
+	var walk func(level []*treeNode, depth int)
+	walk = func(level []*treeNode, depth int) {
+		for _, n := range sortNodes(level, key, key2) {
+			p := n.proc
+			folded := len(n.kids) > 0 && collapsed[p.PID]
+			p.Command = strings.Repeat("  ", depth) + treeMarker(n, folded) + p.Command
+			out = append(out, p)
+			if !folded {
+				walk(n.kids, depth+1)
+			}
+		}
+	}
+	walk(roots, 0)
+	return out
+}
+
+// total adds the subtree's usage into n.proc and returns n.
+func (n *treeNode) total() *treeNode {
+	for _, k := range n.kids {
+		k.total()
+		n.proc.CPU += k.proc.CPU
+		n.proc.Memory += k.proc.Memory
+		n.proc.ReadKBs += k.proc.ReadKBs
+		n.proc.WriteKBs += k.proc.WriteKBs
+		n.size += 1 + k.size
+	}
+	return n
+}
+
+// sortNodes orders siblings the way the flat list is sorted.
+func sortNodes(level []*treeNode, key, key2 string) []*treeNode {
+	procs := make([]model.Process, len(level))
+	byPID := make(map[int]*treeNode, len(level))
+	for i, n := range level {
+		procs[i] = n.proc
+		byPID[n.proc.PID] = n
+	}
+	sampler.SortProcesses(procs, key, key2)
+	out := make([]*treeNode, len(procs))
+	for i, p := range procs {
+		out[i] = byPID[p.PID]
+	}
+	return out
+}
+
+// treeMarker is "▾ " for an open parent, "▸ +N " for a folded one hiding N
+// descendants and "· " for a leaf.
+func treeMarker(n *treeNode, folded bool) string {
+	switch {
+	case folded:
+		return fmt.Sprintf("▸ +%d ", n.size)
+	case len(n.kids) > 0:
+		return "▾ "
+	}
+	return "· "
+}
@@ -3,116 +3,32 @@ package ui
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/alert"
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/model"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/output"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/protect"
 	"github.com/Dicklesworthstone/system_resource_protection_script/internal/sampler"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/watch"
 )
 
-const (
-	historyPoints  = 60
-	primaryColor   = "#00D7FF" // Cyan
-	secondaryColor = "#FF005F" // Pink/Red
-	successColor   = "#00FF87" // Green
-	warningColor   = "#FFD700" // Gold
-	borderColor    = "#444444" // Dark Grey
-	labelColor     = "#888888" // Light Grey
-	criticalColor  = "#FF0000" // Red for critical alerts
-	coolColor      = "#00BFFF" // Deep sky blue for cool temps
-	warmColor      = "#FFA500" // Orange for warm temps
-	hotColor       = "#FF4500" // OrangeRed for hot temps
-	accentColor    = "#9D4EDD" // Purple accent
-	bgDimColor     = "#1a1a1a" // Subtle background
-)
-
-// Styles
-var (
-	// Text Styles
-	titleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(lipgloss.Color(primaryColor)).
-			Padding(0, 1).
-			Bold(true)
-
-	subtleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(labelColor))
-
-	labelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(primaryColor)).Bold(true)
-
-	headerStyle = lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder(), false, false, true, false).
-			BorderForeground(lipgloss.Color(borderColor)).
-			MarginBottom(1)
-
-	// Container Styles
-	cardStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(borderColor)).
-			Padding(0, 1).
-			MarginRight(1).
-			MarginBottom(0)
-
-	// Enhanced card styles - focusedCardStyle available for future panel focus feature
-	focusedCardStyle = lipgloss.NewStyle().
-				Border(lipgloss.DoubleBorder()).
-				BorderForeground(lipgloss.Color(primaryColor)).
-				Padding(0, 1).
-				MarginRight(1).
-				MarginBottom(0)
-
-	alertCardStyle = lipgloss.NewStyle().
-			Border(lipgloss.ThickBorder()).
-			BorderForeground(lipgloss.Color(criticalColor)).
-			Padding(0, 1).
-			MarginRight(1).
-			MarginBottom(0)
-
-	// Metrics Styles
-	gaugeLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(primaryColor)).Bold(true)
-	valStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
-
-	// Alert/critical styles
-	criticalStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(criticalColor)).
-			Bold(true)
-
-	// Pulsing style for attention-grabbing alerts (used with tickCount animation)
-	pulseStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(lipgloss.Color(criticalColor)).
-			Bold(true).
-			Padding(0, 1)
-
-	// Table header style for consistent table headers
-	tableHeaderStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color(primaryColor)).
-				Bold(true).
-				Underline(true)
-
-	// Badge style for counts and status indicators
-	badgeStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(lipgloss.Color(accentColor)).
-			Padding(0, 1).
-			Bold(true)
-
-	// Mini gauge base style (used as container for inline gauges)
-	miniGaugeStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color(labelColor))
-
-	// Table Styles
-	rowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#EEEEEE"))
-	dimStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
-)
-
-// Model renders live samples from the sampler.
+// Model renders live samples from the sampler, or a -replay capture.
 type Model struct {
 	cfg       config.Config
 	latest    model.Sample
@@ -124,9 +40,14 @@ type Model struct {
 
 	sortKey   string
 	filter    string
+	filterRe  *regexp.Regexp // filter, or inputBuf while typing; nil = no filtering
+	filterErr error          // inputBuf doesn't compile yet
 	inputMode bool
 	inputBuf  []rune
 
+	treeView  bool         // group processes under their parents
+	collapsed map[int]bool // tree view: PIDs whose children are folded
+
 	// History for sparklines
 	cpuHist       []float64
 	memHist       []float64
@@ -143,6 +64,7 @@ type Model struct {
 	activeTab     int // 0=Dashboard, 1=Analysis, 2=System Info
 	showHelp      bool
 	paused        bool
+	pending       *model.Sample // newest sample taken while paused
 	showIOPanels  bool
 	showGPU       bool
 	showBatt      bool
@@ -150,6 +72,7 @@ type Model struct {
 	showInotify   bool
 	showCgroups   bool
 	statusMsg     string
+	replay        bool // samples come from a -replay capture, not this host
 
 	// Mouse support
 	mouseEnabled bool
@@ -160,7 +83,13 @@ type Model struct {
 	showProcDetail bool
 	detailPID      int
 
+	confirmKill *killPrompt // pending k/K, sent once answered with y
+
 	// Alert tracking
+	alertEval    *alert.Evaluator   // -alert rules; nil without any
+	alertHook    *alert.Hook        // -on-alert; nil without one
+	protector    *protect.Protector // -protect-cpu; nil when off
+	capper       *protect.Capper    // -cap-cgroup; nil when off
 	alertCount   int
 	criticalCPU  bool
 	criticalMem  bool
@@ -173,16 +102,33 @@ type Model struct {
 	jsonFile string
 }
 
-func New(cfg config.Config) *Model {
+// New returns a Model showing samples from src. With -replay set, src is
+// taken to be a capture: nothing the UI does acts on the live system.
+func New(cfg config.Config, src model.SampleSource) *Model {
+	if t, ok := themes[cfg.Theme]; ok {
+		applyTheme(t)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
-	s := sampler.New(cfg.Interval)
+	sortKey := cfg.Sort
+	switch sortKey {
+	case "cpu", "mem", "io", "fd", "peak":
+	default:
+		sortKey = "cpu"
+	}
+	// Set up exactly as the streaming modes do; see watch.New for -replay.
+	ws := watch.New(cfg)
 	return &Model{
 		cfg:           cfg,
-		stream:        s.Stream(ctx),
+		protector:     ws.Protector,
+		capper:        ws.Capper,
+		alertEval:     ws.Alerts,
+		alertHook:     ws.Hook,
+		stream:        src.Stream(ctx),
+		replay:        cfg.Replay != "",
 		ctxCancel:     cancel,
 		width:         120,
 		height:        40,
-		sortKey:       "cpu",
+		sortKey:       sortKey,
 		filter:        "",
 		perCoreHist:   make(map[int][]float64),
 		cumulativeCPU: make(map[string]float64),
@@ -195,6 +141,8 @@ func New(cfg config.Config) *Model {
 		showCgroups:   false,
 		mouseEnabled:  true,
 		selectedProc:  -1,
+		treeView:      cfg.Tree,
+		collapsed:     make(map[int]bool),
 		focusedPanel:  0,
 		jsonFile: func() string {
 			return os.Getenv("SRPS_SYSMONI_JSON_FILE")
@@ -202,6 +150,14 @@ func New(cfg config.Config) *Model {
 	}
 }
 
+// killPrompt is a signal waiting for confirmation.
+type killPrompt struct {
+	pid     int
+	command string
+	sig     syscall.Signal
+	name    string // SIGTERM or SIGKILL
+}
+
 // Messages
 type tickMsg struct{}
 
@@ -251,29 +207,45 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		if m.inputMode {
+			// The list is filtered live while typing; Enter keeps the
+			// filter, Esc drops it.
 			switch msg.Type {
 			case tea.KeyEnter:
+				if m.filterErr != nil {
+					m.statusMsg = fmt.Sprintf("Invalid filter: %v", m.filterErr)
+					return m, nil
+				}
 				m.filter = strings.TrimSpace(string(m.inputBuf))
 				m.inputMode = false
 				m.inputBuf = nil
-				m.topOffset = 0
-				m.selectedProc = -1 // Reset selection when filter changes
 				return m, nil
 			case tea.KeyEsc:
 				m.inputMode = false
 				m.inputBuf = nil
+				m.filter = ""
+				m.setFilter("")
 				return m, nil
 			case tea.KeyBackspace:
 				if len(m.inputBuf) > 0 {
 					m.inputBuf = m.inputBuf[:len(m.inputBuf)-1]
 				}
-				return m, nil
 			default:
-				if msg.Runes != nil {
-					m.inputBuf = append(m.inputBuf, msg.Runes...)
+				if msg.Runes == nil {
+					return m, nil
 				}
-				return m, nil
+				m.inputBuf = append(m.inputBuf, msg.Runes...)
 			}
+			m.setFilter(strings.TrimSpace(string(m.inputBuf)))
+			return m, nil
+		}
+		if k := m.confirmKill; k != nil {
+			m.confirmKill = nil
+			if msg.String() == "y" || msg.String() == "Y" {
+				m.statusMsg = m.kill(k)
+			} else {
+				m.statusMsg = "Kill cancelled"
+			}
+			return m, nil
 		}
 		switch msg.String() {
 		case "q", "ctrl+c":
@@ -282,7 +254,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			if m.filter != "" {
 				m.filter = ""
-				m.topOffset = 0
+				m.setFilter("")
 				m.statusMsg = "Filter cleared"
 			} else if m.selectedProc >= 0 {
 				m.selectedProc = -1
@@ -297,23 +269,40 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.showHelp = !m.showHelp
 		case "s":
 			if m.sortKey == "cpu" {
-				m.sortKey = "mem"
+				m.setSort("mem")
 			} else if m.sortKey == "mem" {
-				m.sortKey = "io"
+				m.setSort("io")
 			} else if m.sortKey == "io" {
-				m.sortKey = "fd"
+				m.setSort("fd")
+			} else if m.sortKey == "fd" {
+				m.setSort("peak")
 			} else {
-				m.sortKey = "cpu"
+				m.setSort("cpu")
 			}
+		case "c":
+			m.setSort("cpu")
+		case "m":
+			m.setSort("mem")
+		case "i":
+			m.setSort("io")
+		case "T":
+			m.treeView = !m.treeView
 			m.topOffset = 0
-			m.statusMsg = fmt.Sprintf("Sort: %s", strings.ToUpper(m.sortKey))
+			m.selectedProc = -1
+			m.statusMsg = fmt.Sprintf("Process tree %s", onOff(m.treeView))
+		case "e":
+			m.toggleCollapsed()
+		case "k":
+			m.requestKill(syscall.SIGTERM, "SIGTERM")
+		case "K":
+			m.requestKill(syscall.SIGKILL, "SIGKILL")
 		case "g":
 			m.showGPU = !m.showGPU
 			m.statusMsg = fmt.Sprintf("GPU panels %s", onOff(m.showGPU))
 		case "b":
 			m.showBatt = !m.showBatt
 			m.statusMsg = fmt.Sprintf("Battery panel %s", onOff(m.showBatt))
-		case "i":
+		case "d":
 			m.showIOPanels = !m.showIOPanels
 			m.statusMsg = fmt.Sprintf("IO/FD panels %s", onOff(m.showIOPanels))
 		case "t":
@@ -322,14 +311,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "n":
 			m.showInotify = !m.showInotify
 			m.statusMsg = fmt.Sprintf("Inotify panel %s", onOff(m.showInotify))
-		case "c":
+		case "C":
 			m.showCgroups = !m.showCgroups
 			m.statusMsg = fmt.Sprintf("Cgroups panel %s", onOff(m.showCgroups))
-		case "m":
+		case "M":
 			m.mouseEnabled = !m.mouseEnabled
 			m.statusMsg = fmt.Sprintf("Mouse %s", onOff(m.mouseEnabled))
-		case "f":
+		case " ", "f":
 			m.paused = !m.paused
+			if !m.paused && m.pending != nil {
+				m.show(*m.pending)
+				m.pending = nil
+			}
 			m.statusMsg = fmt.Sprintf("Updates %s", onOff(!m.paused))
 		case "I":
 			if len(m.latest.Top) > 0 {
@@ -340,7 +333,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "/":
 			m.inputMode = true
-			m.inputBuf = nil
+			m.inputBuf = []rune(m.filter)
 			m.topOffset = 0
 		case "o":
 			if m.jsonFile != "" {
@@ -350,6 +343,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.jsonFile = f
 				m.statusMsg = fmt.Sprintf("JSON output: %s", f)
 			}
+		case "r":
+			if path, err := m.writeReport(); err != nil {
+				m.statusMsg = fmt.Sprintf("Report failed: %v", err)
+			} else {
+				m.statusMsg = fmt.Sprintf("Report saved: %s", path)
+			}
 		case "enter":
 			// Show process detail modal for selected process
 			if m.selectedProc >= 0 {
@@ -377,7 +376,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.bumpTopOffset(1)
 			}
-		case "up", "k":
+		case "up":
 			if m.selectedProc >= 0 {
 				if m.selectedProc > 0 {
 					m.selectedProc--
@@ -391,7 +390,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "pgdown", "J":
 			m.bumpTopOffset(m.visibleTopPage())
-		case "pgup", "K":
+		case "pgup":
 			m.bumpTopOffset(-m.visibleTopPage())
 		case "end":
 			m.jumpTopEnd()
@@ -406,18 +405,24 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case tickMsg:
 		m.tickCount++
-		if m.paused {
-			return m, tickCmd()
-		}
 		select {
 		case samp, ok := <-m.stream:
 			if ok {
-				m.latest = samp
-				m.recordHistory(samp)
-				m.updateStats(samp)
+				// Alerts, protection and the JSON file keep running while
+				// paused; only the screen holds still.
+				if m.paused {
+					m.pending = &samp
+				} else {
+					m.show(samp)
+				}
 				m.updateAlerts(samp)
+				m.protect(samp)
 				m.maybeWriteJSON(samp)
-				m.clampTopOffset()
+			} else {
+				m.stream = nil // stop polling a closed channel
+				if m.replay {
+					m.statusMsg = "End of replay"
+				}
 			}
 		default:
 		}
@@ -426,16 +431,142 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// updateAlerts checks for critical conditions and updates alert state
+// show puts s on screen.
+func (m *Model) show(s model.Sample) {
+	m.latest = s
+	m.recordHistory(s)
+	m.updateStats(s)
+	m.clampTopOffset()
+}
+
+// toggleCollapsed folds or unfolds the children of the selected process
+// (or of the first visible row) in the tree view.
+func (m *Model) toggleCollapsed() {
+	if !m.treeView {
+		m.statusMsg = "Not in tree view (T)"
+		return
+	}
+	procs := m.sortAndFilter(m.latest.Top)
+	i := m.selectedProc
+	if i < 0 || i >= len(procs) {
+		i = m.topOffset
+	}
+	if i >= len(procs) {
+		return
+	}
+	pid := procs[i].PID
+	if m.collapsed[pid] {
+		delete(m.collapsed, pid)
+		m.statusMsg = fmt.Sprintf("Unfolded PID %d", pid)
+	} else {
+		m.collapsed[pid] = true
+		m.statusMsg = fmt.Sprintf("Folded PID %d", pid)
+	}
+}
+
+// setSort switches the process table to key; the table is re-sorted on the
+// next render, so the change shows without waiting for a sample.
+func (m *Model) setSort(key string) {
+	m.sortKey = key
+	m.topOffset = 0
+	m.statusMsg = fmt.Sprintf("Sort: %s", strings.ToUpper(key))
+}
+
+// requestKill asks to confirm sending sig to the selected process. Without a
+// selection it selects the first visible row instead, so the target can be
+// chosen from the keyboard before anything is sent. init and sysmoni itself
+// are refused.
+func (m *Model) requestKill(sig syscall.Signal, name string) {
+	if m.replay {
+		m.statusMsg = "Signals are disabled while replaying a capture"
+		return
+	}
+	procs := m.sortAndFilter(m.latest.Top)
+	if len(procs) == 0 {
+		m.statusMsg = "No process to signal"
+		return
+	}
+	if m.selectedProc < 0 || m.selectedProc >= len(procs) {
+		m.selectedProc = min(m.topOffset, len(procs)-1)
+		p := procs[m.selectedProc]
+		key := "k"
+		if sig == syscall.SIGKILL {
+			key = "K"
+		}
+		m.statusMsg = fmt.Sprintf("Selected: %s (PID %d), ↑/↓ to move, %s again to send %s",
+			truncate(p.Command, 20), p.PID, key, name)
+		return
+	}
+	p := procs[m.selectedProc]
+	if p.PID <= 1 || p.PID == os.Getpid() {
+		m.statusMsg = fmt.Sprintf("Refusing to signal PID %d (%s)", p.PID, truncate(p.Command, 20))
+		return
+	}
+	m.confirmKill = &killPrompt{pid: p.PID, command: p.Command, sig: sig, name: name}
+	m.statusMsg = fmt.Sprintf("Send %s to %s (PID %d)? y/N", name, truncate(p.Command, 20), p.PID)
+}
+
+// kill sends a confirmed signal and returns the status line to show.
+func (m *Model) kill(k *killPrompt) string {
+	log := slog.With("pid", k.pid, "comm", k.command, "signal", k.name)
+	if err := signalProcess(k.pid, k.sig); err != nil {
+		log.Error("tui: signal failed", "err", err)
+		switch {
+		case errors.Is(err, syscall.EPERM):
+			return fmt.Sprintf("%s PID %d: permission denied (run as root or as the process owner)", k.name, k.pid)
+		case errors.Is(err, syscall.ESRCH):
+			return fmt.Sprintf("PID %d already exited", k.pid)
+		}
+		return fmt.Sprintf("%s PID %d failed: %v", k.name, k.pid, err)
+	}
+	log.Warn("tui: sent signal")
+	m.selectedProc = -1
+	return fmt.Sprintf("Sent %s to %s (PID %d)", k.name, truncate(k.command, 20), k.pid)
+}
+
+// protect runs the -cap-cgroup capper and the -protect-cpu protector and
+// reports what they did (their log has the details) on the status line.
+func (m *Model) protect(s model.Sample) {
+	if m.capper != nil {
+		if err := m.capper.Apply(s); err != nil {
+			m.statusMsg = fmt.Sprintf("Cap cgroup failed: %v", err)
+		}
+	}
+	if m.protector == nil {
+		return
+	}
+	decisions, err := m.protector.Apply(s)
+	switch {
+	case err != nil:
+		m.statusMsg = fmt.Sprintf("Protect failed: %v", err)
+	case len(decisions) > 0 && m.cfg.ProtectDryRun:
+		m.statusMsg = fmt.Sprintf("Protect (dry run): would renice %s (PID %d)", truncate(decisions[0].Command, 20), decisions[0].PID)
+	case len(decisions) > 0:
+		m.statusMsg = fmt.Sprintf("Protect: reniced %s (PID %d) to %d", truncate(decisions[0].Command, 20), decisions[0].PID, decisions[0].NewNice)
+	}
+}
+
+// updateAlerts checks for critical conditions and updates alert state.
+// -alert rule changes go to the log (stderr would corrupt the screen) and
+// the status line.
 func (m *Model) updateAlerts(s model.Sample) {
+	if m.alertEval != nil {
+		for _, a := range m.alertEval.Evaluate(s) {
+			slog.Warn("alert", "rule", a.Rule, "state", a.State, "value", a.Value, "pid", a.PID, "comm", a.Command)
+			m.statusMsg = fmt.Sprintf("Alert %s: %s (%.1f)", a.State, a.Rule, a.Value)
+			if m.alertHook != nil {
+				m.alertHook.Fire(context.Background(), a)
+			}
+		}
+	}
 	m.alertCount = 0
 	m.criticalCPU = s.CPU.Total > 90
 	m.criticalMem = pct(s.Memory.UsedBytes, s.Memory.TotalBytes) > 90
 	m.criticalSwap = pct(s.Memory.SwapUsed, s.Memory.SwapTotal) > 80
 	m.criticalTemp = false
 
-	for _, t := range s.Temps {
-		if t.Temp > 85 {
+	for _, t := range allTemps(s) {
+		if t.Temp >= m.cfg.TempCrit {
 			m.criticalTemp = true
 			break
 		}
@@ -455,6 +586,47 @@ func (m *Model) updateAlerts(s model.Sample) {
 	}
 }
 
+// alerts describes the active critical conditions set by updateAlerts.
+func (m *Model) alerts() []string {
+	s := m.latest
+	var out []string
+	if m.criticalCPU {
+		out = append(out, fmt.Sprintf("CPU critical: %.1f%%", s.CPU.Total))
+	}
+	if m.criticalMem {
+		out = append(out, fmt.Sprintf("Memory critical: %.1f%% used", pct(s.Memory.UsedBytes, s.Memory.TotalBytes)))
+	}
+	if m.criticalSwap {
+		out = append(out, fmt.Sprintf("Swap critical: %.1f%% used", pct(s.Memory.SwapUsed, s.Memory.SwapTotal)))
+	}
+	if m.criticalTemp {
+		out = append(out, fmt.Sprintf("Temperature critical: sensor at or above %.0f°C", m.cfg.TempCrit))
+	}
+	return out
+}
+
+// writeReport saves the current view as a timestamped markdown file in
+// SRPS_SYSMONI_REPORT_DIR (default: the working directory).
+func (m *Model) writeReport() (string, error) {
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("sysmoni-report-%s.md", time.Now().Format("20060102-150405"))
+	path := filepath.Join(os.Getenv("SRPS_SYSMONI_REPORT_DIR"), name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	err = output.WriteMarkdown(f, output.Report{
+		Host:   host,
+		Sample: m.latest,
+		Procs:  m.sortAndFilter(m.latest.Top),
+		Alerts: m.alerts(),
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return path, err
+}
+
 func (m *Model) updateStats(s model.Sample) {
 	// Accumulate CPU integral (CPU% * interval_seconds)
 	// Approximate interval as 1s or use s.Interval if precise
@@ -463,16 +635,22 @@ func (m *Model) updateStats(s model.Sample) {
 	for _, p := range s.Top {
 		m.cumulativeCPU[p.Command] += p.CPU * factor
 	}
-	for _, p := range s.Throttled {
-		m.throttleCount[p.Command]++
+	// Frequent flyers count ticks a command was deprioritized either way:
+	// niced, or in a cgroup that hit its CPU quota.
+	seen := make(map[int]bool)
+	for _, p := range append(append([]model.Process(nil), s.CPUThrottled...), s.Niced...) {
+		if !seen[p.PID] {
+			seen[p.PID] = true
+			m.throttleCount[p.Command]++
+		}
 	}
 }
 
 func (m *Model) recordHistory(s model.Sample) {
 	appendHist := func(hist []float64, val float64) []float64 {
 		hist = append(hist, val)
-		if len(hist) > historyPoints {
-			hist = hist[len(hist)-historyPoints:]
+		if len(hist) > m.cfg.SparkDepth {
+			hist = hist[len(hist)-m.cfg.SparkDepth:]
 		}
 		return hist
 	}
@@ -490,8 +668,8 @@ func (m *Model) recordHistory(s model.Sample) {
 	for i, v := range s.CPU.PerCore {
 		buf := m.perCoreHist[i]
 		buf = append(buf, v)
-		if len(buf) > historyPoints {
-			buf = buf[len(buf)-historyPoints:]
+		if len(buf) > m.cfg.SparkDepth {
+			buf = buf[len(buf)-m.cfg.SparkDepth:]
 		}
 		m.perCoreHist[i] = buf
 	}
@@ -520,13 +698,13 @@ func (m *Model) View() string {
 
 	// Tab Styles with glow effect for active
 	activeTabStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFFFFF")).
+		Foreground(lipgloss.Color(onColor)).
 		Background(lipgloss.Color(secondaryColor)).
 		Padding(0, 1).
 		Bold(true)
 	inactiveTabStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#888888")).
-		Background(lipgloss.Color("#333333")).
+		Foreground(lipgloss.Color(labelColor)).
+		Background(lipgloss.Color(trackColor)).
 		Padding(0, 1)
 
 	tabs := []string{" 1:Dashboard ", " 2:Analysis ", " 3:System "}
@@ -549,12 +727,26 @@ func (m *Model) View() string {
 		sortIcon = "▼I"
 	case "fd":
 		sortIcon = "▼F"
+	case "peak":
+		sortIcon = "▼P"
 	default:
 		sortIcon = "▼C"
 	}
-	pauseIcon := ""
+	replayBadge := ""
+	if m.replay {
+		replayBadge = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#000000")).
+			Background(lipgloss.Color(accentColor)).
+			Bold(true).
+			Render(fmt.Sprintf(" ▶ REPLAY %gx ", m.cfg.ReplaySpeed))
+	}
+	pausedBadge := ""
 	if m.paused {
-		pauseIcon = " ⏸"
+		pausedBadge = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#000000")).
+			Background(lipgloss.Color(warningColor)).
+			Bold(true).
+			Render(" ⏸ PAUSED ")
 	}
 
 	// Alert badge using pulseStyle with animation
@@ -568,12 +760,30 @@ func (m *Model) View() string {
 		alertBadge = alertStyleLocal.Render(fmt.Sprintf("⚠ %d", m.alertCount))
 	}
 
-	info := subtleStyle.Render(fmt.Sprintf("%s%s%s%s", sortIcon, strings.ToUpper(m.sortKey), pauseIcon, filterTxt))
+	// Failing readers (see the System tab) mean some numbers are missing,
+	// not zero.
+	errBadge := ""
+	if n := len(s.Errors); n > 0 {
+		errBadge = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(warningColor)).
+			Bold(true).
+			Render(fmt.Sprintf("✗ %d", n))
+	}
+
+	treeTxt := ""
+	if m.treeView {
+		treeTxt = " tree"
+	}
+	intervalTxt := ""
+	if m.cfg.Adaptive {
+		intervalTxt = " ⟳" + s.Interval.String()
+	}
+	info := subtleStyle.Render(fmt.Sprintf("%s%s%s%s%s", sortIcon, strings.ToUpper(m.sortKey), treeTxt, intervalTxt, filterTxt))
 	timestamp := subtleStyle.Render(s.Timestamp.Format("15:04:05"))
 
 	// Build header with proper spacing
 	leftPart := tabBar
-	rightPart := lipgloss.JoinHorizontal(lipgloss.Center, alertBadge, " ", info, " ", timestamp)
+	rightPart := lipgloss.JoinHorizontal(lipgloss.Center, replayBadge, pausedBadge, " ", errBadge, " ", alertBadge, " ", info, " ", timestamp)
 
 	gap := m.width - lipgloss.Width(leftPart) - lipgloss.Width(rightPart) - 2
 	if gap < 1 {
@@ -599,8 +809,8 @@ func (m *Model) View() string {
 	}
 
 	// Enhanced footer with keyboard hints and status
-	footerLeft := subtleStyle.Render("tab/1-3:view  s:sort  /:filter  ?:help")
-	toggles := fmt.Sprintf("g:%s i:%s t:%s b:%s",
+	footerLeft := subtleStyle.Render("tab/1-3:view  c/m/i:sort  k:kill  /:filter  ?:help")
+	toggles := fmt.Sprintf("g:%s d:%s t:%s b:%s",
 		onOffIcon(m.showGPU), onOffIcon(m.showIOPanels), onOffIcon(m.showTemps), onOffIcon(m.showBatt))
 	footerMid := subtleStyle.Render(toggles)
 	footerRight := ""
@@ -623,6 +833,52 @@ func (m *Model) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, header, content, footer)
 }
 
+// procStarted shows a process's start time and age, e.g.
+// "10-16 09:12:03 (up 6h05m)".
+func procStarted(p *model.Process) string {
+	if p.StartTime.IsZero() {
+		return "unknown"
+	}
+	up := p.Uptime.Round(time.Second)
+	age := up.String()
+	if up >= time.Hour {
+		age = fmt.Sprintf("%dh%02dm", int(up.Hours()), int(up.Minutes())%60)
+	}
+	if up >= 48*time.Hour {
+		age = fmt.Sprintf("%dd%02dh", int(up.Hours())/24, int(up.Hours())%24)
+	}
+	return fmt.Sprintf("%s (up %s)", p.StartTime.Format("01-02 15:04:05"), age)
+}
+
+// procState spells out the ps state letter, highlighting uninterruptible sleep.
+func procState(st string) string {
+	switch st {
+	case "R":
+		return "R (running)"
+	case "S":
+		return "S (sleeping)"
+	case "D":
+		return criticalStyle.Render("D (uninterruptible)")
+	case "Z":
+		return criticalStyle.Render("Z (zombie)")
+	case "T", "t":
+		return st + " (stopped)"
+	case "I":
+		return "I (idle)"
+	}
+	return st
+}
+
+// staleMark flags an async section whose data is older than expected.
+func staleMark(s model.Sample, section string) string {
+	for _, sec := range s.Sections {
+		if sec.Name == section && sec.Stale {
+			return subtleStyle.Render(" (stale)")
+		}
+	}
+	return ""
+}
+
 // onOffIcon returns a visual indicator for on/off state
 func onOffIcon(v bool) string {
 	if v {
@@ -634,14 +890,22 @@ func onOffIcon(v bool) string {
 func (m *Model) renderDashboard(s model.Sample) string {
 	// --- Row 1: Vitals (CPU, MEM, SWAP, LOAD) ---
 	// CPU Section with gradient gauge
-	cpuGauge := renderGauge("CPU", s.CPU.Total) // Use convenient wrapper
-	cpuGraph := renderSparklinePct(m.cpuHist, 20, primaryColor)
+	cpuGauge := renderGaugeEnhanced("CPU", s.CPU.Total, m.level(s.CPU.Total), false)
+	cpuGraph := renderSparklinePct(m.cpuHist, m.sparkWidth(20), primaryColor)
 	// Add pulsing critical badge when CPU is over 90%
 	cpuAlert := ""
 	if m.criticalCPU && m.tickCount%4 < 2 {
 		cpuAlert = " " + pulseStyle.Render("CRITICAL")
 	}
 	cpuBlock := lipgloss.JoinHorizontal(lipgloss.Bottom, cpuGauge, "  ", cpuGraph, cpuAlert)
+	if s.CPU.Iowait >= 0.5 || s.CPU.Steal >= 0.5 {
+		stealStyle := subtleStyle
+		if s.CPU.Steal >= 10 {
+			stealStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor))
+		}
+		cpuBlock = lipgloss.JoinVertical(lipgloss.Left, cpuBlock,
+			subtleStyle.Render(fmt.Sprintf("iowait %.1f%% ", s.CPU.Iowait))+stealStyle.Render(fmt.Sprintf("steal %.1f%%", s.CPU.Steal)))
+	}
 	// Use alert border if critical
 	cpuCardStyle := cardStyle
 	if m.criticalCPU {
@@ -651,14 +915,28 @@ func (m *Model) renderDashboard(s model.Sample) string {
 
 	// Memory Section with gradient gauge
 	memVal := pct(s.Memory.UsedBytes, s.Memory.TotalBytes)
-	memGauge := renderGaugeEnhanced("MEM", memVal, "#BD93F9", true) // Use gradient
-	memGraph := renderSparklinePct(m.memHist, 20, "#BD93F9")
+	memGauge := renderGaugeEnhanced("MEM", memVal, m.level(memVal), false)
+	memGraph := renderSparklinePct(m.memHist, m.sparkWidth(20), memoryColor)
 	// Add pulsing critical badge when MEM is over 90%
 	memAlert := ""
 	if m.criticalMem && m.tickCount%4 < 2 {
 		memAlert = " " + pulseStyle.Render("LOW MEM")
 	}
-	memDetails := subtleStyle.Render(fmt.Sprintf("%.1f/%.1f GB | cache %.1f GB | buf %.1f GB", bytesToGiB(s.Memory.UsedBytes), bytesToGiB(s.Memory.TotalBytes), bytesToGiB(s.Memory.Cached), bytesToGiB(s.Memory.Buffers)))
+	memDetails := subtleStyle.Render(fmt.Sprintf("%.1f/%.1f GB | avail %.1f GB | cache %.1f GB | buf %.1f GB", bytesToGiB(s.Memory.UsedBytes), bytesToGiB(s.Memory.TotalBytes), bytesToGiB(s.Memory.AvailableBytes), bytesToGiB(s.Memory.Cached), bytesToGiB(s.Memory.Buffers)))
+	if b := s.Balloon; b != nil && b.InflatedBytes > 0 {
+		// Pressure here may be the host reclaiming memory, not the guest using it.
+		memDetails += lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor)).Render(
+			fmt.Sprintf(" | balloon %.1f of %.1f GB", bytesToGiB(b.InflatedBytes), bytesToGiB(b.ConfiguredBytes)))
+	}
+	if len(s.NUMA) > 0 {
+		// A node much fuller than the others means remote allocations and
+		// possibly reclaim on that node while the total looks fine.
+		parts := make([]string, 0, len(s.NUMA))
+		for _, n := range s.NUMA {
+			parts = append(parts, fmt.Sprintf("n%d %.0f%%", n.ID, pct(n.UsedBytes, n.TotalBytes)))
+		}
+		memDetails += "\n" + subtleStyle.Render("NUMA "+strings.Join(parts, " · "))
+	}
 	memBlock := lipgloss.JoinVertical(lipgloss.Left,
 		lipgloss.JoinHorizontal(lipgloss.Bottom, memGauge, "  ", memGraph, memAlert),
 		memDetails)
@@ -687,9 +965,23 @@ func (m *Model) renderDashboard(s model.Sample) string {
 	// Use miniGaugeStyle as container for load info
 	loadMiniGauge := miniGaugeStyle.Render("LOAD: ") + loadValStyle.Render(fmt.Sprintf("%.2f", s.CPU.Load1)) +
 		subtleStyle.Render(fmt.Sprintf(" (%.0f cores) 5m %.2f 15m %.2f", float64(len(s.CPU.PerCore)), s.CPU.Load5, s.CPU.Load15))
-	miscBlock := lipgloss.JoinVertical(lipgloss.Left,
-		lipgloss.JoinHorizontal(lipgloss.Bottom, swapGauge, swapAlert),
-		loadMiniGauge)
+	if s.Memory.SwapInPerSec > 0 || s.Memory.SwapOutPerSec > 0 {
+		swapAlert += subtleStyle.Render(fmt.Sprintf(" in %.0f out %.0f pg/s", s.Memory.SwapInPerSec, s.Memory.SwapOutPerSec))
+	}
+	miscLines := []string{lipgloss.JoinHorizontal(lipgloss.Bottom, swapGauge, swapAlert), loadMiniGauge}
+	if p := s.Pressure; p.Supported {
+		// Stall share over the last 10s; memory/io "full" stalls mean nothing ran.
+		psiColor := successColor
+		if p.Memory.Full10 > 5 || p.IO.Full10 > 10 {
+			psiColor = criticalColor
+		} else if p.CPU.Some10 > 20 || p.Memory.Some10 > 10 || p.IO.Some10 > 20 {
+			psiColor = warningColor
+		}
+		miscLines = append(miscLines, miniGaugeStyle.Render("PSI: ")+
+			lipgloss.NewStyle().Foreground(lipgloss.Color(psiColor)).Render(
+				fmt.Sprintf("cpu %.1f%% mem %.1f%% io %.1f%%", p.CPU.Some10, p.Memory.Some10, p.IO.Some10)))
+	}
+	miscBlock := lipgloss.JoinVertical(lipgloss.Left, miscLines...)
 	miscCardStyle := cardStyle
 	if m.criticalSwap {
 		miscCardStyle = alertCardStyle
@@ -703,16 +995,41 @@ func (m *Model) renderDashboard(s model.Sample) string {
 	// Network - use enhanced sparklines with stats on wider terminals
 	var netRxSpark, netTxSpark string
 	if m.width >= 160 {
-		netRxSpark = renderSparklineWithStats(m.netRxHist, 30, successColor)
-		netTxSpark = renderSparklineWithStats(m.netTxHist, 30, "#0077FF")
+		netRxSpark = renderSparklineWithStats(m.netRxHist, m.sparkWidth(30), successColor)
+		netTxSpark = renderSparklineWithStats(m.netTxHist, m.sparkWidth(30), netTxColor)
 	} else {
-		netRxSpark = renderSparklineAuto(m.netRxHist, 15, successColor)
-		netTxSpark = renderSparklineAuto(m.netTxHist, 15, "#0077FF")
+		netRxSpark = renderSparklineAuto(m.netRxHist, m.sparkWidth(15), successColor)
+		netTxSpark = renderSparklineAuto(m.netTxHist, m.sparkWidth(15), netTxColor)
 	}
 	netBlock := lipgloss.JoinVertical(lipgloss.Left,
 		fmt.Sprintf("%s RX %5.1f Mb/s %s", valStyle.Foreground(lipgloss.Color(successColor)).Render("↓"), s.IO.NetRxMbps, netRxSpark),
-		fmt.Sprintf("%s TX %5.1f Mb/s %s", valStyle.Foreground(lipgloss.Color("#0077FF")).Render("↑"), s.IO.NetTxMbps, netTxSpark),
+		fmt.Sprintf("%s TX %5.1f Mb/s %s", valStyle.Foreground(lipgloss.Color(netTxColor)).Render("↑"), s.IO.NetTxMbps, netTxSpark),
 	)
+	if c := s.Connections; c != nil {
+		netBlock = lipgloss.JoinVertical(lipgloss.Left, netBlock,
+			subtleStyle.Render(fmt.Sprintf("TCP est %d lis %d tw %d · UDP %d%s", c.TCPEstablished, c.TCPListen, c.TCPTimeWait, c.UDP, staleMark(s, "connections"))))
+	}
+	for _, ni := range topInterfaces(s.IO.PerInterface, 3) {
+		netBlock = lipgloss.JoinVertical(lipgloss.Left, netBlock,
+			subtleStyle.Render(fmt.Sprintf("  %-10s ↓%6.1f ↑%6.1f Mb/s", truncate(ni.Name, 10), ni.RxMbps, ni.TxMbps)))
+	}
+	for _, ni := range s.IO.PerInterface {
+		if !ni.Degraded {
+			continue
+		}
+		netBlock = lipgloss.JoinVertical(lipgloss.Left, netBlock,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor)).Render(
+				fmt.Sprintf("⚠ %s drop %.0f/%.0f err %.0f/%.0f /s", truncate(ni.Name, 10),
+					ni.RxDroppedPerSec, ni.TxDroppedPerSec, ni.RxErrorsPerSec, ni.TxErrorsPerSec)))
+	}
+	for _, ns := range s.CPU.NetSoftirq {
+		if !ns.Dominated {
+			continue
+		}
+		netBlock = lipgloss.JoinVertical(lipgloss.Left, netBlock,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor)).Render(
+				fmt.Sprintf("⚠ cpu%d softirq %.0f%% (net rx %.0f tx %.0f /s)", ns.CPU, ns.SoftirqPct, ns.RxPerSec, ns.TxPerSec)))
+	}
 	netCard := cardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render("NETWORK"), netBlock))
 
 	// Disk
@@ -721,7 +1038,7 @@ func (m *Model) renderDashboard(s model.Sample) string {
 	topDevs := topDevices(s.IO.PerDevice, 3)
 	devLines := ""
 	for _, d := range topDevs {
-		devLines += fmt.Sprintf("%-6s R%5.1f W%5.1f MB/s\n", d.Name, d.ReadMBs, d.WriteMBs)
+		devLines += fmt.Sprintf("%-6s R%5.1f W%5.1f MB/s %5.0f IOPS\n", d.Name, d.ReadMBs, d.WriteMBs, d.ReadIOPS+d.WriteIOPS)
 	}
 	if devLines == "" {
 		devLines = subtleStyle.Render("no device stats")
@@ -732,69 +1049,93 @@ func (m *Model) renderDashboard(s model.Sample) string {
 		subtleStyle.Render("Top devices:"),
 		devLines,
 	)
+	for _, d := range fullestDisks(s.Disks, 3) {
+		style := subtleStyle
+		if d.Stale {
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor))
+			diskBlock = lipgloss.JoinVertical(lipgloss.Left, diskBlock, style.Render(fmt.Sprintf("%-12s stat timed out", truncate(d.Mountpoint, 12))))
+			continue
+		}
+		if d.Percent >= 90 {
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor))
+		}
+		diskBlock = lipgloss.JoinVertical(lipgloss.Left, diskBlock, style.Render(fmt.Sprintf("%-12s %3.0f%% of %.0f GB", truncate(d.Mountpoint, 12), d.Percent, bytesToGiB(d.TotalBytes))))
+	}
 	diskCard := cardStyle.Render(lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render("DISK I/O"), diskBlock))
 
 	// GPU & Battery & Temperature Summary
 	var extraLines []string
 	if m.showGPU && len(s.GPUs) > 0 {
 		for _, g := range s.GPUs {
-			// Color-coded temperature
-			tempStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(coolColor))
-			if g.TempC >= 85 {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Bold(true)
-			} else if g.TempC >= 70 {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(hotColor))
-			} else if g.TempC >= 50 {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(warmColor))
-			}
+			tempStyle, _ := m.tempLevel(g.TempC)
 			extraLines = append(extraLines,
-				fmt.Sprintf("🎮 %s", truncate(g.Name, 12)),
+				fmt.Sprintf("🎮 %s%s%s", gpuIndex(s.GPUs, g), truncate(g.Name, 12), staleMark(s, "gpu")),
 				fmt.Sprintf("   %s %s  %s",
 					renderMiniGauge(g.Util, 8),
-					lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("%3.0f%%", g.Util)),
-					tempStyle.Render(fmt.Sprintf("%2.0f°C", g.TempC))),
-				fmt.Sprintf("   VRAM: %3.0f/%3.0f MB", g.MemUsedMB, g.MemTotalMB))
+					lipgloss.NewStyle().Foreground(lipgloss.Color(m.level(g.Util))).Bold(true).Render(gpuReading("%3.0f%%", g.Util)),
+					tempStyle.Render(gpuReading("%2.0f°C", g.TempC))),
+				fmt.Sprintf("   VRAM: %s/%s MB", gpuReading("%3.0f", g.MemUsedMB), gpuReading("%3.0f", g.MemTotalMB)))
+			if g.MemUtil > 0 || g.EncoderUtil > 0 || g.DecoderUtil > 0 {
+				extraLines = append(extraLines, subtleStyle.Render(fmt.Sprintf("   mem %s enc %s dec %s",
+					gpuReading("%2.0f%%", g.MemUtil), gpuReading("%2.0f%%", g.EncoderUtil), gpuReading("%2.0f%%", g.DecoderUtil))))
+			}
+			for _, mi := range g.MIG {
+				name := mi.Profile
+				if name == "" {
+					name = fmt.Sprintf("GI %d", mi.GPUInstance)
+				}
+				extraLines = append(extraLines, subtleStyle.Render(fmt.Sprintf("   MIG %-8s %s/%s MB",
+					truncate(name, 8), gpuReading("%.0f", mi.MemUsedMB), gpuReading("%.0f", mi.MemTotalMB))))
+			}
+			if len(g.Procs) > 0 {
+				p := g.Procs[0]
+				line := fmt.Sprintf("   %s (%d) %.0f MB", truncate(p.Command, 12), p.PID, p.MemUsedMB)
+				if len(g.Procs) > 1 {
+					line += fmt.Sprintf(" +%d", len(g.Procs)-1)
+				}
+				extraLines = append(extraLines, subtleStyle.Render(line))
+			}
 		}
 	}
-	if m.showBatt && s.Battery.Percent > 0 {
+	if batt := s.PrimaryBattery(); m.showBatt && batt.Percent > 0 {
 		// Battery with icon based on level
 		battIcon := "🔋"
 		battStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(successColor))
-		if s.Battery.Percent <= 20 {
+		if batt.Percent <= 20 {
 			battIcon = "🪫"
 			battStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Bold(true)
-		} else if s.Battery.Percent <= 40 {
+		} else if batt.Percent <= 40 {
 			battStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor))
 		}
-		if s.Battery.State == "Charging" {
+		if batt.State == "Charging" {
 			battIcon = "⚡"
 		}
 		extraLines = append(extraLines,
 			fmt.Sprintf("%s %s %s",
 				battIcon,
-				battStyle.Render(fmt.Sprintf("%.0f%%", s.Battery.Percent)),
-				subtleStyle.Render(s.Battery.State)))
+				battStyle.Render(fmt.Sprintf("%.0f%%", batt.Percent)),
+				subtleStyle.Render(batt.State+batteryPowerText(batt))))
+		if len(s.Batteries) > 1 {
+			var cells []string
+			for _, b := range s.Batteries {
+				cells = append(cells, fmt.Sprintf("%s %.0f%%", b.Name, b.Percent))
+			}
+			extraLines = append(extraLines, subtleStyle.Render("   "+strings.Join(cells, " · ")))
+		}
 	}
 	// Show temperature summary if available
-	if m.showTemps && len(s.Temps) > 0 {
-		maxTemp := s.Temps[0]
-		for _, t := range s.Temps {
+	if temps := allTemps(s); m.showTemps && len(temps) > 0 {
+		maxTemp := temps[0]
+		for _, t := range temps {
 			if t.Temp > maxTemp.Temp {
 				maxTemp = t
 			}
 		}
-		tempStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(coolColor))
-		if maxTemp.Temp >= 85 {
-			tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Bold(true)
-		} else if maxTemp.Temp >= 70 {
-			tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(hotColor))
-		} else if maxTemp.Temp >= 50 {
-			tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(warmColor))
-		}
+		tempStyle, _ := m.tempLevel(maxTemp.Temp)
 		extraLines = append(extraLines,
 			fmt.Sprintf("🌡️ Max: %s (%s)",
 				tempStyle.Render(fmt.Sprintf("%.0f°C", maxTemp.Temp)),
-				truncate(maxTemp.Zone, 10)))
+				truncate(maxTemp.Label, 12)))
 	}
 	extraContent := ""
 	if len(extraLines) == 0 {
@@ -890,11 +1231,13 @@ func (m *Model) renderDashboard(s model.Sample) string {
 
 				ioTable := renderIOTable(m.topIO(s.Top), ioHeight, rightWidth-4)
 				fdTable := renderFDTable(m.topFD(s.Top), fdHeight, rightWidth-4)
-				throttledTable := renderProcessTableCompact(m.sortAndFilter(s.Throttled), thHeight, secondaryColor)
-				coreBlock := renderCoreGridCompact(m.perCoreHist, rightWidth-4)
+				throttledProcs, throttledTitle := throttledPanel(s)
+				throttledProcs = m.sortAndFilter(throttledProcs)
+				throttledTable := renderProcessTableCompact(throttledProcs, thHeight, secondaryColor)
+				coreBlock := renderCoreGridCompact(m.perCoreHist, rightWidth-4, m.level)
 
 				// Use titleStyle for section headers and badgeStyle for throttled count
-				throttledCount := len(m.sortAndFilter(s.Throttled))
+				throttledCount := len(throttledProcs)
 				throttledBadge := ""
 				if throttledCount > 0 {
 					throttledBadge = " " + badgeStyle.Background(lipgloss.Color(secondaryColor)).Render(fmt.Sprintf("%d", throttledCount))
@@ -905,7 +1248,7 @@ func (m *Model) renderDashboard(s model.Sample) string {
 					ioTable,
 					titleStyle.Background(lipgloss.Color(warningColor)).Render("📂 FD TOP"),
 					fdTable,
-					titleStyle.Background(lipgloss.Color(secondaryColor)).Render("🔻 THROTTLED")+throttledBadge,
+					titleStyle.Background(lipgloss.Color(secondaryColor)).Render(throttledTitle)+throttledBadge,
 					throttledTable,
 					titleStyle.Render("CPU CORES"),
 					coreBlock,
@@ -913,9 +1256,10 @@ func (m *Model) renderDashboard(s model.Sample) string {
 			} else {
 				// Without IO panels, show more throttled and cores
 				thHeight := maxInt(6, availHeight/3)
-				throttledProcs := m.sortAndFilter(s.Throttled)
+				throttledProcs, throttledTitle := throttledPanel(s)
+				throttledProcs = m.sortAndFilter(throttledProcs)
 				throttledTable := renderProcessTableCompact(throttledProcs, thHeight, secondaryColor)
-				coreBlock := renderCoreGrid(m.perCoreHist, rightWidth-4)
+				coreBlock := renderCoreGrid(m.perCoreHist, s.CPU.Freqs, rightWidth-4, m.level)
 
 				// Badge for throttled count
 				throttledBadge := ""
@@ -924,7 +1268,7 @@ func (m *Model) renderDashboard(s model.Sample) string {
 				}
 
 				rightColContent = lipgloss.JoinVertical(lipgloss.Left,
-					titleStyle.Background(lipgloss.Color(secondaryColor)).Render("🔻 THROTTLED")+throttledBadge,
+					titleStyle.Background(lipgloss.Color(secondaryColor)).Render(throttledTitle)+throttledBadge,
 					throttledTable,
 					titleStyle.Render("CPU CORES"),
 					coreBlock,
@@ -976,7 +1320,7 @@ func (m *Model) renderAnalysis(s model.Sample) string {
 		titleStyle.Render("🏆 HALL OF SHAME")+shameBadge,
 		shameTable))
 
-	// Frequent Flyers (Right) - processes that have been throttled most often
+	// Frequent Flyers (Right) - processes that have been niced or CPU-throttled most often
 	freqRows := m.getFrequentFlyers(shameHeight - 4)
 	freqTable := renderSimpleTable([]string{"COMMAND", "THROTTLED"}, freqRows, 25, secondaryColor)
 	freqBadge := ""
@@ -987,7 +1331,20 @@ func (m *Model) renderAnalysis(s model.Sample) string {
 		titleStyle.Background(lipgloss.Color(secondaryColor)).Render("✈️ FREQUENT FLYERS")+freqBadge,
 		freqTable))
 
-	return lipgloss.JoinHorizontal(lipgloss.Top, shameCard, freqCard)
+	// Users (far right) - live CPU/memory per owning user
+	var userRows []string
+	for i, u := range s.Users {
+		if i >= shameHeight-4 {
+			break
+		}
+		userRows = append(userRows, fmt.Sprintf("%-12s %5.1f %5.1f %4d", truncate(u.User, 12), u.CPU, u.Memory, u.Processes))
+	}
+	userTable := renderSimpleTable([]string{"USER        ", "  CPU", "  MEM", "PROC"}, userRows, 25, accentColor)
+	userCard := cardStyle.Width(40).Height(shameHeight).Render(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Background(lipgloss.Color(accentColor)).Render("👤 USERS"),
+		userTable))
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, shameCard, freqCard, userCard)
 }
 
 // Helpers for Analysis data
@@ -1003,7 +1360,12 @@ func (m *Model) getHallOfShame(limit int) []string {
 	for k, v := range m.cumulativeCPU {
 		ss = append(ss, kv{k, v})
 	}
-	sort.Slice(ss, func(i, j int) bool { return ss[i].v > ss[j].v })
+	sort.Slice(ss, func(i, j int) bool {
+		if ss[i].v != ss[j].v {
+			return ss[i].v > ss[j].v
+		}
+		return ss[i].k < ss[j].k
+	})
 
 	var rows []string
 	for i := 0; i < limit && i < len(ss); i++ {
@@ -1026,7 +1388,12 @@ func (m *Model) getFrequentFlyers(limit int) []string {
 	for k, v := range m.throttleCount {
 		ss = append(ss, kv{k, v})
 	}
-	sort.Slice(ss, func(i, j int) bool { return ss[i].v > ss[j].v })
+	sort.Slice(ss, func(i, j int) bool {
+		if ss[i].v != ss[j].v {
+			return ss[i].v > ss[j].v
+		}
+		return ss[i].k < ss[j].k
+	})
 
 	var rows []string
 	for i := 0; i < limit && i < len(ss); i++ {
@@ -1037,9 +1404,7 @@ func (m *Model) getFrequentFlyers(limit int) []string {
 
 func (m *Model) topIO(procs []model.Process) []model.Process {
 	sorted := append([]model.Process{}, procs...)
-	sort.Slice(sorted, func(i, j int) bool {
-		return (sorted[i].ReadKBs + sorted[i].WriteKBs) > (sorted[j].ReadKBs + sorted[j].WriteKBs)
-	})
+	sampler.SortProcesses(sorted, "io", m.cfg.Sort2)
 	if len(sorted) > 8 {
 		sorted = sorted[:8]
 	}
@@ -1048,9 +1413,7 @@ func (m *Model) topIO(procs []model.Process) []model.Process {
 
 func (m *Model) topFD(procs []model.Process) []model.Process {
 	sorted := append([]model.Process{}, procs...)
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].FDCount > sorted[j].FDCount
-	})
+	sampler.SortProcesses(sorted, "fd", m.cfg.Sort2)
 	if len(sorted) > 8 {
 		sorted = sorted[:8]
 	}
@@ -1066,7 +1429,7 @@ func (m *Model) renderHelp() string {
 		Foreground(lipgloss.Color(warningColor)).
 		Bold(true)
 	descStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#CCCCCC"))
+		Foreground(lipgloss.Color(mutedColor))
 	sectionStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(accentColor)).
 		Bold(true).
@@ -1099,46 +1462,56 @@ func (m *Model) renderHelp() string {
 	b.WriteString(sectionStyle.Render("⌨️  NAVIGATION") + "\n")
 	b.WriteString(keyStyle.Render("  q/Ctrl+C") + descStyle.Render("      Quit application") + "\n")
 	b.WriteString(keyStyle.Render("  Tab/1-3") + descStyle.Render("       Switch tabs (Dashboard/Analysis/System)") + "\n")
-	b.WriteString(keyStyle.Render("  j/k ↑/↓") + descStyle.Render("       Scroll process list / move selection") + "\n")
+	b.WriteString(keyStyle.Render("  j ↑/↓") + descStyle.Render("         Scroll process list / move selection") + "\n")
 	b.WriteString(keyStyle.Render("  PgUp/PgDn") + descStyle.Render("     Page through process list") + "\n")
 	b.WriteString(keyStyle.Render("  Home/End") + descStyle.Render("      Jump to start/end of list") + "\n")
 	b.WriteString(keyStyle.Render("  Enter") + descStyle.Render("         Show process details modal") + "\n")
 	b.WriteString(keyStyle.Render("  Esc") + descStyle.Render("           Clear selection/filter, close modal") + "\n")
 
 	b.WriteString(sectionStyle.Render("🔍 FILTERING & SORTING") + "\n")
-	b.WriteString(keyStyle.Render("  /") + descStyle.Render("             Start filter input (Enter=apply, Esc=cancel)") + "\n")
-	b.WriteString(keyStyle.Render("  s") + descStyle.Render("             Cycle sort: CPU → MEM → IO → FD") + "\n")
+	b.WriteString(keyStyle.Render("  /") + descStyle.Render("             Filter by regex as you type (Enter=keep, Esc=clear)") + "\n")
+	b.WriteString(keyStyle.Render("  s") + descStyle.Render("             Cycle sort: CPU → MEM → IO → FD → PEAK") + "\n")
+	b.WriteString(keyStyle.Render("  c/m/i") + descStyle.Render("         Sort by CPU / MEM / IO") + "\n")
+	b.WriteString(keyStyle.Render("  T") + descStyle.Render("             Toggle process tree (parents include their children)") + "\n")
+	b.WriteString(keyStyle.Render("  e") + descStyle.Render("             Tree: fold/unfold the selected process") + "\n")
+
+	b.WriteString(sectionStyle.Render("💀 SIGNALS") + "\n")
+	b.WriteString(keyStyle.Render("  k") + descStyle.Render("             SIGTERM selected process (asks y/N)") + "\n")
+	b.WriteString(keyStyle.Render("  K") + descStyle.Render("             SIGKILL selected process (asks y/N)") + "\n")
+	b.WriteString(descStyle.Render("  Without a selection, k/K select the first visible row; PID 1 is refused") + "\n")
 
 	b.WriteString(sectionStyle.Render("🎛️  PANEL TOGGLES") + "\n")
 	b.WriteString(keyStyle.Render("  g") + descStyle.Render("             Toggle GPU panel") + "\n")
 	b.WriteString(keyStyle.Render("  b") + descStyle.Render("             Toggle Battery panel") + "\n")
-	b.WriteString(keyStyle.Render("  i") + descStyle.Render("             Toggle IO/FD panels") + "\n")
+	b.WriteString(keyStyle.Render("  d") + descStyle.Render("             Toggle IO/FD panels") + "\n")
 	b.WriteString(keyStyle.Render("  t") + descStyle.Render("             Toggle Temperature panel") + "\n")
 	b.WriteString(keyStyle.Render("  n") + descStyle.Render("             Toggle Inotify panel") + "\n")
-	b.WriteString(keyStyle.Render("  c") + descStyle.Render("             Toggle Cgroups panel") + "\n")
+	b.WriteString(keyStyle.Render("  C") + descStyle.Render("             Toggle Cgroups panel") + "\n")
 
 	b.WriteString(sectionStyle.Render("⚙️  OTHER CONTROLS") + "\n")
-	b.WriteString(keyStyle.Render("  f") + descStyle.Render("             Freeze/unfreeze updates") + "\n")
-	b.WriteString(keyStyle.Render("  m") + descStyle.Render("             Toggle mouse support") + "\n")
+	b.WriteString(keyStyle.Render("  Space/f") + descStyle.Render("       Pause display (sampling and alerts continue)") + "\n")
+	b.WriteString(keyStyle.Render("  M") + descStyle.Render("             Toggle mouse support") + "\n")
 	b.WriteString(keyStyle.Render("  I") + descStyle.Render("             Show ionice tip for top process") + "\n")
 	b.WriteString(keyStyle.Render("  o") + descStyle.Render("             Toggle JSON output (SRPS_SYSMONI_JSON_FILE)") + "\n")
+	b.WriteString(keyStyle.Render("  r") + descStyle.Render("             Save markdown report (SRPS_SYSMONI_REPORT_DIR)") + "\n")
 	b.WriteString(keyStyle.Render("  ?/h") + descStyle.Render("           Toggle this help") + "\n")
 
 	b.WriteString(sectionStyle.Render("🖱️  MOUSE SUPPORT") + "\n")
 	b.WriteString(descStyle.Render("  Click on processes to select, scroll wheel to navigate") + "\n")
 
 	b.WriteString(sectionStyle.Render("📊 VISUAL INDICATORS") + "\n")
-	b.WriteString(descStyle.Render("  Gauges use gradient colors: ") +
-		lipgloss.NewStyle().Foreground(lipgloss.Color(successColor)).Render("green") +
+	b.WriteString(descStyle.Render("  CPU/MEM/GPU/cores: ") +
+		lipgloss.NewStyle().Foreground(lipgloss.Color(successColor)).Render(fmt.Sprintf("green <%.0f%%", m.cfg.WarnPct)) +
 		descStyle.Render(" → ") +
 		lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor)).Render("yellow") +
 		descStyle.Render(" → ") +
-		lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Render("red") + "\n")
+		lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Render(fmt.Sprintf("red >%.0f%%", m.cfg.CritPct)) +
+		descStyle.Render(fmt.Sprintf("; temps red at %.0f°C", m.cfg.TempCrit)) + "\n")
 	b.WriteString(descStyle.Render("  Alert badge blinks when CPU/MEM/Swap/Temp is critical") + "\n")
 	b.WriteString(descStyle.Render("  Process rows highlight: ") +
 		lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor)).Render("gold=FD growth") +
 		descStyle.Render(", ") +
-		lipgloss.NewStyle().Foreground(lipgloss.Color(secondaryColor)).Render("pink=throttled") + "\n")
+		lipgloss.NewStyle().Foreground(lipgloss.Color(secondaryColor)).Render("pink=niced") + "\n")
 
 	b.WriteString(sectionStyle.Render("💡 TIPS") + "\n")
 	b.WriteString(descStyle.Render("  Throttle IO: sudo ionice -c3 -p <pid>") + "\n")
@@ -1158,9 +1531,9 @@ func renderSimpleTable(headers []string, rows []string, width int, color string)
 	b.WriteString(tableHeaderStyle.Foreground(lipgloss.Color(color)).Render(headStr) + "\n")
 
 	for i, r := range rows {
-		rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#EEEEEE"))
+		rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textColor))
 		if i%2 != 0 {
-			rowStyle = rowStyle.Foreground(lipgloss.Color("#AAAAAA"))
+			rowStyle = rowStyle.Foreground(lipgloss.Color(mutedColor))
 		}
 		b.WriteString(rowStyle.Render(r) + "\n")
 	}
@@ -1169,12 +1542,6 @@ func renderSimpleTable(headers []string, rows []string, width int, color string)
 
 // --- Render Helpers ---
 
-// renderGauge is a convenience wrapper for renderGaugeEnhanced with defaults
-// Use this for simple gauges where gradient coloring is desired
-func renderGauge(label string, pct float64) string {
-	return renderGaugeEnhanced(label, pct, primaryColor, true)
-}
-
 // interpolateColor creates a gradient color based on percentage (0-100)
 // green -> yellow -> orange -> red
 func interpolateColor(pct float64) string {
@@ -1196,6 +1563,34 @@ func interpolateColor(pct float64) string {
 	}
 }
 
+// level returns the severity color for a utilization percent: green below
+// -warn-pct, yellow up to -crit-pct, red above.
+func (m *Model) level(pct float64) string {
+	switch {
+	case pct > m.cfg.CritPct:
+		return criticalColor
+	case pct >= m.cfg.WarnPct:
+		return warningColor
+	}
+	return successColor
+}
+
+// tempLevel returns the style and icon for a temperature: red at -temp-crit
+// and above, orange within 15°C of it, yellow within 35°C, blue below.
+func (m *Model) tempLevel(c float64) (lipgloss.Style, string) {
+	switch crit := m.cfg.TempCrit; {
+	case c >= crit:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Bold(true), "🔥"
+	case c >= crit-15:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(hotColor)), "🟠"
+	case c >= crit-35:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(warmColor)), "🟡"
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(coolColor)), "🟢"
+}
+
+// renderGaugeEnhanced draws a 20-cell gauge. With useGradient the bar runs
+// green to red along its length; otherwise bar and value are baseColor.
 func renderGaugeEnhanced(label string, pct float64, baseColor string, useGradient bool) string {
 	width := 20
 	filled := int((pct / 100) * float64(width))
@@ -1215,23 +1610,20 @@ func renderGaugeEnhanced(label string, pct float64, baseColor string, useGradien
 			bar.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(c)).Render("█"))
 		}
 		// Empty part
-		emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#333333"))
+		emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(trackColor))
 		bar.WriteString(emptyStyle.Render(strings.Repeat("░", width-filled)))
 	} else {
-		// Simple solid color with alert threshold
+		// Solid severity color chosen by the caller
 		style := lipgloss.NewStyle().Foreground(lipgloss.Color(baseColor))
-		if pct > 90 {
-			style = style.Foreground(lipgloss.Color(criticalColor))
-		} else if pct > 75 {
-			style = style.Foreground(lipgloss.Color(warningColor))
-		}
 		bar.WriteString(style.Render(strings.Repeat("█", filled)))
-		bar.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#333333")).Render(strings.Repeat("░", width-filled)))
+		bar.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(trackColor)).Render(strings.Repeat("░", width-filled)))
 	}
 
 	// Value display with color based on severity
-	valColor := "#FFFFFF"
-	if pct > 90 {
+	valColor := textColor
+	if !useGradient {
+		valColor = baseColor
+	} else if pct > 90 {
 		valColor = criticalColor
 	} else if pct > 75 {
 		valColor = warningColor
@@ -1245,6 +1637,14 @@ func renderGaugeEnhanced(label string, pct float64, baseColor string, useGradien
 }
 
 // renderMiniGauge renders a compact inline gauge
+// gpuReading formats a GPU reading, or "n/a" where the driver has none.
+func gpuReading(format string, v float64) string {
+	if v == model.GPUUnavailable {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, v)
+}
+
 func renderMiniGauge(pct float64, width int) string {
 	filled := int((pct / 100) * float64(width))
 	if filled > width {
@@ -1260,10 +1660,16 @@ func renderMiniGauge(pct float64, width int) string {
 		c := interpolateColor(charPct)
 		bar.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(c)).Render("▰"))
 	}
-	bar.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#333333")).Render(strings.Repeat("▱", width-filled)))
+	bar.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(trackColor)).Render(strings.Repeat("▱", width-filled)))
 	return bar.String()
 }
 
+// sparkWidth scales a sparkline sized for a 120-column terminal to the
+// current width, down to half its size and up to -spark-depth.
+func (m *Model) sparkWidth(base int) int {
+	return min(max(base*m.width/120, base/2), m.cfg.SparkDepth)
+}
+
 func renderSparklineAuto(values []float64, width int, color string) string {
 	if len(values) == 0 {
 		return strings.Repeat(" ", width)
@@ -1311,8 +1717,57 @@ func renderSparklineAuto(values []float64, width int, color string) string {
 
 func topDevices(devs []model.IODevice, n int) []model.IODevice {
 	sorted := append([]model.IODevice{}, devs...)
-	sort.Slice(sorted, func(i, j int) bool {
-		return (sorted[i].ReadMBs + sorted[i].WriteMBs) > (sorted[j].ReadMBs + sorted[j].WriteMBs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].ReadMBs+sorted[i].WriteMBs, sorted[j].ReadMBs+sorted[j].WriteMBs
+		if a != b {
+			return a > b
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// fullestDisks returns stale mounts first, then the n fullest by percent.
+func fullestDisks(disks []model.Disk, n int) []model.Disk {
+	sorted := append([]model.Disk{}, disks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Stale != sorted[j].Stale {
+			return sorted[i].Stale
+		}
+		return sorted[i].Percent > sorted[j].Percent
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// throttledPanel picks the side panel's list: processes in CPU-throttled
+// cgroups when there are any, since that is real starvation, else niced ones.
+func throttledPanel(s model.Sample) ([]model.Process, string) {
+	if len(s.CPUThrottled) > 0 {
+		return s.CPUThrottled, "🔻 CPU THROTTLED"
+	}
+	return s.Niced, "🔻 NICED"
+}
+
+// topInterfaces returns the n busiest non-loopback interfaces with traffic.
+func topInterfaces(ifs []model.NetInterface, n int) []model.NetInterface {
+	var sorted []model.NetInterface
+	for _, ni := range ifs {
+		if !ni.Loopback && ni.RxMbps+ni.TxMbps > 0 {
+			sorted = append(sorted, ni)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].RxMbps+sorted[i].TxMbps, sorted[j].RxMbps+sorted[j].TxMbps
+		if a != b {
+			return a > b
+		}
+		return sorted[i].Name < sorted[j].Name
 	})
 	if len(sorted) > n {
 		sorted = sorted[:n]
@@ -1520,7 +1975,7 @@ func renderFDTable(procs []model.Process, height int, width int) string {
 }
 
 // renderCoreGridCompact renders a more compact CPU core grid for smaller spaces
-func renderCoreGridCompact(hist map[int][]float64, width int) string {
+func renderCoreGridCompact(hist map[int][]float64, width int, level func(float64) string) string {
 	var keys []int
 	for k := range hist {
 		keys = append(keys, k)
@@ -1536,7 +1991,7 @@ func renderCoreGridCompact(hist map[int][]float64, width int) string {
 		var lineParts []string
 		for j := 0; j < coresPerLine && i+j < len(keys); j++ {
 			c := keys[i+j]
-			sp := renderSparklinePct(hist[c], sparkWidth, primaryColor)
+			sp := renderSparklinePct(hist[c], sparkWidth, level(lastValue(hist[c])))
 			lineParts = append(lineParts, fmt.Sprintf("%2d%s", c, sp))
 		}
 		lines = append(lines, strings.Join(lineParts, " "))
@@ -1550,7 +2005,10 @@ func renderCoreGridCompact(hist map[int][]float64, width int) string {
 	return strings.Join(lines, "\n")
 }
 
-func renderCoreGrid(hist map[int][]float64, width int) string {
+// renderCoreGrid lists cores two per line with a sparkline each, plus the
+// current clock when cpufreq is available. Each sparkline takes the level
+// color of the core's current load.
+func renderCoreGrid(hist map[int][]float64, freqs []float64, width int, level func(float64) string) string {
 	// Create a simple grid. We assume we have hist points.
 	// Sort keys
 	var keys []int
@@ -1563,13 +2021,13 @@ func renderCoreGrid(hist map[int][]float64, width int) string {
 	// 2 columns of cores
 	for i := 0; i < len(keys); i += 2 {
 		c1 := keys[i]
-		sp1 := renderSparklinePct(hist[c1], 10, primaryColor) // mini sparklines
-		line := fmt.Sprintf("%2d %s", c1, sp1)
+		sp1 := renderSparklinePct(hist[c1], 10, level(lastValue(hist[c1]))) // mini sparklines
+		line := fmt.Sprintf("%2d %s", c1, sp1) + coreFreq(freqs, c1)
 
 		if i+1 < len(keys) {
 			c2 := keys[i+1]
-			sp2 := renderSparklinePct(hist[c2], 10, primaryColor)
-			line += fmt.Sprintf("   %2d %s", c2, sp2)
+			sp2 := renderSparklinePct(hist[c2], 10, level(lastValue(hist[c2])))
+			line += fmt.Sprintf("   %2d %s", c2, sp2) + coreFreq(freqs, c2)
 		}
 		lines = append(lines, line)
 	}
@@ -1577,6 +2035,40 @@ func renderCoreGrid(hist map[int][]float64, width int) string {
 	return strings.Join(lines, "\n")
 }
 
+// lastValue returns the newest point of a history, or 0 when it is empty.
+func lastValue(hist []float64) float64 {
+	if len(hist) == 0 {
+		return 0
+	}
+	return hist[len(hist)-1]
+}
+
+// batteryPowerText renders draw and time estimate, e.g. " 11.2W 3h05m left".
+func batteryPowerText(b model.Battery) string {
+	var out string
+	if b.PowerW > 0 {
+		out += fmt.Sprintf(" %.1fW", b.PowerW)
+	}
+	if b.TimeRemaining > 0 {
+		h := int(b.TimeRemaining.Hours())
+		mins := int(b.TimeRemaining.Minutes()) % 60
+		suffix := "left"
+		if b.State == "Charging" {
+			suffix = "to full"
+		}
+		out += fmt.Sprintf(" %dh%02dm %s", h, mins, suffix)
+	}
+	return out
+}
+
+// coreFreq formats core c's clock as " 2.4G", or "" when unknown.
+func coreFreq(freqs []float64, c int) string {
+	if c >= len(freqs) || freqs[c] <= 0 {
+		return ""
+	}
+	return subtleStyle.Render(fmt.Sprintf(" %.1fG", freqs[c]/1000))
+}
+
 // renderSparklineWithStats renders a sparkline with min/max/avg annotations
 func renderSparklineWithStats(values []float64, width int, color string) string {
 	if len(values) == 0 {
@@ -1667,7 +2159,7 @@ func (m *Model) renderProcDetailModal(s model.Sample) string {
 	content.WriteString("\n\n")
 
 	// Process info rows
-	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textColor))
 	modalLabelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(labelColor)).Width(12)
 
 	rows := []struct {
@@ -1677,18 +2169,42 @@ func (m *Model) renderProcDetailModal(s model.Sample) string {
 		{"Command", proc.Command},
 		{"PID", fmt.Sprintf("%d", proc.PID)},
 		{"Nice", fmt.Sprintf("%d", proc.Nice)},
+		{"Started", procStarted(proc)},
+		{"State", procState(proc.State)},
+		{"Threads", fmt.Sprintf("%d", proc.Threads)},
 		{"CPU", fmt.Sprintf("%.1f%%", proc.CPU)},
 		{"Memory", fmt.Sprintf("%.1f%%", proc.Memory)},
+		{"RSS / Peak", fmt.Sprintf("%s / %s%s", formatBytes(proc.RSSBytes), formatBytes(proc.PeakRSSBytes), peakFlag(proc))},
+		{"Peak Virt", formatBytes(proc.PeakVirtBytes)},
 		{"Read", fmt.Sprintf("%.1f kB/s", proc.ReadKBs)},
 		{"Write", fmt.Sprintf("%.1f kB/s", proc.WriteKBs)},
 		{"FD Count", fmt.Sprintf("%d", proc.FDCount)},
 		{"FD Change", fmt.Sprintf("%+d", proc.FDDiff)},
 	}
+	if m.cfg.Schedstat {
+		rows = append(rows, struct {
+			label string
+			value string
+		}{"Sched wait", fmt.Sprintf("%.0f µs/slice (system %.0f µs)", proc.SchedLatencyUs, s.CPU.SchedLatencyUs)})
+	}
 
 	for _, r := range rows {
 		content.WriteString(modalLabelStyle.Render(r.label+":") + " " + infoStyle.Render(r.value) + "\n")
 	}
 
+	// Hottest threads when -threads is on
+	var threadLines []string
+	for _, t := range s.Threads {
+		if t.PID != proc.PID || len(threadLines) >= 5 {
+			continue
+		}
+		threadLines = append(threadLines, fmt.Sprintf("  %d/%-7d %-16s %5.1f%%", t.PID, t.TID, truncate(t.Command, 16), t.CPU))
+	}
+	if len(threadLines) > 0 {
+		content.WriteString("\n" + modalLabelStyle.Render("Threads:") + "\n")
+		content.WriteString(infoStyle.Render(strings.Join(threadLines, "\n")) + "\n")
+	}
+
 	// Mini gauges for CPU and Memory
 	content.WriteString("\n")
 	content.WriteString(modalLabelStyle.Render("CPU:") + " " + renderMiniGauge(proc.CPU, 30) + "\n")
@@ -1708,7 +2224,7 @@ func (m *Model) renderProcDetailModal(s model.Sample) string {
 	// Center the modal on screen with a dim background
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modal,
 		lipgloss.WithWhitespaceChars("░"),
-		lipgloss.WithWhitespaceForeground(lipgloss.Color("#111111")))
+		lipgloss.WithWhitespaceForeground(lipgloss.Color(shadowColor)))
 }
 
 // renderSystemInfo renders the third tab with system details (temps, inotify, cgroups)
@@ -1716,10 +2232,13 @@ func (m *Model) renderSystemInfo(s model.Sample) string {
 	availHeight := m.height - 4
 
 	// Temperature panel
-	tempsCard := m.renderTempsPanel(s.Temps, availHeight/3)
+	tempsCard := m.renderTempsPanel(allTemps(s), s.Sensors, availHeight/3)
+
+	// OOM / VM tunables panel
+	oomCard := m.renderOOMPanel(s.OOM, availHeight/3)
 
 	// Inotify panel
-	inotifyCard := m.renderInotifyPanel(s.Inotify, availHeight/3)
+	inotifyCard := m.renderInotifyPanel(s.Inotify, s.OpenFDs, s.MaxFDs, availHeight/3)
 
 	// Cgroups panel
 	cgroupsCard := m.renderCgroupsPanel(s.Cgroups, availHeight/3)
@@ -1728,16 +2247,55 @@ func (m *Model) renderSystemInfo(s model.Sample) string {
 	leftWidth := m.width / 2
 	rightWidth := m.width - leftWidth - 2
 
-	leftCol := lipgloss.NewStyle().Width(leftWidth).Render(tempsCard)
+	leftCol := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Width(leftWidth).Render(tempsCard),
+		lipgloss.NewStyle().Width(leftWidth).Render(oomCard))
 	rightCol := lipgloss.JoinVertical(lipgloss.Left,
 		lipgloss.NewStyle().Width(rightWidth).Render(inotifyCard),
-		lipgloss.NewStyle().Width(rightWidth).Render(cgroupsCard))
+		lipgloss.NewStyle().Width(rightWidth).Render(cgroupsCard),
+		lipgloss.NewStyle().Width(rightWidth).Render(m.renderSelfStats(s.Self)),
+		lipgloss.NewStyle().Width(rightWidth).Render(renderErrors(s.Errors)))
 
 	return lipgloss.JoinHorizontal(lipgloss.Top, leftCol, rightCol)
 }
 
-// renderTempsPanel renders temperature readings with thermal coloring
-func (m *Model) renderTempsPanel(temps []model.Temp, height int) string {
+// renderErrors lists the failing readers, whose panels show missing data
+// rather than real zeros. It is empty when every reader succeeded.
+func renderErrors(errs []string) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor))
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = style.Render("✗ " + e)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gpuIndex returns "#<index> " for g when there is more than one GPU.
+func gpuIndex(gpus []model.GPU, g model.GPU) string {
+	if len(gpus) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("#%d ", g.Index)
+}
+
+// allTemps returns the thermal zones followed by the hwmon temperature
+// sensors, labeled "chip label".
+func allTemps(s model.Sample) []model.Temp {
+	temps := append([]model.Temp(nil), s.Temps...)
+	for _, sn := range s.Sensors {
+		if sn.Kind == model.SensorTemp {
+			temps = append(temps, model.Temp{Zone: sn.Chip + "/" + sn.Label, Label: sn.Chip + " " + sn.Label, Temp: sn.Value})
+		}
+	}
+	return temps
+}
+
+// renderTempsPanel renders temperature readings with thermal coloring,
+// followed by hwmon fan speeds and voltages
+func (m *Model) renderTempsPanel(temps []model.Temp, sensors []model.Sensor, height int) string {
 	var content strings.Builder
 
 	header := lipgloss.NewStyle().
@@ -1746,14 +2304,28 @@ func (m *Model) renderTempsPanel(temps []model.Temp, height int) string {
 		Render("🌡️  TEMPERATURES")
 	content.WriteString(header + "\n\n")
 
-	if len(temps) == 0 {
+	var other []string
+	for _, sn := range sensors {
+		name := truncate(sn.Chip+" "+sn.Label, 20)
+		switch sn.Kind {
+		case model.SensorFan:
+			other = append(other, fmt.Sprintf("🌀 %-20s %5.0f RPM", name, sn.Value))
+		case model.SensorVoltage:
+			other = append(other, fmt.Sprintf("⚡ %-20s %6.3f V", name, sn.Value))
+		}
+	}
+
+	if len(temps) == 0 && len(other) == 0 {
 		content.WriteString(subtleStyle.Render("No temperature sensors available\n"))
 	} else {
 		// Sort by temperature descending
 		sortedTemps := make([]model.Temp, len(temps))
 		copy(sortedTemps, temps)
-		sort.Slice(sortedTemps, func(i, j int) bool {
-			return sortedTemps[i].Temp > sortedTemps[j].Temp
+		sort.SliceStable(sortedTemps, func(i, j int) bool {
+			if sortedTemps[i].Temp != sortedTemps[j].Temp {
+				return sortedTemps[i].Temp > sortedTemps[j].Temp
+			}
+			return sortedTemps[i].Zone < sortedTemps[j].Zone
 		})
 
 		maxShown := height - 3
@@ -1763,28 +2335,13 @@ func (m *Model) renderTempsPanel(temps []model.Temp, height int) string {
 
 		for i, t := range sortedTemps {
 			if i >= maxShown {
-				content.WriteString(subtleStyle.Render(fmt.Sprintf("  ... and %d more", len(sortedTemps)-maxShown)) + "\n")
+				content.WriteString(subtleStyle.Render(fmt.Sprintf("  ... and %d more", len(sortedTemps)+len(other)-maxShown)) + "\n")
 				break
 			}
 
-			// Color based on temperature
-			var tempStyle lipgloss.Style
-			var icon string
-			if t.Temp >= 85 {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(criticalColor)).Bold(true)
-				icon = "🔥"
-			} else if t.Temp >= 70 {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(hotColor))
-				icon = "🟠"
-			} else if t.Temp >= 50 {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(warmColor))
-				icon = "🟡"
-			} else {
-				tempStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(coolColor))
-				icon = "🟢"
-			}
+			tempStyle, icon := m.tempLevel(t.Temp)
 
-			zone := truncate(t.Zone, 20)
+			zone := truncate(t.Label, 20)
 			tempStr := tempStyle.Render(fmt.Sprintf("%5.1f°C", t.Temp))
 			// Mini thermal bar
 			barWidth := 15
@@ -1798,17 +2355,71 @@ func (m *Model) renderTempsPanel(temps []model.Temp, height int) string {
 				pct := float64(j) / float64(barWidth) * 100
 				bar += lipgloss.NewStyle().Foreground(lipgloss.Color(interpolateColor(pct))).Render("▰")
 			}
-			bar += lipgloss.NewStyle().Foreground(lipgloss.Color("#333333")).Render(strings.Repeat("▱", barWidth-filled))
+			bar += lipgloss.NewStyle().Foreground(lipgloss.Color(trackColor)).Render(strings.Repeat("▱", barWidth-filled))
 
 			content.WriteString(fmt.Sprintf("%s %-20s %s %s\n", icon, zone, tempStr, bar))
 		}
+		for i, line := range other {
+			if len(sortedTemps)+i >= maxShown {
+				if len(sortedTemps) < maxShown {
+					content.WriteString(subtleStyle.Render(fmt.Sprintf("  ... and %d more", len(other)-i)) + "\n")
+				}
+				break
+			}
+			content.WriteString(line + "\n")
+		}
 	}
 
 	return cardStyle.Height(height).Render(content.String())
 }
 
-// renderInotifyPanel renders inotify watch statistics
-func (m *Model) renderInotifyPanel(info model.Inotify, height int) string {
+// renderSelfStats renders a one-line breakdown of sysmoni's own sampling cost
+func (m *Model) renderSelfStats(st model.SelfStats) string {
+	readers := append([]model.ReaderTiming{}, st.Readers...)
+	sort.SliceStable(readers, func(i, j int) bool { return readers[i].Duration > readers[j].Duration })
+	parts := make([]string, 0, len(readers))
+	for _, r := range readers {
+		parts = append(parts, fmt.Sprintf("%s %s", r.Name, r.Duration.Round(time.Microsecond*100)))
+	}
+	return subtleStyle.Render(fmt.Sprintf("⏱ sample %s: %s", st.SampleDuration.Round(time.Microsecond*100), strings.Join(parts, ", ")))
+}
+
+// renderOOMPanel renders the kernel OOM/overcommit configuration
+func (m *Model) renderOOMPanel(oom model.OOMConfig, height int) string {
+	var content strings.Builder
+
+	header := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(primaryColor)).
+		Bold(true).
+		Render("🧯 OOM CONFIG")
+	content.WriteString(header + "\n\n")
+
+	labelW := lipgloss.NewStyle().Foreground(lipgloss.Color(labelColor)).Width(18)
+	valW := lipgloss.NewStyle().Foreground(lipgloss.Color(textColor))
+
+	overcommit := map[int]string{0: "heuristic", 1: "always", 2: "strict"}[oom.OvercommitMemory]
+	daemon := "none"
+	switch {
+	case oom.EarlyOOMActive && oom.OOMDActive:
+		daemon = "earlyoom + systemd-oomd"
+	case oom.EarlyOOMActive:
+		daemon = "earlyoom"
+	case oom.OOMDActive:
+		daemon = "systemd-oomd"
+	}
+
+	content.WriteString(labelW.Render("Overcommit:") + " " + valW.Render(fmt.Sprintf("%d (%s), ratio %d%%", oom.OvercommitMemory, overcommit, oom.OvercommitRatio)) + "\n")
+	content.WriteString(labelW.Render("Swappiness:") + " " + valW.Render(fmt.Sprintf("%d", oom.Swappiness)) + "\n")
+	content.WriteString(labelW.Render("Min free:") + " " + valW.Render(formatBytes(oom.MinFreeKbytes*1024)) + "\n")
+	content.WriteString(labelW.Render("Panic on OOM:") + " " + valW.Render(fmt.Sprintf("%d", oom.PanicOnOOM)) + "\n")
+	content.WriteString(labelW.Render("OOM daemon:") + " " + valW.Render(daemon) + "\n")
+
+	return cardStyle.Height(height).Render(content.String())
+}
+
+// renderInotifyPanel renders inotify watch statistics and system-wide file
+// handle usage
+func (m *Model) renderInotifyPanel(info model.Inotify, openFDs, maxFDs uint64, height int) string {
 	var content strings.Builder
 
 	header := lipgloss.NewStyle().
@@ -1834,13 +2445,16 @@ func (m *Model) renderInotifyPanel(info model.Inotify, height int) string {
 	}
 
 	labelW := lipgloss.NewStyle().Foreground(lipgloss.Color(labelColor)).Width(16)
-	valW := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+	valW := lipgloss.NewStyle().Foreground(lipgloss.Color(textColor))
 
 	content.WriteString(labelW.Render("Current:") + " " + usageStyle.Render(fmt.Sprintf("%d", info.NrWatches)) + "\n")
 	content.WriteString(labelW.Render("Max User:") + " " + valW.Render(fmt.Sprintf("%d", info.MaxUserWatches)) + "\n")
 	content.WriteString(labelW.Render("Max Instances:") + " " + valW.Render(fmt.Sprintf("%d", info.MaxUserInstances)) + "\n")
 	content.WriteString("\n")
 	content.WriteString(labelW.Render("Usage:") + " " + renderMiniGauge(usagePct, 20) + usageStyle.Render(fmt.Sprintf(" %.1f%%", usagePct)) + "\n")
+	if maxFDs > 0 {
+		content.WriteString(labelW.Render("Open files:") + " " + valW.Render(fmt.Sprintf("%d / %d", openFDs, maxFDs)) + "\n")
+	}
 
 	if usagePct > 80 {
 		content.WriteString("\n")
@@ -1858,7 +2472,7 @@ func (m *Model) renderCgroupsPanel(cgroups []model.Cgroup, height int) string {
 	header := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(primaryColor)).
 		Bold(true).
-		Render("📦 CGROUP CPU USAGE")
+		Render("📦 CGROUP USAGE")
 	content.WriteString(header + "\n\n")
 
 	if len(cgroups) == 0 {
@@ -1867,9 +2481,16 @@ func (m *Model) renderCgroupsPanel(cgroups []model.Cgroup, height int) string {
 		// Sort by CPU descending
 		sortedCgroups := make([]model.Cgroup, len(cgroups))
 		copy(sortedCgroups, cgroups)
-		sort.Slice(sortedCgroups, func(i, j int) bool {
-			return sortedCgroups[i].CPU > sortedCgroups[j].CPU
+		sort.SliceStable(sortedCgroups, func(i, j int) bool {
+			if sortedCgroups[i].CPU != sortedCgroups[j].CPU {
+				return sortedCgroups[i].CPU > sortedCgroups[j].CPU
+			}
+			return sortedCgroups[i].Path < sortedCgroups[j].Path
 		})
+		nameCount := make(map[string]int)
+		for _, cg := range sortedCgroups {
+			nameCount[cg.Name]++
+		}
 
 		maxShown := height - 3
 		if maxShown < 1 {
@@ -1882,7 +2503,12 @@ func (m *Model) renderCgroupsPanel(cgroups []model.Cgroup, height int) string {
 				break
 			}
 
-			name := truncate(cg.Name, 25)
+			label := cg.Name
+			if nameCount[cg.Name] > 1 {
+				// Same leaf in different slices: show the parent too.
+				label = path.Join(path.Base(path.Dir(cg.Path)), cg.Name)
+			}
+			name := truncate(label, 25)
 			cpuPct := cg.CPU
 
 			// Color based on CPU usage
@@ -1892,11 +2518,23 @@ func (m *Model) renderCgroupsPanel(cgroups []model.Cgroup, height int) string {
 			} else if cpuPct > 50 {
 				cpuStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor))
 			} else {
-				cpuStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+				cpuStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textColor))
 			}
 
 			bar := renderMiniGauge(cpuPct, 12)
-			content.WriteString(fmt.Sprintf("%-25s %s %s\n", name, bar, cpuStyle.Render(fmt.Sprintf("%5.1f%%", cpuPct))))
+			ioStr := ""
+			if cg.IOReadBytesPerSec > 0 || cg.IOWriteBytesPerSec > 0 {
+				ioStr = subtleStyle.Render(fmt.Sprintf(" R %s/s W %s/s", formatBytes(uint64(cg.IOReadBytesPerSec)), formatBytes(uint64(cg.IOWriteBytesPerSec))))
+			}
+			if cg.PIDsNearLimit {
+				ioStr += criticalStyle.Render(fmt.Sprintf(" pids %d/%d", cg.PIDsCurrent, cg.PIDsMax))
+			}
+			if cg.ThrottledPerSec > 0 {
+				ioStr += lipgloss.NewStyle().Foreground(lipgloss.Color(warningColor)).Render(
+					fmt.Sprintf(" throttled %.0f/s %.0fms/s", cg.ThrottledPerSec, cg.ThrottledMsPerSec))
+			}
+			memStr := subtleStyle.Render(fmt.Sprintf(" mem %s", formatBytes(cg.MemoryBytes)))
+			content.WriteString(fmt.Sprintf("%-25s %s %s%s%s\n", name, bar, cpuStyle.Render(fmt.Sprintf("%5.1f%%", cpuPct)), memStr, ioStr))
 		}
 	}
 
@@ -2003,6 +2641,29 @@ func pct(used, total uint64) float64 {
 
 func bytesToGiB(b uint64) float64 { return float64(b) / (1024 * 1024 * 1024) }
 
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// peakFlag marks processes whose peak RSS is well above their current RSS,
+// i.e. something that spiked earlier and has since released memory.
+func peakFlag(p *model.Process) string {
+	if p.RSSBytes > 0 && p.PeakRSSBytes >= 2*p.RSSBytes && p.PeakRSSBytes >= 256*1024*1024 {
+		return fmt.Sprintf("  ⚠ peaked %.1fx", float64(p.PeakRSSBytes)/float64(p.RSSBytes))
+	}
+	return ""
+}
+
 func truncate(s string, n int) string {
 	if len(s) > n {
 		return s[:n-1] + "…"
@@ -2031,34 +2692,46 @@ func onOff(v bool) string {
 	return "off"
 }
 
+// setFilter compiles expr (case-insensitively) as the active process filter
+// and returns to the top of the list. An expression that doesn't compile,
+// like a half-typed "(", filters nothing and is kept in filterErr.
+func (m *Model) setFilter(expr string) {
+	m.filterRe, m.filterErr = nil, nil
+	m.topOffset = 0
+	m.selectedProc = -1 // Reset selection when filter changes
+	if expr == "" {
+		return
+	}
+	re, err := sampler.CompileFilter("(?i)" + expr)
+	if err != nil {
+		m.filterErr = err
+		return
+	}
+	m.filterRe = re
+}
+
 func (m *Model) sortAndFilter(rows []model.Process) []model.Process {
 	// Filter
 	var filtered []model.Process
-	filterLower := strings.ToLower(m.filter)
 	for _, r := range rows {
-		if filterLower != "" && !strings.Contains(strings.ToLower(r.Command), filterLower) {
+		if m.filterRe != nil && !m.filterRe.MatchString(r.Command) {
 			continue
 		}
 		filtered = append(filtered, r)
 	}
-	// Sort based on current sort key
-	sort.Slice(filtered, func(i, j int) bool {
-		switch m.sortKey {
-		case "mem":
-			return filtered[i].Memory > filtered[j].Memory
-		case "io":
-			return (filtered[i].ReadKBs + filtered[i].WriteKBs) > (filtered[j].ReadKBs + filtered[j].WriteKBs)
-		case "fd":
-			return filtered[i].FDCount > filtered[j].FDCount
-		default: // "cpu"
-			return filtered[i].CPU > filtered[j].CPU
-		}
-	})
+	if m.treeView {
+		return treeRows(filtered, m.sortKey, m.cfg.Sort2, m.collapsed)
+	}
+	// Sort based on current sort key; ties fall back to -sort2, then PID
+	sampler.SortProcesses(filtered, m.sortKey, m.cfg.Sort2)
 	return filtered
 }
 
 func displayFilter(m *Model) string {
 	if m.inputMode {
+		if m.filterErr != nil {
+			return "/" + string(m.inputBuf) + " (incomplete)"
+		}
 		return "/" + string(m.inputBuf)
 	}
 	return m.filter
@@ -2076,13 +2749,33 @@ func (m *Model) maybeWriteJSON(s model.Sample) {
 	_ = json.NewEncoder(f).Encode(s)
 }
 
-// RunTUI starts the Bubble Tea program.
-func RunTUI(cfg config.Config) error {
+// monochrome reports whether the TUI must not emit colors: -no-color,
+// -theme mono, a non-empty NO_COLOR (https://no-color.org), or stdout not
+// being a terminal.
+func monochrome(cfg config.Config) bool {
+	if cfg.NoColor || cfg.Theme == "mono" || os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	fi, err := os.Stdout.Stat()
+	return err != nil || fi.Mode()&os.ModeCharDevice == 0
+}
+
+// RunTUI starts the Bubble Tea program on samples from src. On exit it
+// restores any cgroup limits changed by -cap-cgroup.
+func RunTUI(cfg config.Config, src model.SampleSource) error {
+	if monochrome(cfg) {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
 	p := tea.NewProgram(
-		New(cfg),
+		New(cfg, src),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(), // Enable mouse support
 	)
-	_, err := p.Run()
+	final, err := p.Run()
+	if m, ok := final.(*Model); ok && m.capper != nil {
+		if rerr := m.capper.Restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
 	return err
 }
@@ -0,0 +1,63 @@
+// Package watch sets up what acts on each sample, the same way for the
+// streaming modes and the TUI: the -alert rules and their -on-alert hook,
+// the -protect-cpu protector and the -cap-cgroup capper.
+package watch
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/alert"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/protect"
+)
+
+// Watchers are the per-sample watchers cfg enables; each is nil when off.
+type Watchers struct {
+	Alerts    *alert.Evaluator
+	Hook      *alert.Hook
+	Protector *protect.Protector
+	Capper    *protect.Capper
+}
+
+// New builds the watchers cfg enables. A -replay capture describes another
+// time (or host): its alerts are still evaluated, but nothing that acts on
+// this host (hook, protector, capper) is set up.
+func New(cfg config.Config) Watchers {
+	var w Watchers
+	live := cfg.Replay == ""
+	if len(cfg.Alerts) > 0 {
+		if rules, err := alert.ParseRules(cfg.Alerts); err != nil {
+			// Validate already rejected bad rules; this is unreachable in main.
+			slog.Error("ignoring -alert", "err", err)
+		} else {
+			w.Alerts = alert.NewEvaluator(rules)
+			if strings.TrimSpace(cfg.OnAlert) != "" && live {
+				w.Hook = alert.NewHook(cfg.OnAlert, cfg.OnAlertTimeout, cfg.OnAlertCooldown)
+			}
+		}
+	}
+	if cfg.ProtectCPU > 0 && live {
+		w.Protector = protect.New(protectOptions(cfg), protect.SystemAction())
+	}
+	if caps, err := protect.ParseCgroupCaps(cfg.CapCgroups); err == nil && len(caps) > 0 && live {
+		w.Capper = protect.NewCapper(caps, cfg.Enforce)
+	}
+	return w
+}
+
+// Empty reports whether no watcher is enabled.
+func (w Watchers) Empty() bool {
+	return w.Alerts == nil && w.Protector == nil && w.Capper == nil
+}
+
+// protectOptions maps the -protect-* flags onto protect.Options.
+func protectOptions(cfg config.Config) protect.Options {
+	return protect.Options{
+		CPU:    cfg.ProtectCPU,
+		After:  cfg.ProtectAfter,
+		Nice:   cfg.ProtectNice,
+		IdleIO: cfg.ProtectIdleIO,
+		DryRun: cfg.ProtectDryRun,
+	}
+}
@@ -0,0 +1,33 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/Dicklesworthstone/system_resource_protection_script/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	cfg := config.Default()
+	cfg.Alerts = []string{"cpu>90"}
+	cfg.OnAlert = "true"
+	cfg.ProtectCPU = 80
+	cfg.CapCgroups = []string{"batch.slice:50"}
+
+	w := New(cfg)
+	if w.Alerts == nil || w.Hook == nil || w.Protector == nil || w.Capper == nil {
+		t.Errorf("live: %+v, want every watcher", w)
+	}
+
+	cfg.Replay = "capture.ndjson"
+	w = New(cfg)
+	if w.Alerts == nil {
+		t.Error("replay: no alert evaluator")
+	}
+	if w.Hook != nil || w.Protector != nil || w.Capper != nil {
+		t.Errorf("replay: %+v, want only alerts", w)
+	}
+
+	if !New(config.Default()).Empty() {
+		t.Error("default config enables a watcher")
+	}
+}